@@ -23,6 +23,7 @@ type cadFileController struct {
 	projectService        service.ProjectService
 	jwtService            service.JWTService
 	processingPlanService service.ProcessingPlanService
+	toolSelectionService  service.ToolSelectionService
 	cache                 *redis.Client
 }
 
@@ -37,16 +38,18 @@ type CadFileController interface {
 	DownloadOBJ(w http.ResponseWriter, r *http.Request)
 	FindAll(w http.ResponseWriter, r *http.Request)
 	FindAllFiles(w http.ResponseWriter, r *http.Request)
+	FindToolCandidates(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
 }
 
 // NewCADFileController -
-func NewCADFileController(service service.CadFileService, pService service.ProjectService, jwtService service.JWTService, processingPlanService service.ProcessingPlanService, cache *redis.Client) CadFileController {
+func NewCADFileController(service service.CadFileService, pService service.ProjectService, jwtService service.JWTService, processingPlanService service.ProcessingPlanService, toolSelectionService service.ToolSelectionService, cache *redis.Client) CadFileController {
 	return &cadFileController{
 		cadFileService:        service,
 		projectService:        pService,
 		jwtService:            jwtService,
 		processingPlanService: processingPlanService,
+		toolSelectionService:  toolSelectionService,
 		cache:                 cache,
 	}
 }
@@ -203,6 +206,45 @@ func (c *cadFileController) FindAllFiles(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// FindToolCandidates - ranks the tools in the library against each bend of a CAD file
+func (c *cadFileController) FindToolCandidates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		candidates, err := c.toolSelectionService.Rank(cadFile.BendFeatures)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK!", candidates)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+}
+
 func (c *cadFileController) DownloadOBJ(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -310,7 +352,7 @@ func (c *cadFileController) Delete(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if cadFile.FeatureProps.ProcessLevel == 2 {
-			processingPlan, err := c.processingPlanService.Find(cadFile.ID.Hex())
+			processingPlans, err := c.processingPlanService.FindVersions(cadFile.ID.Hex())
 			if err != nil {
 				res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
 				w.WriteHeader(http.StatusNotFound)
@@ -318,12 +360,14 @@ func (c *cadFileController) Delete(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			_, err = cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
-			if err != nil {
-				res := helper.BuildErrorResponse("Deletion failed", err.Error(), helper.EmptyObj{})
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(res)
-				return
+			for _, processingPlan := range processingPlans {
+				_, err = cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
+				if err != nil {
+					res := helper.BuildErrorResponse("Deletion failed", err.Error(), helper.EmptyObj{})
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(res)
+					return
+				}
 			}
 
 			_, err = c.processingPlanService.Delete(id)
@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+type costingController struct {
+	costingService        service.CostingService
+	taskService           service.TaskService
+	cadFileService        service.CadFileService
+	processingPlanService service.ProcessingPlanService
+	materialService       service.MaterialService
+	machineService        service.MachineService
+	jwtService            service.JWTService
+}
+
+// CostResult is the cost of a CAD file's processing plan.
+type CostResult struct {
+	CADFileID string               `json:"cadfile_id"`
+	FileName  string               `json:"filename"`
+	Version   int64                `json:"version"`
+	Cost      entity.CostBreakdown `json:"cost"`
+}
+
+// TaskCostResult is the cost of every processing plan of a task.
+type TaskCostResult struct {
+	TaskID                     string       `json:"task_id"`
+	TotalCost                  float64      `json:"total_cost"`
+	EstimatedManufacturingTime float64      `json:"estimated_manufacturing_time"`
+	Plans                      []CostResult `json:"plans"`
+}
+
+// CostingController -
+type CostingController interface {
+	FindSettings(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	FindTaskCost(w http.ResponseWriter, r *http.Request)
+	Estimate(w http.ResponseWriter, r *http.Request)
+}
+
+// NewCostingController -
+func NewCostingController(costingService service.CostingService, taskService service.TaskService, cadFileService service.CadFileService,
+	processingPlanService service.ProcessingPlanService, materialService service.MaterialService, machineService service.MachineService, jwtService service.JWTService) CostingController {
+	return &costingController{
+		costingService:        costingService,
+		taskService:           taskService,
+		cadFileService:        cadFileService,
+		processingPlanService: processingPlanService,
+		materialService:       materialService,
+		machineService:        machineService,
+		jwtService:            jwtService,
+	}
+}
+
+// FindSettings -
+func (c *costingController) FindSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		settings, err := c.costingService.Find()
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", settings)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// UpdateSettings -
+func (c *costingController) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		settings := &entity.CostingSettings{}
+		err := json.NewDecoder(r.Body).Decode(settings)
+		if err != nil {
+			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		err = c.costingService.Validate(settings)
+		if err != nil {
+			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		settings.UpdatedAt = time.Now().Unix()
+
+		settings, err = c.costingService.Save(settings)
+		if err != nil {
+			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", settings)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// FindTaskCost - the cost of a task and of each processing plan it produced
+func (c *costingController) FindTaskCost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		task, err := c.taskService.Find(id)
+		if err != nil || task.UserID.Hex() != claims["user_id"].(string) {
+			res := helper.BuildErrorResponse("Task not found", "Unknown task ID", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		result := TaskCostResult{
+			TaskID:                     id,
+			TotalCost:                  task.TotalCost,
+			EstimatedManufacturingTime: task.EstimatedManufacturingTime,
+			Plans:                      []CostResult{},
+		}
+
+		for _, processed := range task.ProcessedCADFiles {
+			if processed.ProcessType != entity.ProcessPlanning || processed.Status != entity.Complete {
+				continue
+			}
+
+			// Tasks completed before plans were recorded on them fall back to
+			// the active plan.
+			var processingPlan *entity.ProcessingPlan
+			if processed.PlanID.IsZero() {
+				processingPlan, err = c.processingPlanService.Find(processed.ID.Hex())
+			} else {
+				processingPlan, err = c.processingPlanService.FindByID(processed.PlanID.Hex())
+			}
+			if err != nil {
+				res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			result.Plans = append(result.Plans, CostResult{CADFileID: processed.ID.Hex(), FileName: processed.FileName, Version: processingPlan.Version, Cost: processingPlan.Cost})
+		}
+
+		res := helper.BuildResponse(true, "OK", result)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Estimate - prices a CAD file's processing plan at the current rates, for the
+// quantity in the query or else the plan's quantity
+func (c *costingController) Estimate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		processingPlan, err := c.processingPlanService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		material, err := c.materialService.Find(cadFile.Material)
+		if err != nil {
+			res := helper.BuildErrorResponse("Material not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		var machine *entity.Machine
+		if !processingPlan.MachineID.IsZero() {
+			// Plans whose machine was deleted are priced at the shop rate.
+			machine, _ = c.machineService.Find(processingPlan.MachineID.Hex())
+		}
+
+		quantity := processingPlan.Quantity
+		if q := r.FormValue("quantity"); q != "" {
+			quantity, err = strconv.ParseInt(q, 10, 64)
+			if err != nil || quantity < 1 {
+				res := helper.BuildErrorResponse("Failed to process request", "quantity must be a positive number", helper.EmptyObj{})
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+		}
+
+		cost, err := c.costingService.Estimate(processingPlan, cadFile, material, machine, quantity)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", CostResult{CADFileID: id, FileName: cadFile.FileName, Cost: cost})
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+type deadLetterController struct {
+	deadLetterQueues map[string]msgqueue.DeadLetterQueue
+	jwtService       service.JWTService
+}
+
+type ReplayResult struct {
+	Count int `json:"count"`
+}
+
+// DeadLetterController -
+type DeadLetterController interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	Replay(w http.ResponseWriter, r *http.Request)
+	Purge(w http.ResponseWriter, r *http.Request)
+}
+
+// NewDeadLetterController - deadLetterQueues maps an event name to the dead-letter
+// queue of the listener that consumes it.
+func NewDeadLetterController(deadLetterQueues map[string]msgqueue.DeadLetterQueue, jwtService service.JWTService) DeadLetterController {
+	return &deadLetterController{
+		deadLetterQueues: deadLetterQueues,
+		jwtService:       jwtService,
+	}
+}
+
+// FindAll - list the dead-lettered messages of an event, optionally limited by ?limit=n
+func (c *deadLetterController) FindAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+
+		deadLetterQueue, ok := c.deadLetterQueues[params["event"]]
+		if !ok {
+			res := helper.BuildErrorResponse("Event not found", "Unknown event name", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+
+		deadLetters, err := deadLetterQueue.Inspect(limit)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", deadLetters)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Unknown event name", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Replay - move dead-lettered messages of an event back onto its queue, optionally limited by ?limit=n
+func (c *deadLetterController) Replay(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+
+		deadLetterQueue, ok := c.deadLetterQueues[params["event"]]
+		if !ok {
+			res := helper.BuildErrorResponse("Event not found", "Unknown event name", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+
+		count, err := deadLetterQueue.Replay(limit)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), &ReplayResult{Count: count})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", &ReplayResult{Count: count})
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Replay failed", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Purge - drop all dead-lettered messages of an event
+func (c *deadLetterController) Purge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+
+		deadLetterQueue, ok := c.deadLetterQueues[params["event"]]
+		if !ok {
+			res := helper.BuildErrorResponse("Event not found", "Unknown event name", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		count, err := deadLetterQueue.Purge()
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", &ReplayResult{Count: count})
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Purge failed", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+)
+
+type eventMetricsController struct {
+	metrics    map[string]*msgqueue.ConsumerMetrics
+	jwtService service.JWTService
+}
+
+// EventMetricsController -
+type EventMetricsController interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+}
+
+// NewEventMetricsController - metrics maps an event name to the metrics of the
+// event processor that consumes it.
+func NewEventMetricsController(metrics map[string]*msgqueue.ConsumerMetrics, jwtService service.JWTService) EventMetricsController {
+	return &eventMetricsController{
+		metrics:    metrics,
+		jwtService: jwtService,
+	}
+}
+
+// FindAll - snapshot of the metrics of every event processor
+func (c *eventMetricsController) FindAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		stats := make(map[string]msgqueue.ConsumerStats, len(c.metrics))
+		for event, metrics := range c.metrics {
+			stats[event] = metrics.Snapshot()
+		}
+
+		res := helper.BuildResponse(true, "OK", stats)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
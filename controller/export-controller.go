@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type exportController struct {
+	exportService         service.ExportService
+	processingPlanService service.ProcessingPlanService
+	cadFileService        service.CadFileService
+	projectService        service.ProjectService
+	toolService           service.ToolService
+	jwtService            service.JWTService
+}
+
+// ExportController -
+type ExportController interface {
+	ExportPlan(w http.ResponseWriter, r *http.Request)
+	ExportProject(w http.ResponseWriter, r *http.Request)
+	ExportProgram(w http.ResponseWriter, r *http.Request)
+	ProgramDialects(w http.ResponseWriter, r *http.Request)
+}
+
+// NewExportController -
+func NewExportController(exportService service.ExportService, processingPlanService service.ProcessingPlanService, cadFileService service.CadFileService,
+	projectService service.ProjectService, toolService service.ToolService, jwtService service.JWTService) ExportController {
+	return &exportController{
+		exportService:         exportService,
+		processingPlanService: processingPlanService,
+		cadFileService:        cadFileService,
+		projectService:        projectService,
+		toolService:           toolService,
+		jwtService:            jwtService,
+	}
+}
+
+// writeExport sends an export as a file download.
+func writeExport(w http.ResponseWriter, export *service.Export) {
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(export.Content)
+}
+
+// ExportPlan - the active processing plan of a CAD file as CSV, XLSX or JSON
+func (c *exportController) ExportPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		processingPlan, err := c.processingPlanService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		export, err := c.exportService.ExportPlan(processingPlan, r.FormValue("format"))
+		if errors.Is(err, service.ErrUnknownExportFormat) {
+			res := helper.BuildErrorResponse("Failed to process request", "format must be csv, xlsx or json", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		} else if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		writeExport(w, export)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ExportProject - the active processing plans of a project's CAD files as
+// CSV, a multi-sheet XLSX workbook or JSON
+func (c *exportController) ExportProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		project, err := c.projectService.Find(id)
+		if err != nil || project.OwnerID.Hex() != claims["user_id"].(string) {
+			res := helper.BuildErrorResponse("Project not found", "Unknown project ID", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFiles, err := c.cadFileService.FindAll(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("CAD files not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		processingPlans := []entity.ProcessingPlan{}
+		for _, cadFile := range cadFiles {
+			if cadFile.FeatureProps.ProcessLevel != 2 {
+				continue
+			}
+
+			processingPlan, err := c.processingPlanService.Find(cadFile.ID.Hex())
+			if err != nil {
+				res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			processingPlans = append(processingPlans, *processingPlan)
+		}
+
+		export, err := c.exportService.ExportProject(project, processingPlans, r.FormValue("format"))
+		if errors.Is(err, service.ErrUnknownExportFormat) {
+			res := helper.BuildErrorResponse("Failed to process request", "format must be csv, xlsx or json", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		} else if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		writeExport(w, export)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ExportProgram - the active processing plan of a CAD file as a press brake
+// program, after checking each step against its selected tool
+func (c *exportController) ExportProgram(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		dialect := r.FormValue("dialect")
+		if dialect == "" {
+			dialect = "generic"
+		}
+
+		processingPlan, err := c.processingPlanService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("CAD file not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		// Tools missing from the library are reported by BuildProgram.
+		tools := make(map[string]*entity.Tool)
+		for _, bend := range processingPlan.BendFeatures {
+			if _, ok := tools[bend.ToolID]; ok || bend.ToolID == "" {
+				continue
+			}
+
+			tool, err := c.toolService.Find(bend.ToolID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			} else if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			tools[bend.ToolID] = tool
+		}
+
+		program, issues := service.BuildProgram(processingPlan, cadFile, tools)
+		if len(issues) > 0 {
+			res := helper.BuildErrorResponse("Processing plan can not be run with its selected tools", fmt.Sprintf("%d step(s) failed the tool check", len(issues)), issues)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		export, err := service.ExportProgram(dialect, program)
+		if errors.Is(err, service.ErrUnknownProgramDialect) {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), service.ProgramDialects())
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		} else if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		writeExport(w, export)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ProgramDialects - the press brake program dialects that can be exported
+func (c *exportController) ProgramDialects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		res := helper.BuildResponse(true, "OK", service.ProgramDialects())
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
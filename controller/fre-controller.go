@@ -29,7 +29,7 @@ type freController struct {
 	config                configuration.ServiceConfig
 	taskService           service.TaskService
 	cache                 *redis.Client
-	eventEmitter          msgqueue.EventEmitter
+	outboxRelay           *service.OutboxRelay
 	processor             *service.Processor
 }
 
@@ -41,14 +41,13 @@ type ProcessResult struct {
 // FREController -
 type FREController interface {
 	ProcessCADFile(w http.ResponseWriter, r *http.Request)
-	ExtractBendFeatures(UserID string, TaskID string, cadFile *entity.CADFile)
-	GenerateProcessingPlan(UserID string, TaskID string, cadFile *entity.CADFile)
+	ReplanCADFile(w http.ResponseWriter, r *http.Request)
 	BatchProcessCADFiles(w http.ResponseWriter, r *http.Request)
 }
 
 // NewFREController -
 func NewFREController(configuration configuration.ServiceConfig, cadService service.CadFileService, pPlanService service.ProcessingPlanService,
-	uService service.UserService, jwtService service.JWTService, taskService service.TaskService, cache *redis.Client, eventEmitter msgqueue.EventEmitter, processor *service.Processor) FREController {
+	uService service.UserService, jwtService service.JWTService, taskService service.TaskService, cache *redis.Client, outboxRelay *service.OutboxRelay, processor *service.Processor) FREController {
 	return &freController{
 		userService:           uService,
 		cadFileService:        cadService,
@@ -57,25 +56,34 @@ func NewFREController(configuration configuration.ServiceConfig, cadService serv
 		config:                configuration,
 		taskService:           taskService,
 		cache:                 cache,
-		eventEmitter:          eventEmitter,
+		outboxRelay:           outboxRelay,
 		processor:             processor,
 	}
 }
 
-func (c *freController) ExtractBendFeatures(UserID string, TaskID string, cadFile *entity.CADFile) {
-	request := &contracts.FeatureRecognitionStarted{
-		UserID:    UserID,
-		CADFileID: cadFile.ID.Hex(),
-		TaskID:    TaskID,
-		URL:       cadFile.StepURL,
-		EventType: "featureRecognitionStarted",
+// startEvent returns the event that starts the next processing step of a
+// CAD file, depending on its process level.
+func startEvent(UserID string, TaskID string, cadFile *entity.CADFile) msgqueue.Event {
+	if cadFile.FeatureProps.ProcessLevel == 0 {
+		event := &contracts.FeatureRecognitionStarted{
+			UserID:    UserID,
+			CADFileID: cadFile.ID.Hex(),
+			TaskID:    TaskID,
+			URL:       cadFile.StepURL,
+			EventType: "featureRecognitionStarted",
+		}
+		event.Envelope = contracts.NewEnvelope(event.EventVersion(), TaskID, "")
+
+		return event
 	}
 
-	c.eventEmitter.Emit(request)
+	return planningEvent(UserID, TaskID, cadFile, nil)
 }
 
-func (c *freController) GenerateProcessingPlan(UserID string, TaskID string, cadFile *entity.CADFile) {
-	request := &contracts.ProcessPlanningStarted{
+// planningEvent returns the event that asks the planner for a bending
+// sequence of a CAD file that meets the given constraints, if any.
+func planningEvent(UserID string, TaskID string, cadFile *entity.CADFile, constraints *entity.PlanningConstraints) *contracts.ProcessPlanningStarted {
+	event := &contracts.ProcessPlanningStarted{
 		CADFileID:      cadFile.ID.Hex(),
 		UserID:         UserID,
 		TaskID:         TaskID,
@@ -83,9 +91,29 @@ func (c *freController) GenerateProcessingPlan(UserID string, TaskID string, cad
 		SerializedData: cadFile.FeatureProps.SerialData,
 		EventType:      "processPlanningStarted",
 		FRETime:        cadFile.FeatureProps.FRETime,
+		Constraints:    constraints,
+	}
+	event.Envelope = contracts.NewEnvelope(event.EventVersion(), TaskID, "")
+
+	return event
+}
+
+// createTask stores the task together with the events that start processing
+// its CAD files. The outbox relay publishes the events once they are committed.
+func (c *freController) createTask(task *entity.Task, events ...msgqueue.Event) (*entity.Task, error) {
+	records, err := service.NewOutboxRecords(task.ID, events...)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = c.taskService.CreateWithEvents(task, records)
+	if err != nil {
+		return nil, err
 	}
 
-	c.eventEmitter.Emit(request)
+	c.outboxRelay.Notify()
+
+	return task, nil
 }
 
 // ProcessCADFile -
@@ -114,9 +142,17 @@ func (c *freController) ProcessCADFile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if cadFile.FeatureProps.ProcessLevel == 1 && cadFile.HasDFMErrors() {
+			res := helper.BuildErrorResponse("Process failed", "CAD file breaks design-for-manufacturability rules", cadFile.DFMFindings)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
 		if cadFile.FeatureProps.ProcessLevel == 0 || cadFile.FeatureProps.ProcessLevel == 1 {
 			var task entity.Task
 
+			task.ID = primitive.NewObjectID()
 			task.TaskID = primitive.NewObjectID()
 			task.UserID, err = primitive.ObjectIDFromHex(id)
 			if err != nil {
@@ -134,30 +170,16 @@ func (c *freController) ProcessCADFile(w http.ResponseWriter, r *http.Request) {
 			var res helper.Response
 			if cadFile.FeatureProps.ProcessLevel == 0 {
 				res = helper.BuildResponse(true, "Feature recognition started", &helper.EmptyObj{})
-
-				// task.ProcessType = append(task.ProcessType, entity.FeatureRecognition)
-				task, err := c.taskService.Create(&task)
-				if err != nil {
-					response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
-					w.WriteHeader(http.StatusInternalServerError)
-					json.NewEncoder(w).Encode(response)
-					return
-				}
-
-				c.ExtractBendFeatures(id, task.TaskID.Hex(), cadFile)
-			} else if cadFile.FeatureProps.ProcessLevel == 1 {
+			} else {
 				res = helper.BuildResponse(true, "Process planning started", &helper.EmptyObj{})
+			}
 
-				// task.ProcessType = append(task.ProcessType, entity.ProcessPlanning)
-				task, err := c.taskService.Create(&task)
-				if err != nil {
-					response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
-					w.WriteHeader(http.StatusInternalServerError)
-					json.NewEncoder(w).Encode(response)
-					return
-				}
-
-				c.GenerateProcessingPlan(id, task.TaskID.Hex(), cadFile)
+			_, err = c.createTask(&task, startEvent(id, task.ID.Hex(), cadFile))
+			if err != nil {
+				response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
 			}
 
 			go persistence.ClearCache(TASKCACHE)
@@ -181,6 +203,103 @@ func (c *freController) ProcessCADFile(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ReplanCADFile - asks the planner for a new processing plan of a CAD file that
+// meets the constraints in the body. The current plan is kept.
+func (c *freController) ReplanCADFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		id := claims["user_id"].(string)
+
+		params := mux.Vars(r)
+		cadFileID := params["id"]
+
+		cadFile, err := c.cadFileService.Find(cadFileID)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		if cadFile.FeatureProps.ProcessLevel == 0 {
+			res := helper.BuildErrorResponse("Process failed", "CAD file features have not been recognised", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		if cadFile.HasDFMErrors() {
+			res := helper.BuildErrorResponse("Process failed", "CAD file breaks design-for-manufacturability rules", cadFile.DFMFindings)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		constraints := &entity.PlanningConstraints{}
+		err = json.NewDecoder(r.Body).Decode(constraints)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		err = c.processingPlanService.ValidateConstraints(constraints, cadFile)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		var task entity.Task
+
+		task.ID = primitive.NewObjectID()
+		task.TaskID = primitive.NewObjectID()
+		task.UserID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		task.Status = entity.Processing
+		task.Quantity = 1
+		task.CADFiles = append(task.CADFiles, cadFile.FileName)
+		task.Description = "1 process planning process(es) started"
+		task.CreatedAt = time.Now().Unix()
+
+		_, err = c.createTask(&task, planningEvent(id, task.ID.Hex(), cadFile, constraints))
+		if err != nil {
+			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		go persistence.ClearCache(TASKCACHE)
+
+		res := helper.BuildResponse(true, "Process re-planning started", constraints)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
 // BatchProcessCADFiles -
 func (c *freController) BatchProcessCADFiles(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -245,6 +364,7 @@ func (c *freController) BatchProcessCADFiles(w http.ResponseWriter, r *http.Requ
 
 			var freNum = 0
 			var ppNum = 0
+			var dfmNum = 0
 
 			var task entity.Task
 			task.ID = primitive.NewObjectID()
@@ -264,15 +384,21 @@ func (c *freController) BatchProcessCADFiles(w http.ResponseWriter, r *http.Requ
 			task.Status = entity.Processing
 			task.CreatedAt = time.Now().Unix()
 
-			for _, cadFile := range cadFiles {
+			var events []msgqueue.Event
+			for i := range cadFiles {
+				cadFile := &cadFiles[i]
 				if cadFile.FeatureProps.ProcessLevel == 0 {
-					c.ExtractBendFeatures(id, task.ID.Hex(), &cadFile)
 					freNum++
+				} else if cadFile.FeatureProps.ProcessLevel == 1 && cadFile.HasDFMErrors() {
+					dfmNum++
+					continue
 				} else if cadFile.FeatureProps.ProcessLevel == 1 {
-					c.GenerateProcessingPlan(id, task.ID.Hex(), &cadFile)
 					ppNum++
+				} else {
+					continue
 				}
 
+				events = append(events, startEvent(id, task.ID.Hex(), cadFile))
 				task.CADFiles = append(task.CADFiles, cadFile.FileName)
 			}
 
@@ -288,9 +414,18 @@ func (c *freController) BatchProcessCADFiles(w http.ResponseWriter, r *http.Requ
 				resultString = fmt.Sprintf("%d feature recognition process(es) started", freNum)
 			}
 
+			if dfmNum > 0 {
+				skipped := fmt.Sprintf("%d CAD file(s) skipped for breaking design-for-manufacturability rules", dfmNum)
+				if resultString == "" {
+					resultString = skipped
+				} else {
+					resultString += "; " + skipped
+				}
+			}
+
 			task.Description = resultString
 
-			_, err = c.taskService.Create(&task)
+			_, err = c.createTask(&task, events...)
 			if err != nil {
 				response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
 				resp, err := json.Marshal(response)
@@ -0,0 +1,712 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	"github.com/WilfredDube/fxtract-backend/repository"
+	persistence "github.com/WilfredDube/fxtract-backend/repository/reposelect"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type processingPlanController struct {
+	processingPlanService service.ProcessingPlanService
+	cadFileService        service.CadFileService
+	projectService        service.ProjectService
+	userService           service.UserService
+	pdfTemplateService    service.PDFTemplateService
+	jwtService            service.JWTService
+}
+
+// ReviewersRequest - the users asked to review a processing plan
+type ReviewersRequest struct {
+	ReviewerIDs []string `json:"reviewer_ids"`
+}
+
+// ReviewRequest - a review status change or comment
+type ReviewRequest struct {
+	Status  entity.PlanStatus `json:"status,omitempty"`
+	Comment string            `json:"comment"`
+}
+
+// ProcessingPlanController -
+type ProcessingPlanController interface {
+	FindVersions(w http.ResponseWriter, r *http.Request)
+	FindVersion(w http.ResponseWriter, r *http.Request)
+	Activate(w http.ResponseWriter, r *http.Request)
+	Compare(w http.ResponseWriter, r *http.Request)
+	AssignReviewers(w http.ResponseWriter, r *http.Request)
+	AddComment(w http.ResponseWriter, r *http.Request)
+	ChangeStatus(w http.ResponseWriter, r *http.Request)
+	RenderPDF(w http.ResponseWriter, r *http.Request)
+}
+
+// NewProcessingPlanController -
+func NewProcessingPlanController(processingPlanService service.ProcessingPlanService, cadFileService service.CadFileService, projectService service.ProjectService,
+	userService service.UserService, pdfTemplateService service.PDFTemplateService, jwtService service.JWTService) ProcessingPlanController {
+	return &processingPlanController{
+		processingPlanService: processingPlanService,
+		cadFileService:        cadFileService,
+		projectService:        projectService,
+		userService:           userService,
+		pdfTemplateService:    pdfTemplateService,
+		jwtService:            jwtService,
+	}
+}
+
+// reviewErrorStatus returns the HTTP status for a failed review change.
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidReview):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrPlanReleased), errors.Is(err, service.ErrInvalidTransition),
+		errors.Is(err, repository.ErrReviewConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNotReviewer), errors.Is(err, service.ErrNotPlanMember),
+		errors.Is(err, service.ErrNotPlanOwner):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FindVersions - every version of a CAD file's processing plan
+func (c *processingPlanController) FindVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		processingPlans, err := c.processingPlanService.FindVersions(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", processingPlans)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// FindVersion -
+func (c *processingPlanController) FindVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		processingPlan, err := c.processingPlanService.FindVersion(id, version)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", processingPlan)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Activate - makes a version the processing plan used for the CAD file
+func (c *processingPlanController) Activate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		activated, err := c.processingPlanService.Activate(id, version)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		if activated == 0 {
+			res := helper.BuildErrorResponse("Processing plan not found", "Unknown processing plan version", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		go persistence.ClearCache(id)
+
+		processingPlan, err := c.processingPlanService.FindVersion(id, version)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", processingPlan)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Compare - the differences between the from and to versions in the query
+func (c *processingPlanController) Compare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		fromVersion, err := strconv.ParseInt(r.FormValue("from"), 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "from must be a version number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		toVersion, err := strconv.ParseInt(r.FormValue("to"), 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "to must be a version number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		from, err := c.processingPlanService.FindVersion(id, fromVersion)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		to, err := c.processingPlanService.FindVersion(id, toVersion)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		res := helper.BuildResponse(true, "OK", service.ComparePlans(from, to))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// AssignReviewers - sets the users who review a processing plan version
+func (c *processingPlanController) AssignReviewers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		user, err := c.userService.Profile(claims["user_id"].(string))
+		if err != nil {
+			res := helper.BuildErrorResponse("User not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		request := &ReviewersRequest{}
+		err = json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		project, err := c.projectService.Find(cadFile.ProjectID.Hex())
+		if err != nil {
+			res := helper.BuildErrorResponse("Project not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		reviewers := []entity.Reviewer{}
+		for _, reviewerID := range request.ReviewerIDs {
+			reviewer, err := c.userService.Profile(reviewerID)
+			if err != nil {
+				res := helper.BuildErrorResponse("Reviewer not found", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			reviewers = append(reviewers, entity.Reviewer{UserID: reviewer.ID, Name: reviewer.FullName()})
+		}
+
+		processingPlan, err := c.processingPlanService.MutateReview(id, version, func(processingPlan *entity.ProcessingPlan) error {
+			return c.processingPlanService.AssignReviewers(processingPlan, reviewers, user, project.OwnerID)
+		})
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(reviewErrorStatus(err))
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		go persistence.ClearCache(id)
+
+		res := helper.BuildResponse(true, "OK", processingPlan)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// AddComment - adds a review comment to a processing plan version
+func (c *processingPlanController) AddComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		user, err := c.userService.Profile(claims["user_id"].(string))
+		if err != nil {
+			res := helper.BuildErrorResponse("User not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		request := &ReviewRequest{}
+		err = json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		project, err := c.projectService.Find(cadFile.ProjectID.Hex())
+		if err != nil {
+			res := helper.BuildErrorResponse("Project not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		comment := entity.ReviewComment{
+			AuthorID:  user.ID,
+			Author:    user.FullName(),
+			Comment:   request.Comment,
+			CreatedAt: time.Now().Unix(),
+		}
+
+		processingPlan, err := c.processingPlanService.MutateReview(id, version, func(processingPlan *entity.ProcessingPlan) error {
+			return c.processingPlanService.AddComment(processingPlan, comment, user, project.OwnerID)
+		})
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(reviewErrorStatus(err))
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		go persistence.ClearCache(id)
+
+		res := helper.BuildResponse(true, "OK", processingPlan)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ChangeStatus - moves a processing plan version through its review. The PDF
+// of an approved plan is regenerated to show who approved it.
+func (c *processingPlanController) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		user, err := c.userService.Profile(claims["user_id"].(string))
+		if err != nil {
+			res := helper.BuildErrorResponse("User not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		request := &ReviewRequest{}
+		err = json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		project, err := c.projectService.Find(cadFile.ProjectID.Hex())
+		if err != nil {
+			res := helper.BuildErrorResponse("Project not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		processingPlan, err := c.processingPlanService.MutateReview(id, version, func(processingPlan *entity.ProcessingPlan) error {
+			return c.processingPlanService.Transition(processingPlan, request.Status, user, project.OwnerID, request.Comment)
+		})
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(reviewErrorStatus(err))
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		if processingPlan.Status == entity.PlanApproved {
+			blob := service.NewAzureBlobService()
+
+			// Without its mesh the plan is printed without illustrations.
+			part, _ := service.LoadPartMesh(blob, cadFile.ObjpURL)
+
+			template, err := c.planTemplate(processingPlan, cadFile)
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			pdfBuff, err := service.NewPDFService().GeneratePDF(processingPlan, part, template)
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			// Each approval gets its own PDF, so the one the plan links to is
+			// never overwritten by an approval that has not been saved.
+			filename := fmt.Sprintf(cadFile.ProjectID.Hex()+"/%s-r%d.pdf", processingPlan.ID.Hex(), processingPlan.ReviewRevision)
+			_, pdfURL, err := blob.UploadFromBuffer(&pdfBuff, filename)
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			approvedAt := processingPlan.ApprovedAt
+			processingPlan, err = c.processingPlanService.MutateReview(id, version, func(processingPlan *entity.ProcessingPlan) error {
+				if processingPlan.ReviewStatus() != entity.PlanApproved || processingPlan.ApprovedAt != approvedAt {
+					return fmt.Errorf("%w: the plan's review changed while its PDF was drawn", repository.ErrReviewConflict)
+				}
+
+				processingPlan.PdfURL = pdfURL
+				return nil
+			})
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(reviewErrorStatus(err))
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+		}
+
+		go persistence.ClearCache(id)
+
+		res := helper.BuildResponse(true, "OK", processingPlan)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RenderPDF - draws a processing plan version with another template and
+// returns the PDF without replacing the plan's own. Users can pick the
+// templates assigned to them and those without customers; admins can pick
+// any template.
+func (c *processingPlanController) RenderPDF(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		params := mux.Vars(r)
+		id := params["id"]
+
+		version, err := strconv.ParseInt(params["version"], 10, 64)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", "version must be a number", helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		processingPlan, err := c.processingPlanService.FindVersion(id, version)
+		if err != nil {
+			res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFile, err := c.cadFileService.Find(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", "CAD file not found", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		var template *entity.PDFTemplate
+		if templateID := r.FormValue("template"); templateID == "" {
+			template, err = c.planTemplate(processingPlan, cadFile)
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+		} else {
+			template, err = c.pdfTemplateService.Find(templateID)
+			if err != nil {
+				res := helper.BuildErrorResponse("PDF template not found", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			userID, err := primitive.ObjectIDFromHex(claims["user_id"].(string))
+			if err != nil {
+				res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+
+			role, _ := c.jwtService.GetUserRole(r, "fxtract")
+			if role != entity.ADMIN && len(template.CustomerIDs) > 0 && !template.HasCustomer(userID) {
+				res := helper.BuildErrorResponse("Unauthorised", "PDF template is not available to the user", helper.EmptyObj{})
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+		}
+
+		// Without its mesh the plan is printed without illustrations.
+		part, _ := service.LoadPartMesh(service.NewAzureBlobService(), cadFile.ObjpURL)
+
+		pdfBuff, err := service.NewPDFService().GeneratePDF(processingPlan, part, template)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		writeExport(w, &service.Export{
+			Filename:    fmt.Sprintf("%s-v%d.pdf", processingPlan.ID.Hex(), processingPlan.Version),
+			ContentType: "application/pdf",
+			Content:     pdfBuff.Bytes(),
+		})
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
+
+// planTemplate returns the template a plan was printed with. Plans printed
+// with the Fxtract branding, or with a template that has since been deleted,
+// get the current template of the project's owner.
+func (c *processingPlanController) planTemplate(processingPlan *entity.ProcessingPlan, cadFile *entity.CADFile) (*entity.PDFTemplate, error) {
+	if !processingPlan.PDFTemplateID.IsZero() {
+		template, err := c.pdfTemplateService.Find(processingPlan.PDFTemplateID.Hex())
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return template, err
+		}
+	}
+
+	project, err := c.projectService.Find(cadFile.ProjectID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	return c.pdfTemplateService.ForCustomer(project.OwnerID.Hex())
+}
@@ -161,6 +161,14 @@ func (c *controller) UpdateProject(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = c.projectService.Validate(project)
+		if err != nil {
+			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
 		response, err = c.projectService.Update(project)
 		if err != nil {
 			response := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
@@ -355,7 +363,7 @@ func (c *controller) Delete(w http.ResponseWriter, r *http.Request) {
 				cloudService.Delete(file.StepURL, service.CADFILE)
 
 				if file.FeatureProps.ProcessLevel == 2 {
-					processingPlan, err := c.processingPlanService.Find(file.ID.Hex())
+					processingPlans, err := c.processingPlanService.FindVersions(file.ID.Hex())
 					if err != nil {
 						res := helper.BuildErrorResponse("Processing plan not found", err.Error(), helper.EmptyObj{})
 						w.WriteHeader(http.StatusNotFound)
@@ -363,9 +371,11 @@ func (c *controller) Delete(w http.ResponseWriter, r *http.Request) {
 						return
 					}
 
-					cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
+					for _, processingPlan := range processingPlans {
+						cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
+					}
 
-					_, err = c.processingPlanService.Delete(id)
+					_, err = c.processingPlanService.Delete(file.ID.Hex())
 					if err != nil {
 						res := helper.BuildErrorResponse("Processing plan deletion failed", err.Error(), helper.EmptyObj{})
 						w.WriteHeader(http.StatusNotFound)
@@ -736,7 +746,7 @@ func (c *controller) DeleteCADFile(w http.ResponseWriter, r *http.Request) {
 		cloudService := service.NewAzureBlobService()
 
 		if cadFile.FeatureProps.ProcessLevel == 2 {
-			processingPlan, err := c.processingPlanService.Find(id)
+			processingPlans, err := c.processingPlanService.FindVersions(id)
 			if err != nil {
 				res := helper.BuildErrorResponse("Processing plan deletion failed", err.Error(), helper.EmptyObj{})
 				w.WriteHeader(http.StatusNotFound)
@@ -744,12 +754,14 @@ func (c *controller) DeleteCADFile(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			_, err = cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
-			if err != nil {
-				res := helper.BuildErrorResponse("Cloud process plan deletion failed", err.Error(), helper.EmptyObj{})
-				w.WriteHeader(http.StatusNotFound)
-				json.NewEncoder(w).Encode(res)
-				return
+			for _, processingPlan := range processingPlans {
+				_, err = cloudService.Delete(processingPlan.PdfURL, service.PDFFILE)
+				if err != nil {
+					res := helper.BuildErrorResponse("Cloud process plan deletion failed", err.Error(), helper.EmptyObj{})
+					w.WriteHeader(http.StatusNotFound)
+					json.NewEncoder(w).Encode(res)
+					return
+				}
 			}
 
 			_, err = c.processingPlanService.Delete(id)
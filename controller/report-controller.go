@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	persistence "github.com/WilfredDube/fxtract-backend/repository/reposelect"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type reportController struct {
+	projectService service.ProjectService
+	cadFileService service.CadFileService
+	taskService    service.TaskService
+	outboxRelay    *service.OutboxRelay
+	jwtService     service.JWTService
+}
+
+// ReportController -
+type ReportController interface {
+	GenerateProjectReport(w http.ResponseWriter, r *http.Request)
+}
+
+// NewReportController -
+func NewReportController(projectService service.ProjectService, cadFileService service.CadFileService, taskService service.TaskService,
+	outboxRelay *service.OutboxRelay, jwtService service.JWTService) ReportController {
+	return &reportController{
+		projectService: projectService,
+		cadFileService: cadFileService,
+		taskService:    taskService,
+		outboxRelay:    outboxRelay,
+		jwtService:     jwtService,
+	}
+}
+
+// GenerateProjectReport - starts a task that draws the processing plans of a
+// project's CAD files in one PDF. The PDF's URL is on the finished task.
+func (c *reportController) GenerateProjectReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := c.jwtService.GetAuthenticationToken(r, "fxtract")
+	if err != nil {
+		response := helper.BuildErrorResponse("Unauthorised", "User not authenticated", helper.EmptyObj{})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		userID := claims["user_id"].(string)
+
+		params := mux.Vars(r)
+		id := params["id"]
+
+		project, err := c.projectService.Find(id)
+		if err != nil || project.OwnerID.Hex() != userID {
+			res := helper.BuildErrorResponse("Project not found", "Unknown project ID", helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		cadFiles, err := c.cadFileService.FindAll(id)
+		if err != nil {
+			res := helper.BuildErrorResponse("CAD files not found", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		var task entity.Task
+		for _, cadFile := range cadFiles {
+			if cadFile.FeatureProps.ProcessLevel == 2 {
+				task.CADFiles = append(task.CADFiles, cadFile.FileName)
+			}
+		}
+
+		if len(task.CADFiles) == 0 {
+			res := helper.BuildErrorResponse("Failed to process request", "Project has no processing plans to report", helper.EmptyObj{})
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		task.ID = primitive.NewObjectID()
+		task.TaskID = primitive.NewObjectID()
+		task.UserID, err = primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			res := helper.BuildErrorResponse("Process failed", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		// The report is a single step of the task, however many parts it covers.
+		task.Status = entity.Processing
+		task.Quantity = 1
+		task.Description = "Project report of " + project.Title
+		task.CreatedAt = time.Now().Unix()
+
+		event := &contracts.ProjectReportRequested{
+			UserID:    userID,
+			ProjectID: id,
+			TaskID:    task.ID.Hex(),
+			EventType: "projectReportRequested",
+		}
+		event.Envelope = contracts.NewEnvelope(event.EventVersion(), task.ID.Hex(), "")
+
+		records, err := service.NewOutboxRecords(task.ID, event)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		_, err = c.taskService.CreateWithEvents(&task, records)
+		if err != nil {
+			res := helper.BuildErrorResponse("Failed to process request", err.Error(), helper.EmptyObj{})
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		c.outboxRelay.Notify()
+		go persistence.ClearCache(TASKCACHE)
+
+		res := helper.BuildResponse(true, "Project report started", &ProcessResult{Message: "Project report started", TaskID: task.ID.Hex()})
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	res := helper.BuildErrorResponse("Failed to process request", "Invalid token", helper.EmptyObj{})
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(res)
+}
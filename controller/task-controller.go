@@ -94,7 +94,7 @@ func (c *taskController) Find(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		res := helper.BuildResponse(true, string(entity.Complete), task)
+		res := helper.BuildResponse(true, string(task.Status), task)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(res)
 		return
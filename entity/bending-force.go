@@ -0,0 +1,13 @@
+package entity
+
+// BendingForceModel names the formula used to estimate the force needed to
+// form a bend.
+type BendingForceModel string
+
+// Bending force models
+const (
+	LegacyBending BendingForceModel = "legacy"
+	AirBending    BendingForceModel = "air"
+	Bottoming     BendingForceModel = "bottoming"
+	Coining       BendingForceModel = "coining"
+)
@@ -13,17 +13,51 @@ type CADFile struct {
 	Filesize     int64              `json:"filesize" bson:"filesize" validate:"empty=false"`
 	FeatureProps FeatureProperty    `json:"feature_props" bson:"feature_props" validate:"empty=false"`
 	BendFeatures []BendFeature      `json:"bend_features" bson:"bend_features" validate:"empty=false"`
+	FlatPattern  FlatPattern        `json:"flat_pattern" bson:"flat_pattern"`
+	DFMFindings  []DFMFinding       `json:"dfm_findings" bson:"dfm_findings,omitempty"`
 	CreatedAt    int64              `json:"created_at" bson:"created_at" validate:"empty=false"`
 }
 
+// HasDFMErrors reports whether the part breaks a design rule that keeps it from
+// being planned.
+func (c *CADFile) HasDFMErrors() bool {
+	for _, finding := range c.DFMFindings {
+		if finding.Severity == DFMError {
+			return true
+		}
+	}
+
+	return false
+}
+
 // FeatureProperty -
 type FeatureProperty struct {
 	SerialData   string  `json:"serial_data" bson:"serial_data" validate:"empty=false"`
 	Thickness    float64 `json:"thickness" bson:"thickness" validate:"empty=false"`
 	BendingForce float64 `json:"bending_force" bson:"bending_force" validate:"empty=false"`
-	ProcessLevel int     `json:"process_level" bson:"process_level" validate:"empty=false"`
-	FRETime      float64 `json:"fre_time" bson:"fre_time" validate:"empty=false"`
-	BendCount    int     `json:"bend_count" bson:"bend_count" validate:"empty=false"`
+	// BendingForceModel is the model BendingForce was calculated with.
+	BendingForceModel BendingForceModel `json:"bending_force_model,omitempty" bson:"bending_force_model,omitempty"`
+	ProcessLevel      int               `json:"process_level" bson:"process_level" validate:"empty=false"`
+	FRETime           float64           `json:"fre_time" bson:"fre_time" validate:"empty=false"`
+	BendCount         int               `json:"bend_count" bson:"bend_count" validate:"empty=false"`
+	// Flanges are the faces joined by the bends. Older feature recognition
+	// workers do not report them.
+	Flanges []Flange `json:"flanges,omitempty" bson:"flanges,omitempty"`
+}
+
+// Flange is a flat face of a part, measured to the outside mold lines of the
+// bends on its edges.
+type Flange struct {
+	FaceID int64   `json:"face_id" bson:"face_id"`
+	Length float64 `json:"length" bson:"length"`
+}
+
+// FlatPattern is the unfolded blank of a part.
+type FlatPattern struct {
+	KFactor         float64 `json:"k_factor" bson:"k_factor"`
+	DevelopedLength float64 `json:"developed_length" bson:"developed_length"`
+	BlankLength     float64 `json:"blank_length" bson:"blank_length"`
+	BlankWidth      float64 `json:"blank_width" bson:"blank_width"`
 }
 
 // BendFeature -
@@ -36,5 +70,10 @@ type BendFeature struct {
 	Radius       float64 `json:"radius" bson:"radius" validate:"empty=false"`
 	Direction    float64 `json:"direction" bson:"direction" validate:"empty=false"`
 	ToolID       string  `json:"tool_id" bson:"tool_id" validate:"empty=false"`
-	CreatedAt    int64   `json:"created_at" bson:"created_at" validate:"empty=false"`
+	BendingForce float64 `json:"bending_force" bson:"bending_force"`
+	// Bend allowance, bend deduction and outside setback of the bend.
+	BendAllowance  float64 `json:"bend_allowance" bson:"bend_allowance"`
+	BendDeduction  float64 `json:"bend_deduction" bson:"bend_deduction"`
+	OutsideSetback float64 `json:"outside_setback" bson:"outside_setback"`
+	CreatedAt      int64   `json:"created_at" bson:"created_at" validate:"empty=false"`
 }
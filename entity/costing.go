@@ -0,0 +1,31 @@
+package entity
+
+// CostingSettings are the shop rates manufacturing costs are estimated with.
+type CostingSettings struct {
+	// HourlyRate is the cost of an hour of press brake time.
+	HourlyRate float64 `json:"hourly_rate" bson:"hourly_rate"`
+	// SetupCost is the cost of each tool change when setting up a batch.
+	SetupCost      float64         `json:"setup_cost" bson:"setup_cost"`
+	QuantityBreaks []QuantityBreak `json:"quantity_breaks" bson:"quantity_breaks"`
+	UpdatedAt      int64           `json:"updated_at" bson:"updated_at"`
+}
+
+// QuantityBreak is the discount, as a fraction of the cost, given on batches
+// of at least MinQuantity parts.
+type QuantityBreak struct {
+	MinQuantity int64   `json:"min_quantity" bson:"min_quantity"`
+	Discount    float64 `json:"discount" bson:"discount"`
+}
+
+// CostBreakdown is the estimated cost of making a batch of parts to a
+// processing plan.
+type CostBreakdown struct {
+	Quantity     int64   `json:"quantity" bson:"quantity"`
+	BlankMass    float64 `json:"blank_mass" bson:"blank_mass"`
+	MaterialCost float64 `json:"material_cost" bson:"material_cost"`
+	MachineCost  float64 `json:"machine_cost" bson:"machine_cost"`
+	SetupCost    float64 `json:"setup_cost" bson:"setup_cost"`
+	Discount     float64 `json:"discount" bson:"discount"`
+	UnitCost     float64 `json:"unit_cost" bson:"unit_cost"`
+	TotalCost    float64 `json:"total_cost" bson:"total_cost"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+// DFMSeverity tells whether a design-for-manufacturability finding stops a
+// part from being planned.
+type DFMSeverity string
+
+// DFM severities
+const (
+	DFMWarning DFMSeverity = "warning"
+	DFMError   DFMSeverity = "error"
+)
+
+// DFM rules
+const (
+	MinBendRadiusRule   = "MIN_BEND_RADIUS"
+	MinFlangeLengthRule = "MIN_FLANGE_LENGTH"
+	BendProximityRule   = "BEND_PROXIMITY"
+	UnsupportedBendRule = "UNSUPPORTED_BEND"
+)
+
+// DFMFinding is a design-for-manufacturability rule broken by a part.
+type DFMFinding struct {
+	Rule     string      `json:"rule" bson:"rule"`
+	Severity DFMSeverity `json:"severity" bson:"severity"`
+	BendID   int64       `json:"bend_id,omitempty" bson:"bend_id,omitempty"`
+	FaceID   int64       `json:"face_id,omitempty" bson:"face_id,omitempty"`
+	Message  string      `json:"message" bson:"message"`
+}
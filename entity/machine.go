@@ -0,0 +1,39 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Machine is a press brake in the shop.
+type Machine struct {
+	ID   primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name string             `json:"name" bson:"name" validate:"empty=false"`
+	// MaxTonnage is the largest force, in kN, the press can apply.
+	MaxTonnage float64 `json:"max_tonnage" bson:"max_tonnage" validate:"empty=false"`
+	// BedLength is the longest bend, in mm, the press can form.
+	BedLength float64 `json:"bed_length" bson:"bed_length" validate:"empty=false"`
+	// BackGaugeMin and BackGaugeMax are the shortest and longest flanges, in
+	// mm, the back gauge can position.
+	BackGaugeMin float64 `json:"back_gauge_min" bson:"back_gauge_min"`
+	BackGaugeMax float64 `json:"back_gauge_max" bson:"back_gauge_max" validate:"empty=false"`
+	// ToolStations is how many tools can be mounted at once.
+	ToolStations int64   `json:"tool_stations" bson:"tool_stations" validate:"empty=false"`
+	HourlyRate   float64 `json:"hourly_rate" bson:"hourly_rate"`
+	// BendingForceModel is used for projects on the machine that do not
+	// choose a model of their own.
+	BendingForceModel BendingForceModel `json:"bending_force_model,omitempty" bson:"bending_force_model,omitempty"`
+	CreatedAt         int64             `json:"created_at" bson:"created_at" validate:"empty=false"`
+}
+
+// CapacityIssue is a way in which a part exceeds what its machine can do.
+type CapacityIssue struct {
+	Code    string `json:"code" bson:"code"`
+	BendID  int64  `json:"bend_id,omitempty" bson:"bend_id,omitempty"`
+	Message string `json:"message" bson:"message"`
+}
+
+// Capacity issue codes
+const (
+	TonnageExceeded      = "TONNAGE_EXCEEDED"
+	BedLengthExceeded    = "BED_LENGTH_EXCEEDED"
+	ToolStationsExceeded = "TOOL_STATIONS_EXCEEDED"
+	BackGaugeOutOfRange  = "BACK_GAUGE_OUT_OF_RANGE"
+)
@@ -2,8 +2,15 @@ package entity
 
 // Material -
 type Material struct {
-	Name            string  `json:"name" bson:"name" validate:"empty=false"`
+	Name string `json:"name" bson:"name" validate:"empty=false"`
+	// TensileStrength is the ultimate tensile strength in MPa (N/mm²).
 	TensileStrength float64 `json:"-" bson:"tensile_strength" validate:"empty=false"`
 	KFactor         float64 `json:"-" bson:"k_factor" validate:"empty=false"`
-	CreatedAt       int64   `json:"-" bson:"created_at" validate:"empty=false"`
+	// Density is in kg/m³ and PricePerKg in the shop's currency.
+	Density    float64 `json:"density,omitempty" bson:"density,omitempty"`
+	PricePerKg float64 `json:"price_per_kg,omitempty" bson:"price_per_kg,omitempty"`
+	// MinRadiusRatio is the smallest inside bend radius, as a multiple of the
+	// sheet thickness, the material can be bent to without cracking.
+	MinRadiusRatio float64 `json:"min_radius_ratio,omitempty" bson:"min_radius_ratio,omitempty"`
+	CreatedAt      int64   `json:"-" bson:"created_at" validate:"empty=false"`
 }
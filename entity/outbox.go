@@ -0,0 +1,26 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "Pending"
+	OutboxSent    OutboxStatus = "Sent"
+	OutboxFailed  OutboxStatus = "Failed"
+)
+
+// OutboxRecord is an event waiting to be published. It is written in the same
+// transaction as the task that caused it and published by the outbox relay.
+type OutboxRecord struct {
+	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TaskID        primitive.ObjectID `json:"task_id" bson:"task_id"`
+	EventName     string             `json:"event_name" bson:"event_name"`
+	Payload       string             `json:"payload" bson:"payload"`
+	Status        OutboxStatus       `json:"status" bson:"status"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	LastError     string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt int64              `json:"next_attempt_at" bson:"next_attempt_at"`
+	SentAt        int64              `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	CreatedAt     int64              `json:"created_at" bson:"created_at"`
+}
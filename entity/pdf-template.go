@@ -0,0 +1,90 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// PaperSize is the page size of a PDF.
+type PaperSize string
+
+// Paper sizes
+const (
+	PaperA4     PaperSize = "A4"
+	PaperLetter PaperSize = "Letter"
+)
+
+// Fields of the processing plan PDF's header. Templates list the fields they
+// print, in the order they are printed.
+const (
+	FieldEngineer          = "engineer"
+	FieldDate              = "date"
+	FieldProjectName       = "project_name"
+	FieldModules           = "modules"
+	FieldPartName          = "part_name"
+	FieldPartNo            = "part_no"
+	FieldMaterial          = "material"
+	FieldBendingForce      = "bending_force"
+	FieldBendingForceModel = "bending_force_model"
+	FieldMachine           = "machine"
+	FieldPlanVersion       = "plan_version"
+	FieldApprovedBy        = "approved_by"
+	FieldTools             = "tools"
+	FieldRotations         = "rotations"
+	FieldFlips             = "flips"
+	FieldQuantity          = "quantity"
+	FieldPlanningTime      = "planning_time"
+	FieldProductionTime    = "production_time"
+)
+
+// PDFFields are all the header fields, in their default order.
+var PDFFields = []string{
+	FieldEngineer, FieldDate, FieldProjectName, FieldModules, FieldPartName, FieldPartNo,
+	FieldMaterial, FieldBendingForce, FieldBendingForceModel, FieldMachine, FieldPlanVersion, FieldApprovedBy,
+	FieldTools, FieldRotations, FieldFlips, FieldQuantity, FieldPlanningTime, FieldProductionTime,
+}
+
+// RGB is a colour of a PDF template.
+type RGB struct {
+	Red   int `json:"red" bson:"red"`
+	Green int `json:"green" bson:"green"`
+	Blue  int `json:"blue" bson:"blue"`
+}
+
+// PDFTemplate is the branding and layout of the PDFs made for a customer.
+// Customers without a template of their own get the default template.
+type PDFTemplate struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name" validate:"empty=false"`
+	CompanyName string             `json:"company_name" bson:"company_name"`
+	// Logo is a base64 encoded image printed in place of the company name,
+	// and LogoType is its format, png or jpg. It is uploaded on its own.
+	Logo     string `json:"-" bson:"logo,omitempty"`
+	LogoType string `json:"logo_type,omitempty" bson:"logo_type,omitempty"`
+	// PrimaryColour is used for the company name and section titles. Tables
+	// shade every other row in the SecondaryColour, when there is one.
+	PrimaryColour   RGB    `json:"primary_colour" bson:"primary_colour"`
+	SecondaryColour *RGB   `json:"secondary_colour,omitempty" bson:"secondary_colour,omitempty"`
+	HeaderText      string `json:"header_text" bson:"header_text"`
+	FooterText      string `json:"footer_text" bson:"footer_text"`
+	// QRCodeURL is the target of the QR code on plans. {cadfile_id}, {plan_id}
+	// and {version} are replaced with those of the plan. Plans have no QR
+	// code when it is empty.
+	QRCodeURL string    `json:"qr_code_url" bson:"qr_code_url"`
+	Fields    []string  `json:"fields" bson:"fields"`
+	PaperSize PaperSize `json:"paper_size" bson:"paper_size"`
+	// CustomerIDs are the users whose PDFs use the template. Templates without
+	// customers can be picked by anyone when re-rendering a plan.
+	CustomerIDs []primitive.ObjectID `json:"customer_ids" bson:"customer_ids"`
+	Default     bool                 `json:"default" bson:"default"`
+	CreatedAt   int64                `json:"created_at" bson:"created_at" validate:"empty=false"`
+	UpdatedAt   int64                `json:"updated_at" bson:"updated_at"`
+}
+
+// HasCustomer reports whether the template is assigned to the user.
+func (t *PDFTemplate) HasCustomer(userID primitive.ObjectID) bool {
+	for _, id := range t.CustomerIDs {
+		if id == userID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// PlanStatus is where a processing plan is in its review.
+type PlanStatus string
+
+// Review states. A plan goes from draft to in review, where a reviewer
+// approves or rejects it. A rejected plan goes back to draft, and an approved
+// plan can be released to the shop floor, after which it can not change.
+const (
+	PlanDraft    PlanStatus = "draft"
+	PlanInReview PlanStatus = "in_review"
+	PlanApproved PlanStatus = "approved"
+	PlanRejected PlanStatus = "rejected"
+	PlanReleased PlanStatus = "released"
+)
+
+// Reviewer is a user asked to review a processing plan.
+type Reviewer struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name   string             `json:"name" bson:"name"`
+}
+
+// ReviewComment is a remark made on a processing plan during its review.
+type ReviewComment struct {
+	AuthorID  primitive.ObjectID `json:"author_id" bson:"author_id"`
+	Author    string             `json:"author" bson:"author"`
+	Status    PlanStatus         `json:"status,omitempty" bson:"status,omitempty"`
+	Comment   string             `json:"comment" bson:"comment"`
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+// PlanningObjective is what the process planner minimises when it orders the
+// bends of a part.
+type PlanningObjective string
+
+// Planning objectives
+const (
+	MinimiseTime        PlanningObjective = "time"
+	MinimiseToolChanges PlanningObjective = "tool_changes"
+	MinimiseHandling    PlanningObjective = "handling"
+)
+
+// PlanningConstraints are the engineer's requirements for a bending sequence.
+// Zero values leave the planner free: no fixed first or last bend, and no
+// limit on rotations or flips unless one is given.
+type PlanningConstraints struct {
+	FirstBendID    int64             `json:"first_bend_id,omitempty" bson:"first_bend_id,omitempty"`
+	LastBendID     int64             `json:"last_bend_id,omitempty" bson:"last_bend_id,omitempty"`
+	ForbiddenTools []string          `json:"forbidden_tools,omitempty" bson:"forbidden_tools,omitempty"`
+	MaxRotations   *int64            `json:"max_rotations,omitempty" bson:"max_rotations,omitempty"`
+	MaxFlips       *int64            `json:"max_flips,omitempty" bson:"max_flips,omitempty"`
+	Objective      PlanningObjective `json:"objective,omitempty" bson:"objective,omitempty"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+type ProcessedEventStatus string
+
+const (
+	EventInProgress ProcessedEventStatus = "InProgress"
+	EventDone       ProcessedEventStatus = "Done"
+)
+
+// ProcessedEvent is an entry in the ledger of events a listener has handled,
+// keyed by the event's ID.
+type ProcessedEvent struct {
+	ID          string               `json:"id" bson:"_id"`
+	EventName   string               `json:"event_name" bson:"event_name"`
+	Status      ProcessedEventStatus `json:"status" bson:"status"`
+	ClaimedAt   int64                `json:"claimed_at" bson:"claimed_at"`
+	CompletedAt int64                `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
@@ -8,5 +8,10 @@ type Project struct {
 	Title       string             `json:"title" bson:"title" validate:"empty=false"`
 	Description string             `json:"description" bson:"description" validate:"empty=false"`
 	OwnerID     primitive.ObjectID `json:"-" bson:"owner_id" validate:"empty=false"`
-	CreatedAt   int64              `json:"created_at" bson:"created_at" validate:"empty=false"`
+	// MachineID is the press brake the project's parts are made on.
+	MachineID primitive.ObjectID `json:"machine_id,omitempty" bson:"machine_id,omitempty"`
+	// BendingForceModel is the model used for the project's parts. Projects
+	// that do not choose one use their machine's model, or else LegacyBending.
+	BendingForceModel BendingForceModel `json:"bending_force_model,omitempty" bson:"bending_force_model,omitempty"`
+	CreatedAt         int64             `json:"created_at" bson:"created_at" validate:"empty=false"`
 }
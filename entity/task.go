@@ -15,7 +15,11 @@ type Task struct {
 	ProcessingTime             float64            `json:"-" bson:"processing_time" validate:"empty=false"`
 	EstimatedManufacturingTime float64            `json:"-" bson:"estimated_manufacturing_time" validate:"empty=false"`
 	TotalCost                  float64            `json:"-" bson:"total_cost" validate:"empty=false"`
+	Progress                   []Progress         `json:"progress,omitempty" bson:"progress,omitempty"`
 	CreatedAt                  int64              `json:"created_at" bson:"created_at" validate:"empty=false"`
+	Version                    int64              `json:"-" bson:"version"`
+	// ReportURL is the PDF made by a project report task.
+	ReportURL string `json:"report_url,omitempty" bson:"report_url,omitempty"`
 }
 
 type ProcessType string
@@ -24,13 +28,84 @@ type Status string
 const (
 	FeatureRecognition ProcessType = "Feature recognition"
 	ProcessPlanning    ProcessType = "Process planning"
+	ProjectReport      ProcessType = "Project report"
 	Complete           Status      = "Complete"
 	Processing         Status      = "Processing"
+	Failed             Status      = "Failed"
+	PartiallyFailed    Status      = "Partially failed"
 )
 
+// HasProcessed reports whether the CAD file has already been recorded as
+// processed by the given step.
+func (t *Task) HasProcessed(cadFileID primitive.ObjectID, processType ProcessType) bool {
+	for _, processed := range t.ProcessedCADFiles {
+		if processed.ID == cadFileID && processed.ProcessType == processType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Settle sets the task's final status once every CAD file has been processed.
+func (t *Task) Settle() {
+	if int64(len(t.ProcessedCADFiles)) < t.Quantity {
+		return
+	}
+
+	failed := 0
+	for _, processed := range t.ProcessedCADFiles {
+		if processed.Status == Failed {
+			failed++
+		}
+	}
+
+	switch failed {
+	case 0:
+		t.Status = Complete
+	case len(t.ProcessedCADFiles):
+		t.Status = Failed
+	default:
+		t.Status = PartiallyFailed
+	}
+}
+
+// UpdateProgress records the latest progress of a CAD file. Reports older than
+// the one already stored, e.g. redelivered ones, are ignored.
+func (t *Task) UpdateProgress(progress Progress) {
+	for i, current := range t.Progress {
+		if current.CADFileID != progress.CADFileID {
+			continue
+		}
+
+		if progress.UpdatedAt >= current.UpdatedAt {
+			t.Progress[i] = progress
+		}
+		return
+	}
+
+	t.Progress = append(t.Progress, progress)
+}
+
 type Processed struct {
-	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	FileName    string             `json:"filename" bson:"filename" validate:"empty=false"`
-	ProcessType ProcessType        `json:"process_type" bson:"process_type" validate:"empty=false"`
-	Status      Status             `json:"status" bson:"status" validate:"empty=false"`
+	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	FileName     string             `json:"filename" bson:"filename" validate:"empty=false"`
+	ProcessType  ProcessType        `json:"process_type" bson:"process_type" validate:"empty=false"`
+	Status       Status             `json:"status" bson:"status" validate:"empty=false"`
+	ErrorCode    string             `json:"error_code,omitempty" bson:"error_code,omitempty"`
+	ErrorMessage string             `json:"error_message,omitempty" bson:"error_message,omitempty"`
+	// PlanID is the processing plan a completed planning made. Later versions
+	// of the CAD file's plan do not change what the task cost.
+	PlanID primitive.ObjectID `json:"plan_id,omitempty" bson:"plan_id,omitempty"`
+}
+
+// Progress is the latest progress a worker reported for one CAD file.
+type Progress struct {
+	CADFileID   primitive.ObjectID `json:"cadfile_id" bson:"cadfile_id"`
+	FileName    string             `json:"filename" bson:"filename"`
+	ProcessType ProcessType        `json:"process_type" bson:"process_type"`
+	Stage       string             `json:"stage" bson:"stage"`
+	Percent     float64            `json:"percent" bson:"percent"`
+	Message     string             `json:"message" bson:"message"`
+	UpdatedAt   int64              `json:"updated_at" bson:"updated_at"`
 }
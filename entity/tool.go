@@ -8,5 +8,8 @@ type Tool struct {
 	Length    float64 `json:"-" bson:"length" validate:"empty=false"`
 	MinRadius float64 `json:"-" bson:"min_radius" validate:"empty=false"`
 	MaxRadius float64 `json:"-" bson:"max_radius" validate:"empty=false"`
-	CreatedAt int64   `json:"-" bson:"created_at" validate:"empty=false"`
+	// DieOpening is the width of the V-die used with the tool. Zero means the
+	// die opening is taken as 8 times the sheet thickness.
+	DieOpening float64 `json:"die_opening,omitempty" bson:"die_opening,omitempty"`
+	CreatedAt  int64   `json:"-" bson:"created_at" validate:"empty=false"`
 }
@@ -0,0 +1,53 @@
+package contracts
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Producer identifies this service as the source of the events it emits.
+const Producer = "fxtract-api"
+
+// Envelope carries the metadata shared by all events. It is embedded in every
+// contract, so its fields are serialized alongside the event's own fields.
+//
+//   - ID; unique per event, used to recognise redeliveries
+//   - SchemaVersion; the version of the event's payload schema
+//   - CorrelationID; the task the event belongs to
+//   - CausationID; the ID of the event that caused this one, if any
+type Envelope struct {
+	ID            string `json:"event_id,omitempty"`
+	SchemaVersion int    `json:"schema_version,omitempty"`
+	Timestamp     int64  `json:"timestamp,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	CausationID   string `json:"causation_id,omitempty"`
+	Producer      string `json:"producer,omitempty"`
+}
+
+// NewEnvelope creates the envelope of a new event.
+func NewEnvelope(schemaVersion int, correlationID string, causationID string) Envelope {
+	return Envelope{
+		ID:            primitive.NewObjectID().Hex(),
+		SchemaVersion: schemaVersion,
+		Timestamp:     time.Now().Unix(),
+		CorrelationID: correlationID,
+		CausationID:   causationID,
+		Producer:      Producer,
+	}
+}
+
+// UpcastUnversioned upgrades a payload sent before events had an envelope to
+// schema version 1. The task is the only correlation such events carry.
+func UpcastUnversioned(payload map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := payload["correlation_id"]; !ok {
+		payload["correlation_id"] = payload["task_id"]
+	}
+
+	return payload, nil
+}
+
+// Metadata returns the envelope of the event it is embedded in.
+func (e Envelope) Metadata() Envelope {
+	return e
+}
@@ -0,0 +1,71 @@
+package contracts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpcastUnversioned(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			"correlated by task",
+			map[string]interface{}{"task_id": "task-1"},
+			map[string]interface{}{"task_id": "task-1", "correlation_id": "task-1"},
+		},
+		{
+			"correlation kept",
+			map[string]interface{}{"task_id": "task-1", "correlation_id": "task-0"},
+			map[string]interface{}{"task_id": "task-1", "correlation_id": "task-0"},
+		},
+		{
+			"without task",
+			map[string]interface{}{"project_id": "project-1"},
+			map[string]interface{}{"project_id": "project-1", "correlation_id": nil},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := UpcastUnversioned(test.payload)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: UpcastUnversioned = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestUpcastProcessPlanningStartedV1(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			"unconstrained",
+			map[string]interface{}{"task_id": "task-1", "bend_count": 4.0},
+			map[string]interface{}{"task_id": "task-1", "bend_count": 4.0},
+		},
+		{
+			"stray constraints dropped",
+			map[string]interface{}{"task_id": "task-1", "constraints": map[string]interface{}{"first_bend_id": 2.0}},
+			map[string]interface{}{"task_id": "task-1"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := UpcastProcessPlanningStartedV1(test.payload)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: UpcastProcessPlanningStartedV1 = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package contracts
+
+import "strings"
+
+// eventID falls back to a natural key when an event carries no explicit ID.
+// A task processes every CAD file at most once per step, so the task and CAD
+// file identify an event of a given type; progress events add their stage.
+func eventID(id string, keys ...string) string {
+	if id != "" {
+		return id
+	}
+
+	return strings.Join(keys, ":")
+}
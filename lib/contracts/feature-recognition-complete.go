@@ -5,6 +5,7 @@ import (
 )
 
 type FeatureRecognitionComplete struct {
+	Envelope
 	UserID       string                 `json:"user_id"`
 	CADFileID    string                 `json:"cadfile_id"`
 	TaskID       string                 `json:"task_id" `
@@ -17,3 +18,13 @@ type FeatureRecognitionComplete struct {
 func (c *FeatureRecognitionComplete) EventName() string {
 	return "featureRecognitionComplete"
 }
+
+// EventVersion returns the version of the event's schema
+func (c *FeatureRecognitionComplete) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *FeatureRecognitionComplete) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID)
+}
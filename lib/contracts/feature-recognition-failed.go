@@ -0,0 +1,26 @@
+package contracts
+
+type FeatureRecognitionFailed struct {
+	Envelope
+	UserID       string `json:"user_id"`
+	CADFileID    string `json:"cadfile_id"`
+	TaskID       string `json:"task_id"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	EventType    string `json:"event_type"`
+}
+
+// EventName returns the event's name
+func (c *FeatureRecognitionFailed) EventName() string {
+	return "featureRecognitionFailed"
+}
+
+// EventVersion returns the version of the event's schema
+func (c *FeatureRecognitionFailed) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *FeatureRecognitionFailed) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID)
+}
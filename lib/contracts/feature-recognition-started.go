@@ -1,6 +1,7 @@
 package contracts
 
 type FeatureRecognitionStarted struct {
+	Envelope
 	TaskID    string `json:"task_id" `
 	URL       string `json:"url"`
 	CADFileID string `json:"cadfile_id"`
@@ -12,3 +13,13 @@ type FeatureRecognitionStarted struct {
 func (c *FeatureRecognitionStarted) EventName() string {
 	return "featureRecognitionStarted"
 }
+
+// EventVersion returns the version of the event's schema
+func (c *FeatureRecognitionStarted) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *FeatureRecognitionStarted) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID)
+}
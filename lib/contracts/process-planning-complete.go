@@ -5,6 +5,7 @@ import (
 )
 
 type ProcessPlanningComplete struct {
+	Envelope
 	UserID         string                `json:"user_id"`
 	CADFileID      string                `json:"cadfile_id"`
 	TaskID         string                `json:"task_id" `
@@ -17,3 +18,13 @@ type ProcessPlanningComplete struct {
 func (c *ProcessPlanningComplete) EventName() string {
 	return "processPlanningComplete"
 }
+
+// EventVersion returns the version of the event's schema
+func (c *ProcessPlanningComplete) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *ProcessPlanningComplete) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID)
+}
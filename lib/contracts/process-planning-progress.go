@@ -0,0 +1,29 @@
+package contracts
+
+import "fmt"
+
+type ProcessPlanningProgress struct {
+	Envelope
+	UserID    string  `json:"user_id"`
+	CADFileID string  `json:"cadfile_id"`
+	TaskID    string  `json:"task_id"`
+	Stage     string  `json:"stage"`
+	Percent   float64 `json:"percent"`
+	Message   string  `json:"message"`
+	EventType string  `json:"event_type"`
+}
+
+// EventName returns the event's name
+func (c *ProcessPlanningProgress) EventName() string {
+	return "processPlanningProgress"
+}
+
+// EventVersion returns the version of the event's schema
+func (c *ProcessPlanningProgress) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *ProcessPlanningProgress) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID, c.Stage, fmt.Sprintf("%g", c.Percent))
+}
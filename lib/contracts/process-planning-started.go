@@ -1,16 +1,41 @@
 package contracts
 
+import (
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
 type ProcessPlanningStarted struct {
-	UserID         string  `json:"user_id"`
-	CADFileID      string  `json:"cadfile_id"`
-	TaskID         string  `json:"task_id" `
-	BendCount      int64   `json:"bend_count"`
-	SerializedData string  `json:"serialized_data"`
-	EventType      string  `json:"event_type"`
-	FRETime        float64 `json:"fre_time"`
+	Envelope
+	UserID         string                      `json:"user_id"`
+	CADFileID      string                      `json:"cadfile_id"`
+	TaskID         string                      `json:"task_id" `
+	BendCount      int64                       `json:"bend_count"`
+	SerializedData string                      `json:"serialized_data"`
+	EventType      string                      `json:"event_type"`
+	FRETime        float64                     `json:"fre_time"`
+	Constraints    *entity.PlanningConstraints `json:"constraints,omitempty"`
 }
 
 // EventName returns the event's name
 func (c *ProcessPlanningStarted) EventName() string {
 	return "processPlanningStarted"
 }
+
+// EventVersion returns the version of the event's schema. Version 2 added
+// the planning constraints.
+func (c *ProcessPlanningStarted) EventVersion() int {
+	return 2
+}
+
+// EventID returns the event's unique ID
+func (c *ProcessPlanningStarted) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.CADFileID)
+}
+
+// UpcastProcessPlanningStartedV1 upgrades a version 1 payload, which always
+// asked for an unconstrained plan, to version 2.
+func UpcastProcessPlanningStartedV1(payload map[string]interface{}) (map[string]interface{}, error) {
+	delete(payload, "constraints")
+
+	return payload, nil
+}
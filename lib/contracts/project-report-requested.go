@@ -0,0 +1,26 @@
+package contracts
+
+// ProjectReportRequested asks for the manufacturing report of a project, a
+// single PDF of the processing plans of all its CAD files.
+type ProjectReportRequested struct {
+	Envelope
+	UserID    string `json:"user_id"`
+	ProjectID string `json:"project_id"`
+	TaskID    string `json:"task_id"`
+	EventType string `json:"event_type"`
+}
+
+// EventName returns the event's name
+func (c *ProjectReportRequested) EventName() string {
+	return "projectReportRequested"
+}
+
+// EventVersion returns the version of the event's schema
+func (c *ProjectReportRequested) EventVersion() int {
+	return 1
+}
+
+// EventID returns the event's unique ID
+func (c *ProjectReportRequested) EventID() string {
+	return eventID(c.ID, c.EventName(), c.TaskID, c.ProjectID)
+}
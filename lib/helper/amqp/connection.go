@@ -0,0 +1,150 @@
+package amqp
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrConnectionClosed is returned by Connection.Channel while the supervisor
+// is re-establishing a lost connection.
+var ErrConnectionClosed = errors.New("AMQP connection is closed")
+
+const maxReconnectInterval = time.Minute
+
+// Connection supervises an AMQP connection. It watches the underlying
+// connection for unexpected closes and redials with exponential backoff, so
+// that emitters and listeners sharing it can recover after a broker restart.
+// Users that hold broker-side state (exchanges, queues, consumers) register
+// with NotifyReconnect to recreate it once the connection is back.
+type Connection struct {
+	url           string
+	retryInterval time.Duration
+
+	mutex      sync.RWMutex
+	connection *amqp.Connection
+	reconnects []chan struct{}
+	closed     bool
+}
+
+// Dial connects to the broker, retrying every retryInterval until the first
+// connection succeeds, and then starts supervising it.
+func Dial(amqpURL string, retryInterval time.Duration) *Connection {
+	c := &Connection{
+		url:           amqpURL,
+		retryInterval: retryInterval,
+		connection:    <-RetryConnect(amqpURL, retryInterval),
+	}
+
+	go c.supervise(c.connection)
+
+	return c
+}
+
+// Channel opens a new channel on the current connection.
+func (c *Connection) Channel() (*amqp.Channel, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.connection == nil {
+		return nil, ErrConnectionClosed
+	}
+
+	return c.connection.Channel()
+}
+
+// IsConnected reports whether a connection to the broker is currently open.
+func (c *Connection) IsConnected() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.connection != nil && !c.connection.IsClosed()
+}
+
+// NotifyReconnect registers a channel that receives a value every time the
+// connection has been re-established. Sends never block; a reconnect that
+// happens while a previous one is still pending is coalesced with it.
+func (c *Connection) NotifyReconnect() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	reconnect := make(chan struct{}, 1)
+	c.reconnects = append(c.reconnects, reconnect)
+
+	return reconnect
+}
+
+// Close closes the connection and stops supervising it.
+func (c *Connection) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	if c.connection == nil {
+		return nil
+	}
+
+	return c.connection.Close()
+}
+
+func (c *Connection) supervise(connection *amqp.Connection) {
+	for {
+		reason, ok := <-connection.NotifyClose(make(chan *amqp.Error, 1))
+
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			return
+		}
+		c.connection = nil
+		c.mutex.Unlock()
+
+		if ok {
+			log.Printf("AMQP connection lost: %s", reason)
+		} else {
+			log.Println("AMQP connection lost")
+		}
+
+		connection = c.redial()
+
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			connection.Close()
+			return
+		}
+		c.connection = connection
+		reconnects := c.reconnects
+		c.mutex.Unlock()
+
+		for _, reconnect := range reconnects {
+			select {
+			case reconnect <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// redial keeps trying to connect, doubling the wait after every failed
+// attempt up to maxReconnectInterval.
+func (c *Connection) redial() *amqp.Connection {
+	interval := c.retryInterval
+	for {
+		connection, err := amqp.Dial(c.url)
+		if err == nil {
+			log.Println("AMQP connection re-established")
+			return connection
+		}
+
+		log.Printf("AMQP reconnection failed with error (retrying in %s): %s", interval.String(), err)
+		time.Sleep(interval)
+
+		if interval *= 2; interval > maxReconnectInterval {
+			interval = maxReconnectInterval
+		}
+	}
+}
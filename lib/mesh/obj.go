@@ -0,0 +1,122 @@
+// Package mesh reads the Wavefront OBJ meshes made by feature recognition and
+// draws them as shaded 2D projections.
+package mesh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NoFace is the face ID of polygons outside any named face group.
+const NoFace int64 = -1
+
+// Vec3 is a point or direction in model space.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// Polygon is a face of a mesh, given by indices into its vertices. FaceID is
+// the ID of the CAD face the polygon belongs to.
+type Polygon struct {
+	Vertices []int
+	FaceID   int64
+}
+
+// Mesh is a polygon mesh of a part.
+type Mesh struct {
+	Vertices []Vec3
+	Polygons []Polygon
+}
+
+// ParseOBJ reads a mesh from an OBJ file. Feature recognition writes each CAD
+// face as its own group or object, named with the face ID, e.g. "g face_12";
+// the ID is read from the trailing digits of the name. Normals, texture
+// coordinates and materials are ignored.
+func ParseOBJ(r io.Reader) (*Mesh, error) {
+	mesh := &Mesh{}
+	faceID := NoFace
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: vertex needs 3 coordinates", line)
+			}
+
+			var coords [3]float64
+			for i := range coords {
+				c, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+				coords[i] = c
+			}
+			mesh.Vertices = append(mesh.Vertices, Vec3{coords[0], coords[1], coords[2]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: face needs 3 vertices", line)
+			}
+
+			polygon := Polygon{FaceID: faceID}
+			for _, field := range fields[1:] {
+				index, err := strconv.Atoi(strings.SplitN(field, "/", 2)[0])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+
+				// Negative indices count back from the last vertex read.
+				if index < 0 {
+					index += len(mesh.Vertices)
+				} else {
+					index--
+				}
+
+				if index < 0 || index >= len(mesh.Vertices) {
+					return nil, fmt.Errorf("line %d: vertex %s does not exist", line, field)
+				}
+				polygon.Vertices = append(polygon.Vertices, index)
+			}
+			mesh.Polygons = append(mesh.Polygons, polygon)
+		case "g", "o":
+			faceID = NoFace
+			if len(fields) > 1 {
+				faceID = groupFaceID(fields[len(fields)-1])
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(mesh.Polygons) == 0 {
+		return nil, fmt.Errorf("mesh has no faces")
+	}
+
+	return mesh, nil
+}
+
+// groupFaceID returns the number at the end of a group name.
+func groupFaceID(name string) int64 {
+	start := len(name)
+	for start > 0 && name[start-1] >= '0' && name[start-1] <= '9' {
+		start--
+	}
+
+	id, err := strconv.ParseInt(name[start:], 10, 64)
+	if err != nil {
+		return NoFace
+	}
+
+	return id
+}
@@ -0,0 +1,94 @@
+package mesh
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOBJ(t *testing.T) {
+	obj := `# part.obj
+mtllib part.mtl
+v 0 0 0
+v 10 0 0
+v 10 5 0
+v 0 5 0
+vn 0 0 1
+vt 0 0
+
+f 1 2 3
+g face_12
+f 1/1/1 2/1/1 3/1/1 4/1/1
+o body face 7
+f -4 -3 -2
+g
+f 1 3 4
+`
+
+	mesh, err := ParseOBJ(strings.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vertices := []Vec3{{0, 0, 0}, {10, 0, 0}, {10, 5, 0}, {0, 5, 0}}
+	if !reflect.DeepEqual(mesh.Vertices, vertices) {
+		t.Errorf("vertices %v, want %v", mesh.Vertices, vertices)
+	}
+
+	polygons := []Polygon{
+		{Vertices: []int{0, 1, 2}, FaceID: NoFace},
+		{Vertices: []int{0, 1, 2, 3}, FaceID: 12},
+		{Vertices: []int{0, 1, 2}, FaceID: 7},
+		{Vertices: []int{0, 2, 3}, FaceID: NoFace},
+	}
+	if !reflect.DeepEqual(mesh.Polygons, polygons) {
+		t.Errorf("polygons %v, want %v", mesh.Polygons, polygons)
+	}
+}
+
+func TestParseOBJErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		want string
+	}{
+		{"empty", "", "mesh has no faces"},
+		{"vertices only", "v 0 0 0\nv 1 0 0\nv 0 1 0\n", "mesh has no faces"},
+		{"short vertex", "v 0 0\n", "line 1: vertex needs 3 coordinates"},
+		{"bad coordinate", "v 0 x 0\n", "line 1: "},
+		{"short face", "v 0 0 0\nv 1 0 0\nf 1 2\n", "line 3: face needs 3 vertices"},
+		{"bad index", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 a\n", "line 4: "},
+		{"index past the end", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 4\n", "line 4: vertex 4 does not exist"},
+		{"zero index", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n", "line 4: vertex 0 does not exist"},
+		{"negative index before the start", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -1 -2 -4\n", "line 4: vertex -4 does not exist"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseOBJ(strings.NewReader(test.obj))
+			if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+				t.Errorf("ParseOBJ error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestGroupFaceID(t *testing.T) {
+	tests := []struct {
+		name string
+		want int64
+	}{
+		{"face_12", 12},
+		{"12", 12},
+		{"face12", 12},
+		{"face_1_30", 30},
+		{"face", NoFace},
+		{"", NoFace},
+	}
+
+	for _, test := range tests {
+		if got := groupFaceID(test.name); got != test.want {
+			t.Errorf("groupFaceID(%q) = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
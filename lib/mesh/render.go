@@ -0,0 +1,164 @@
+package mesh
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Colours of rendered parts
+var (
+	Background = color.RGBA{255, 255, 255, 255}
+	PartColour = color.RGBA{176, 184, 190, 255}
+	Highlight  = color.RGBA{3, 166, 166, 255}
+)
+
+// margin is the share of the image left blank around the part.
+const margin = 0.08
+
+// light is the direction of the light on the part, in view space.
+var light = normalize(Vec3{-0.4, 0.6, 0.7})
+
+// Render draws an isometric view of the mesh. Polygons of the faces in
+// highlight are drawn in the highlight colour, the rest in the part colour,
+// and both sides of a polygon are lit so the winding order does not matter.
+func (m *Mesh) Render(width, height int, highlight map[int64]bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = Background.R, Background.G, Background.B, Background.A
+	}
+
+	if len(m.Vertices) == 0 {
+		return img
+	}
+
+	view := make([]Vec3, len(m.Vertices))
+	for i, v := range m.Vertices {
+		view[i] = isometric(v)
+	}
+
+	// Fit the projected part into the image, keeping its proportions.
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, v := range view {
+		minX, maxX = math.Min(minX, v.X), math.Max(maxX, v.X)
+		minY, maxY = math.Min(minY, v.Y), math.Max(maxY, v.Y)
+	}
+
+	usable := 1 - 2*margin
+	scale := math.Min(float64(width)*usable/math.Max(maxX-minX, 1e-9), float64(height)*usable/math.Max(maxY-minY, 1e-9))
+	offsetX := (float64(width) - (maxX-minX)*scale) / 2
+	offsetY := (float64(height) - (maxY-minY)*scale) / 2
+
+	screen := make([]Vec3, len(view))
+	for i, v := range view {
+		screen[i] = Vec3{
+			X: offsetX + (v.X-minX)*scale,
+			Y: float64(height) - offsetY - (v.Y-minY)*scale,
+			Z: v.Z,
+		}
+	}
+
+	depth := make([]float64, width*height)
+	for i := range depth {
+		depth[i] = math.Inf(-1)
+	}
+
+	for _, polygon := range m.Polygons {
+		base := PartColour
+		if highlight[polygon.FaceID] {
+			base = Highlight
+		}
+
+		// Polygons are drawn as fans of triangles from their first vertex.
+		for i := 1; i+1 < len(polygon.Vertices); i++ {
+			a, b, c := polygon.Vertices[0], polygon.Vertices[i], polygon.Vertices[i+1]
+
+			normal := normalize(cross(sub(view[b], view[a]), sub(view[c], view[a])))
+			shade := 0.35 + 0.65*math.Abs(dot(normal, light))
+
+			fill(img, depth, screen[a], screen[b], screen[c], shaded(base, shade))
+		}
+	}
+
+	return img
+}
+
+// fill draws a triangle, keeping the pixels nearest the viewer.
+func fill(img *image.RGBA, depth []float64, a, b, c Vec3, colour color.RGBA) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	area := edge(a, b, c)
+	if math.Abs(area) < 1e-12 {
+		return
+	}
+
+	minX := int(math.Max(0, math.Floor(math.Min(a.X, math.Min(b.X, c.X)))))
+	maxX := int(math.Min(float64(width-1), math.Ceil(math.Max(a.X, math.Max(b.X, c.X)))))
+	minY := int(math.Max(0, math.Floor(math.Min(a.Y, math.Min(b.Y, c.Y)))))
+	maxY := int(math.Min(float64(height-1), math.Ceil(math.Max(a.Y, math.Max(b.Y, c.Y)))))
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			p := Vec3{X: float64(x) + 0.5, Y: float64(y) + 0.5}
+
+			wa, wb, wc := edge(b, c, p)/area, edge(c, a, p)/area, edge(a, b, p)/area
+			if wa < 0 || wb < 0 || wc < 0 {
+				continue
+			}
+
+			z := wa*a.Z + wb*b.Z + wc*c.Z
+			if z <= depth[y*width+x] {
+				continue
+			}
+
+			depth[y*width+x] = z
+			img.SetRGBA(x, y, colour)
+		}
+	}
+}
+
+// isometric turns the model so that its X, Y and Z axes are equally
+// foreshortened. Z of the result points towards the viewer.
+func isometric(v Vec3) Vec3 {
+	const cos45, sin45 = math.Sqrt2 / 2, math.Sqrt2 / 2
+	tilt := math.Asin(math.Tan(math.Pi / 6))
+
+	// Turn 45 degrees about the vertical axis, then tilt towards the viewer.
+	x := v.Y*sin45 - v.X*cos45
+	y := v.X*sin45 + v.Y*cos45
+
+	return Vec3{
+		X: x,
+		Y: v.Z*math.Cos(tilt) - y*math.Sin(tilt),
+		Z: v.Z*math.Sin(tilt) + y*math.Cos(tilt),
+	}
+}
+
+func shaded(c color.RGBA, shade float64) color.RGBA {
+	return color.RGBA{uint8(float64(c.R) * shade), uint8(float64(c.G) * shade), uint8(float64(c.B) * shade), c.A}
+}
+
+func edge(a, b, p Vec3) float64 {
+	return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+}
+
+func sub(a, b Vec3) Vec3 {
+	return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+func cross(a, b Vec3) Vec3 {
+	return Vec3{a.Y*b.Z - a.Z*b.Y, a.Z*b.X - a.X*b.Z, a.X*b.Y - a.Y*b.X}
+}
+
+func dot(a, b Vec3) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func normalize(v Vec3) Vec3 {
+	length := math.Sqrt(dot(v, v))
+	if length == 0 {
+		return v
+	}
+
+	return Vec3{v.X / length, v.Y / length, v.Z / length}
+}
@@ -0,0 +1,123 @@
+package amqp
+
+import (
+	"fmt"
+
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/streadway/amqp"
+)
+
+type amqpDeadLetterQueue struct {
+	listener *amqpEventListener
+}
+
+// Inspect fetches messages from the dead-letter queue without acknowledging
+// them. Closing the channel afterwards puts them all back in place.
+func (q *amqpDeadLetterQueue) Inspect(limit int) ([]msgqueue.DeadLetter, error) {
+	channel, err := q.listener.connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	defer channel.Close()
+
+	deadLetters := []msgqueue.DeadLetter{}
+	for limit <= 0 || len(deadLetters) < limit {
+		msg, ok, err := channel.Get(q.listener.deadLetterQueue(), false)
+		if err != nil {
+			return nil, fmt.Errorf("could not read queue %s: %s", q.listener.deadLetterQueue(), err)
+		}
+
+		if !ok {
+			break
+		}
+
+		deadLetters = append(deadLetters, toDeadLetter(msg))
+	}
+
+	return deadLetters, nil
+}
+
+// Replay publishes dead-lettered messages straight to the listener's queue
+// with their retry count reset. Each message is only acknowledged once the
+// broker has confirmed its copy; on failure it stays in the dead-letter queue.
+func (q *amqpDeadLetterQueue) Replay(limit int) (int, error) {
+	channel, err := q.listener.connection.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer channel.Close()
+
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		msg, ok, err := channel.Get(q.listener.deadLetterQueue(), false)
+		if err != nil {
+			return replayed, fmt.Errorf("could not read queue %s: %s", q.listener.deadLetterQueue(), err)
+		}
+
+		if !ok {
+			break
+		}
+
+		headers := copyHeaders(msg.Headers)
+		delete(headers, retryCountHeader)
+		delete(headers, deathReasonHeader)
+		delete(headers, originalQueueHeader)
+
+		err = q.listener.publish("", q.listener.queue, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			Body:          msg.Body,
+		})
+		if err != nil {
+			return replayed, fmt.Errorf("could not replay message: %s", err)
+		}
+
+		if err := msg.Ack(false); err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (q *amqpDeadLetterQueue) Purge() (int, error) {
+	channel, err := q.listener.connection.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer channel.Close()
+
+	return channel.QueuePurge(q.listener.deadLetterQueue(), false)
+}
+
+func toDeadLetter(msg amqp.Delivery) msgqueue.DeadLetter {
+	deadLetter := msgqueue.DeadLetter{
+		Attempts:       retryCount(msg.Headers),
+		Payload:        string(msg.Body),
+		DeadLetteredAt: msg.Timestamp.Unix(),
+	}
+
+	if eventName, ok := msg.Headers[eventNameHeader].(string); ok {
+		deadLetter.EventName = eventName
+	}
+
+	if queue, ok := msg.Headers[originalQueueHeader].(string); ok {
+		deadLetter.Queue = queue
+	}
+
+	if reason, ok := msg.Headers[deathReasonHeader].(string); ok {
+		deadLetter.Reason = reason
+	}
+
+	return deadLetter
+}
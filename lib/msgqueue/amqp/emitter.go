@@ -2,7 +2,9 @@ package amqp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"time"
 
@@ -11,9 +13,43 @@ import (
 	"github.com/streadway/amqp"
 )
 
+// publishIndexHeader carries a message's position in its batch, so that a
+// basic.return can be matched to the event it belongs to.
+const publishIndexHeader = "x-publish-index"
+
+// ErrNotConfirmed is returned by Emit when the broker negatively acknowledged
+// the message or did not confirm it in time.
+var ErrNotConfirmed = errors.New("event was not confirmed by the broker")
+
+// EmitterOptions tunes an AMQP event emitter.
+//
+//   - PoolSize; the number of long-lived confirm-mode channels shared by Emit
+//   - BatchSize; when greater than one, Emit hands events to a background
+//     publisher that sends up to BatchSize events before waiting for their
+//     confirms together
+//   - BatchLinger; how long the background publisher waits for a batch to
+//     fill up before sending what it has
+//   - ConfirmTimeout; how long to wait for the broker to confirm a publish
+type EmitterOptions struct {
+	PoolSize       int
+	BatchSize      int
+	BatchLinger    time.Duration
+	ConfirmTimeout time.Duration
+}
+
+// DefaultEmitterOptions publishes synchronously on a small channel pool.
+var DefaultEmitterOptions = EmitterOptions{
+	PoolSize:       4,
+	BatchSize:      1,
+	BatchLinger:    10 * time.Millisecond,
+	ConfirmTimeout: 30 * time.Second,
+}
+
 type amqpEventEmitter struct {
-	connection *amqp.Connection
+	connection *amqphelper.Connection
 	exchange   string
+	options    EmitterOptions
+	channels   chan *confirmChannel
 	events     chan *emittedEvent
 }
 
@@ -22,6 +58,14 @@ type emittedEvent struct {
 	errorChan chan error
 }
 
+// confirmChannel is a channel in confirm mode together with the notification
+// channels the broker uses to confirm or return messages published on it.
+type confirmChannel struct {
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+}
+
 // NewAMQPEventEmitterFromEnvironment will create a new event emitter from
 // the configured environment variables. Important variables are:
 //
@@ -41,30 +85,72 @@ func NewAMQPEventEmitterFromEnvironment() (msgqueue.EventEmitter, error) {
 		exchange = "example"
 	}
 
-	conn := <-amqphelper.RetryConnect(url, 5*time.Second)
-	return NewAMQPEventEmitter(conn, exchange)
+	conn := amqphelper.Dial(url, 5*time.Second)
+	return NewAMQPEventEmitter(conn, exchange, DefaultEmitterOptions)
 }
 
 // NewAMQPEventEmitter creates a new event emitter.
 // It will need an AMQP connection passed as parameter and use this connection
-// to create its own channel (note: AMQP channels are not thread-safe, so just
+// to create its own channels (note: AMQP channels are not thread-safe, so just
 // accepting the connection as a parameter and then creating our own private
-// channel is the safest way to ensure this).
-func NewAMQPEventEmitter(conn *amqp.Connection, exchange string) (msgqueue.EventEmitter, error) {
+// channels is the safest way to ensure this).
+//
+// Every event is published as mandatory on a confirm-mode channel, and Emit
+// only returns nil once the broker has confirmed that the event was routed.
+// While the connection is down Emit fails fast with
+// msgqueue.ErrBrokerUnavailable; once it is re-established the exchange is
+// redeclared and the channel pool rebuilt.
+func NewAMQPEventEmitter(conn *amqphelper.Connection, exchange string, options EmitterOptions) (msgqueue.EventEmitter, error) {
+	if options.PoolSize < 1 {
+		options.PoolSize = 1
+	}
+
+	if options.BatchSize < 1 {
+		options.BatchSize = 1
+	}
+
 	emitter := amqpEventEmitter{
 		connection: conn,
 		exchange:   exchange,
+		options:    options,
+		channels:   make(chan *confirmChannel, options.PoolSize),
 	}
 
+	reconnects := conn.NotifyReconnect()
+
 	err := emitter.setup()
 	if err != nil {
 		return nil, err
 	}
 
+	go emitter.recover(reconnects)
+
+	if options.BatchSize > 1 {
+		emitter.events = make(chan *emittedEvent, options.BatchSize)
+		go emitter.publishBatches()
+	}
+
 	return &emitter, nil
 }
 
 func (a *amqpEventEmitter) setup() error {
+	if err := a.declare(); err != nil {
+		return err
+	}
+
+	for i := 0; i < a.options.PoolSize; i++ {
+		confirmChannel, err := a.openChannel()
+		if err != nil {
+			return err
+		}
+
+		a.channels <- confirmChannel
+	}
+
+	return nil
+}
+
+func (a *amqpEventEmitter) declare() error {
 	channel, err := a.connection.Channel()
 	if err != nil {
 		return err
@@ -74,29 +160,193 @@ func (a *amqpEventEmitter) setup() error {
 
 	// Normally, all(many) of these options should be configurable.
 	// For our example, it'll probably do.
-	err = channel.ExchangeDeclare(a.exchange, "topic", true, false, false, false, nil)
-	return err
+	return channel.ExchangeDeclare(a.exchange, "topic", true, false, false, false, nil)
 }
 
-func (a *amqpEventEmitter) Emit(event msgqueue.Event) error {
+// recover redeclares the exchange and swaps every pooled channel for a fresh
+// one each time the connection has been re-established.
+func (a *amqpEventEmitter) recover(reconnects <-chan struct{}) {
+	for range reconnects {
+		if err := a.declare(); err != nil {
+			log.Printf("could not redeclare exchange %s: %s", a.exchange, err)
+		}
+
+		// Publishers holding a stale channel fail quickly and hand it back,
+		// so taking the whole pool does not block for long.
+		stale := make([]*confirmChannel, 0, a.options.PoolSize)
+		for i := 0; i < a.options.PoolSize; i++ {
+			stale = append(stale, <-a.channels)
+		}
+
+		for _, channel := range stale {
+			a.release(channel, false)
+		}
+	}
+}
+
+func (a *amqpEventEmitter) openChannel() (*confirmChannel, error) {
 	channel, err := a.connection.Channel()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer channel.Close()
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("could not put channel into confirm mode: %s", err)
+	}
 
-	jsonBody, err := json.Marshal(event)
+	// The notification channels must be able to hold a confirm and a return
+	// for every message in flight, or the connection's reader would block.
+	return &confirmChannel{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, a.options.BatchSize)),
+		returns:  channel.NotifyReturn(make(chan amqp.Return, a.options.BatchSize)),
+	}, nil
+}
+
+// release puts a channel back into the pool. Channels that failed are closed
+// and replaced, so that stray confirms never reach the next publisher.
+func (a *amqpEventEmitter) release(channel *confirmChannel, healthy bool) {
+	if healthy {
+		a.channels <- channel
+		return
+	}
+
+	channel.channel.Close()
+
+	replacement, err := a.openChannel()
 	if err != nil {
-		return fmt.Errorf("could not JSON-serialize event: %s", err)
+		// Keep the pool size constant; the next publish on this channel
+		// fails and triggers another replacement attempt.
+		a.channels <- channel
+		return
 	}
 
-	msg := amqp.Publishing{
-		Headers:     amqp.Table{"x-event-name": event.EventName()},
-		ContentType: "application/json",
-		Body:        jsonBody,
+	a.channels <- replacement
+}
+
+func (a *amqpEventEmitter) Emit(event msgqueue.Event) error {
+	if !a.connection.IsConnected() {
+		return fmt.Errorf("%w: could not emit %s", msgqueue.ErrBrokerUnavailable, event.EventName())
+	}
+
+	if a.events != nil {
+		emitted := &emittedEvent{event: event, errorChan: make(chan error, 1)}
+		a.events <- emitted
+		return <-emitted.errorChan
 	}
 
-	err = channel.Publish(a.exchange, event.EventName(), false, false, msg)
-	return err
+	errs := a.publish([]msgqueue.Event{event})
+	return errs[0]
+}
+
+// publishBatches collects events handed over by Emit and publishes them in
+// batches, reporting each event's outcome on its error channel.
+func (a *amqpEventEmitter) publishBatches() {
+	for first := range a.events {
+		batch := []*emittedEvent{first}
+		linger := time.After(a.options.BatchLinger)
+
+	collect:
+		for len(batch) < a.options.BatchSize {
+			select {
+			case emitted := <-a.events:
+				batch = append(batch, emitted)
+			case <-linger:
+				break collect
+			}
+		}
+
+		events := make([]msgqueue.Event, len(batch))
+		for i, emitted := range batch {
+			events[i] = emitted.event
+		}
+
+		errs := a.publish(events)
+		for i, emitted := range batch {
+			emitted.errorChan <- errs[i]
+		}
+	}
+}
+
+// publish sends the events on a pooled channel and waits until the broker has
+// confirmed all of them. It returns one error per event.
+func (a *amqpEventEmitter) publish(events []msgqueue.Event) []error {
+	errs := make([]error, len(events))
+
+	channel := <-a.channels
+	healthy := true
+	defer func() { a.release(channel, healthy) }()
+
+	for i, event := range events {
+		jsonBody, err := json.Marshal(event)
+		if err != nil {
+			errs[i] = fmt.Errorf("could not JSON-serialize event: %s", err)
+			continue
+		}
+
+		msg := amqp.Publishing{
+			Headers: amqp.Table{
+				"x-event-name":     event.EventName(),
+				publishIndexHeader: int32(i),
+			},
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         jsonBody,
+		}
+
+		err = channel.channel.Publish(a.exchange, event.EventName(), true, false, msg)
+		if err != nil {
+			errs[i] = err
+			healthy = false
+		}
+	}
+
+	// Confirms arrive in publishing order, so they line up with the events
+	// that were actually published. The whole batch shares one deadline; once
+	// it has passed, the events still waiting are not confirmed.
+	timer := time.NewTimer(a.options.ConfirmTimeout)
+	defer timer.Stop()
+
+	expired := false
+	for i := range events {
+		if errs[i] != nil {
+			continue
+		}
+
+		if expired {
+			errs[i] = ErrNotConfirmed
+			continue
+		}
+
+		select {
+		case confirm, ok := <-channel.confirms:
+			if !ok {
+				errs[i] = ErrNotConfirmed
+				healthy = false
+				continue
+			}
+
+			if !confirm.Ack {
+				errs[i] = ErrNotConfirmed
+			}
+		case <-timer.C:
+			expired = true
+			errs[i] = ErrNotConfirmed
+			healthy = false
+		}
+	}
+
+	// A mandatory message that could not be routed is returned before it is
+	// confirmed, so all returns for this batch are buffered by now.
+	for {
+		select {
+		case returned := <-channel.returns:
+			if i, ok := returned.Headers[publishIndexHeader].(int32); ok && i >= 0 && int(i) < len(errs) {
+				errs[i] = fmt.Errorf("%w: %s (%s)", msgqueue.ErrUnroutable, returned.RoutingKey, returned.ReplyText)
+			}
+		default:
+			return errs
+		}
+	}
 }
@@ -2,7 +2,10 @@ package amqp
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	amqphelper "github.com/WilfredDube/fxtract-backend/lib/helper/amqp"
@@ -10,13 +13,51 @@ import (
 	"github.com/streadway/amqp"
 )
 
-const eventNameHeader = "x-event-name"
+const (
+	eventNameHeader     = "x-event-name"
+	retryCountHeader    = "x-retry-count"
+	deathReasonHeader   = "x-death-reason"
+	originalQueueHeader = "x-original-queue"
+)
+
+const (
+	// republishTimeout is how long a move to a retry or dead-letter queue
+	// waits for the broker to confirm it.
+	republishTimeout = 10 * time.Second
+
+	// requeueDelay is how long a message that could not be moved is held
+	// before it is put back on the queue, so that a broken publisher is not
+	// retried in a tight loop.
+	requeueDelay = 5 * time.Second
+)
 
 type amqpEventListener struct {
-	connection *amqp.Connection
+	connection *amqphelper.Connection
 	exchange   string
 	queue      string
 	mapper     msgqueue.EventMapper
+	retry      msgqueue.RetryPolicy
+	prefetch   int
+
+	// publisher is used to move messages to the retry and dead-letter queues.
+	// It is separate from the consuming channel, in confirm mode, and guarded
+	// by publishMutex. It is nil after a failed publish until it is reopened.
+	publisher         *amqp.Channel
+	publisherConfirms chan amqp.Confirmation
+	publishMutex      sync.Mutex
+
+	// subscriptions holds every active Listen call so that it can be bound
+	// and consumed again after a reconnect.
+	subscriptions      []*subscription
+	subscriptionsMutex sync.Mutex
+}
+
+// subscription keeps the channels handed out by Listen. They outlive the
+// AMQP channel that feeds them, which is replaced on every reconnect.
+type subscription struct {
+	eventNames []string
+	deliveries chan msgqueue.Delivery
+	errors     chan error
 }
 
 // NewAMQPEventListenerFromEnvironment will create a new event listener from
@@ -25,12 +66,14 @@ type amqpEventListener struct {
 //   - AMQP_URL; the URL of the AMQP broker to connect to
 //   - AMQP_EXCHANGE; the name of the exchange to bind to
 //   - AMQP_QUEUE; the name of the queue to bind and subscribe
+//   - AMQP_PREFETCH; how many unacknowledged messages a subscription may hold
 //
 // For missing environment variables, this function will assume sane defaults.
 func NewAMQPEventListenerFromEnvironment() (msgqueue.EventListener, error) {
 	var url string
 	var exchange string
 	var queue string
+	var prefetch int
 
 	if url = os.Getenv("AMQP_URL"); url == "" {
 		url = "amqp://localhost:5672"
@@ -44,8 +87,12 @@ func NewAMQPEventListenerFromEnvironment() (msgqueue.EventListener, error) {
 		queue = "example"
 	}
 
-	conn := <-amqphelper.RetryConnect(url, 5*time.Second)
-	return NewAMQPEventListener(conn, exchange, queue)
+	if p := os.Getenv("AMQP_PREFETCH"); p != "" {
+		prefetch, _ = strconv.Atoi(p)
+	}
+
+	conn := amqphelper.Dial(url, 5*time.Second)
+	return NewAMQPEventListener(conn, exchange, queue, msgqueue.DefaultRetryPolicy, prefetch)
 }
 
 // NewAMQPEventListener creates a new event listener.
@@ -53,19 +100,36 @@ func NewAMQPEventListenerFromEnvironment() (msgqueue.EventListener, error) {
 // to create its own channel (note: AMQP channels are not thread-safe, so just
 // accepting the connection as a parameter and then creating our own private
 // channel is the safest way to ensure this).
-func NewAMQPEventListener(conn *amqp.Connection, exchange string, queue string) (msgqueue.EventListener, error) {
+//
+// Rejected deliveries are retried through one delay queue per backoff step
+// (<queue>.retry.<ms>) and dead-lettered to <queue>.dead on the
+// <exchange>.dlx exchange once the retry policy is exhausted.
+//
+// Each subscription holds at most prefetch unacknowledged messages; zero
+// leaves the number unlimited.
+//
+// When the supervised connection is re-established, all exchanges and queues
+// are redeclared and every active Listen subscription is bound and consumed
+// again, feeding the same channels that Listen originally returned.
+func NewAMQPEventListener(conn *amqphelper.Connection, exchange string, queue string, retry msgqueue.RetryPolicy, prefetch int) (msgqueue.EventListener, error) {
 	listener := amqpEventListener{
 		connection: conn,
 		exchange:   exchange,
 		queue:      queue,
 		mapper:     msgqueue.NewEventMapper(),
+		retry:      retry,
+		prefetch:   prefetch,
 	}
 
+	reconnects := conn.NotifyReconnect()
+
 	err := listener.setup()
 	if err != nil {
 		return nil, err
 	}
 
+	go listener.recover(reconnects)
+
 	return &listener, nil
 }
 
@@ -87,66 +151,339 @@ func (a *amqpEventListener) setup() error {
 		return fmt.Errorf("could not declare queue %s: %s", a.queue, err)
 	}
 
+	err = channel.ExchangeDeclare(a.deadLetterExchange(), "direct", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = channel.QueueDeclare(a.deadLetterQueue(), true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not declare queue %s: %s", a.deadLetterQueue(), err)
+	}
+
+	err = channel.QueueBind(a.deadLetterQueue(), a.queue, a.deadLetterExchange(), false, nil)
+	if err != nil {
+		return fmt.Errorf("could not bind queue %s: %s", a.deadLetterQueue(), err)
+	}
+
+	// Messages sit in a delay queue until their TTL expires and are then
+	// dead-lettered back onto the listener's queue via the default exchange.
+	for attempt := 1; attempt <= a.retry.MaxRetries; attempt++ {
+		delay := a.retry.Backoff(attempt)
+		_, err = channel.QueueDeclare(a.retryQueue(attempt), true, false, false, false, amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": a.queue,
+		})
+		if err != nil {
+			return fmt.Errorf("could not declare queue %s: %s", a.retryQueue(attempt), err)
+		}
+	}
+
+	a.publishMutex.Lock()
+	defer a.publishMutex.Unlock()
+
+	return a.openPublisher()
+}
+
+// openPublisher replaces the publisher with a new channel in confirm mode.
+// The caller must hold publishMutex.
+func (a *amqpEventListener) openPublisher() error {
+	if a.publisher != nil {
+		a.publisher.Close()
+		a.publisher = nil
+	}
+
+	publisher, err := a.connection.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err := publisher.Confirm(false); err != nil {
+		publisher.Close()
+		return fmt.Errorf("could not put channel into confirm mode: %s", err)
+	}
+
+	a.publisher = publisher
+	a.publisherConfirms = publisher.NotifyPublish(make(chan amqp.Confirmation, 1))
+
 	return nil
 }
 
+// recover redeclares the listener's topology and resubscribes every active
+// Listen call each time the connection has been re-established.
+func (a *amqpEventListener) recover(reconnects <-chan struct{}) {
+	for range reconnects {
+		if err := a.setup(); err != nil {
+			log.Printf("could not redeclare queue %s after reconnect: %s", a.queue, err)
+			continue
+		}
+
+		a.subscriptionsMutex.Lock()
+		subscriptions := a.subscriptions
+		a.subscriptionsMutex.Unlock()
+
+		for _, sub := range subscriptions {
+			if err := a.subscribe(sub); err != nil {
+				log.Printf("could not resubscribe to queue %s: %s", a.queue, err)
+			}
+		}
+
+		log.Printf("resubscribed to queue %s", a.queue)
+	}
+}
+
+func (a *amqpEventListener) deadLetterExchange() string {
+	return a.exchange + ".dlx"
+}
+
+func (a *amqpEventListener) deadLetterQueue() string {
+	return a.queue + ".dead"
+}
+
+func (a *amqpEventListener) retryQueue(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", a.queue, a.retry.Backoff(attempt)/time.Millisecond)
+}
+
 // Listen configures the event listener to listen for a set of events that are
 // specified by name as parameter.
 // This method will return two channels: One will contain successfully decoded
 // events, the other will contain errors for messages that could not be
-// successfully decoded.
-func (l *amqpEventListener) Listen(eventNames ...string) (<-chan msgqueue.Event, <-chan error, error) {
+// successfully decoded. Messages that cannot be decoded are dead-lettered.
+func (l *amqpEventListener) Listen(eventNames ...string) (<-chan msgqueue.Delivery, <-chan error, error) {
+	sub := &subscription{
+		eventNames: eventNames,
+		deliveries: make(chan msgqueue.Delivery),
+		errors:     make(chan error),
+	}
+
+	if err := l.subscribe(sub); err != nil {
+		return nil, nil, err
+	}
+
+	l.subscriptionsMutex.Lock()
+	l.subscriptions = append(l.subscriptions, sub)
+	l.subscriptionsMutex.Unlock()
+
+	return sub.deliveries, sub.errors, nil
+}
+
+// subscribe binds the subscription's events on a new channel and starts
+// feeding its deliveries. The feeding goroutine ends when the channel closes.
+func (l *amqpEventListener) subscribe(sub *subscription) error {
 	channel, err := l.connection.Channel()
 	if err != nil {
-		return nil, nil, err
+		return err
+	}
+
+	if err := channel.Qos(l.prefetch, 0, false); err != nil {
+		channel.Close()
+		return fmt.Errorf("could not set prefetch count: %s", err)
 	}
 
 	// Create binding between queue and exchange for each listened event type
-	for _, event := range eventNames {
+	for _, event := range sub.eventNames {
 		if err := channel.QueueBind(l.queue, event, l.exchange, false, nil); err != nil {
-			return nil, nil, fmt.Errorf("could not bind event %s to queue %s: %s", event, l.queue, err)
+			channel.Close()
+			return fmt.Errorf("could not bind event %s to queue %s: %s", event, l.queue, err)
 		}
 	}
 
 	msgs, err := channel.Consume(l.queue, "", false, false, false, false, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not consume queue: %s", err)
+		channel.Close()
+		return fmt.Errorf("could not consume queue: %s", err)
 	}
 
-	events := make(chan msgqueue.Event)
-	errors := make(chan error)
-
 	go func() {
 		for msg := range msgs {
 			rawEventName, ok := msg.Headers[eventNameHeader]
 			if !ok {
-				errors <- fmt.Errorf("message did not contain %s header", eventNameHeader)
-				msg.Nack(false, false)
+				err := fmt.Errorf("message did not contain %s header", eventNameHeader)
+				l.deadLetter(msg, err)
+				sub.errors <- err
 				continue
 			}
 
 			eventName, ok := rawEventName.(string)
 			if !ok {
-				errors <- fmt.Errorf("header %s did not contain string", eventNameHeader)
-				msg.Nack(false, false)
+				err := fmt.Errorf("header %s did not contain string", eventNameHeader)
+				l.deadLetter(msg, err)
+				sub.errors <- err
 				continue
 			}
 
 			event, err := l.mapper.MapEvent(eventName, msg.Body)
 			if err != nil {
-				errors <- fmt.Errorf("could not unmarshal event %s: %s", eventName, err)
-				msg.Nack(false, false)
+				err = fmt.Errorf("could not unmarshal event %s: %s", eventName, err)
+				l.deadLetter(msg, err)
+				sub.errors <- err
 				continue
 			}
 
-			events <- event
-			msg.Ack(false)
+			sub.deliveries <- &amqpDelivery{listener: l, msg: msg, event: event}
 		}
 	}()
 
-	return events, errors, nil
+	return nil
 }
 
 func (l *amqpEventListener) Mapper() msgqueue.EventMapper {
 	return l.mapper
 }
+
+func (l *amqpEventListener) DeadLetters() msgqueue.DeadLetterQueue {
+	return &amqpDeadLetterQueue{listener: l}
+}
+
+// retryLater republishes the message to the delay queue of the next attempt
+// and acknowledges the original.
+func (l *amqpEventListener) retryLater(msg amqp.Delivery, attempt int) error {
+	headers := copyHeaders(msg.Headers)
+	headers[retryCountHeader] = int32(attempt)
+
+	err := l.publish("", l.retryQueue(attempt), amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: msg.CorrelationId,
+		MessageId:     msg.MessageId,
+		Timestamp:     msg.Timestamp,
+		Type:          msg.Type,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		// Leave the message on the queue rather than losing it.
+		requeueLater(msg)
+		return fmt.Errorf("could not schedule retry: %s", err)
+	}
+
+	return msg.Ack(false)
+}
+
+// deadLetter moves the message to the listener's dead-letter queue and
+// acknowledges the original.
+func (l *amqpEventListener) deadLetter(msg amqp.Delivery, reason error) error {
+	headers := copyHeaders(msg.Headers)
+	headers[deathReasonHeader] = reason.Error()
+	headers[originalQueueHeader] = l.queue
+
+	err := l.publish(l.deadLetterExchange(), l.queue, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: msg.CorrelationId,
+		MessageId:     msg.MessageId,
+		Timestamp:     time.Now(),
+		Type:          msg.Type,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		requeueLater(msg)
+		return fmt.Errorf("could not dead-letter message: %s", err)
+	}
+
+	return msg.Ack(false)
+}
+
+// publish sends a message and waits until the broker has confirmed it, so
+// that the original is only acknowledged once its copy is safe. The publisher
+// is reopened after any failure, dropping confirms that may still arrive.
+func (l *amqpEventListener) publish(exchange string, key string, msg amqp.Publishing) error {
+	l.publishMutex.Lock()
+	defer l.publishMutex.Unlock()
+
+	if l.publisher == nil {
+		if err := l.openPublisher(); err != nil {
+			return err
+		}
+	}
+
+	if err := l.publisher.Publish(exchange, key, false, false, msg); err != nil {
+		l.openPublisher()
+		return err
+	}
+
+	timer := time.NewTimer(republishTimeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-l.publisherConfirms:
+		if !ok {
+			l.openPublisher()
+			return ErrNotConfirmed
+		}
+
+		if !confirm.Ack {
+			return ErrNotConfirmed
+		}
+
+		return nil
+	case <-timer.C:
+		l.openPublisher()
+		return ErrNotConfirmed
+	}
+}
+
+// requeueLater puts a message back on its queue after requeueDelay. Until
+// then it stays unacknowledged and counts against the prefetch limit, which
+// slows consumption down while messages can not be moved.
+func requeueLater(msg amqp.Delivery) {
+	time.AfterFunc(requeueDelay, func() {
+		if err := msg.Nack(false, true); err != nil {
+			log.Printf("could not requeue message: %s", err)
+		}
+	})
+}
+
+type amqpDelivery struct {
+	listener *amqpEventListener
+	msg      amqp.Delivery
+	event    msgqueue.Event
+}
+
+func (d *amqpDelivery) Event() msgqueue.Event {
+	return d.event
+}
+
+func (d *amqpDelivery) Attempt() int {
+	return retryCount(d.msg.Headers)
+}
+
+func (d *amqpDelivery) Ack() error {
+	return d.msg.Ack(false)
+}
+
+func (d *amqpDelivery) Reject(err error) error {
+	if !d.WillRetry(err) {
+		return d.listener.deadLetter(d.msg, err)
+	}
+
+	return d.listener.retryLater(d.msg, d.Attempt()+1)
+}
+
+func (d *amqpDelivery) WillRetry(err error) bool {
+	return d.listener.retry.ShouldRetry(d.Attempt(), err)
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := amqp.Table{}
+	for key, value := range headers {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+func retryCount(headers amqp.Table) int {
+	switch count := headers[retryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	}
+
+	return 0
+}
@@ -7,13 +7,21 @@ import (
 
 	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
 	"github.com/WilfredDube/fxtract-backend/lib/msgqueue/amqp"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue/memory"
 )
 
 func NewEventListenerFromEnvironment() (msgqueue.EventListener, error) {
 	var listener msgqueue.EventListener
 	var err error
 
-	if url := os.Getenv("AMQP_URL"); url != "" {
+	if backend := os.Getenv("MSGQUEUE_BACKEND"); backend == "memory" {
+		log.Printf("using in-memory event broker")
+
+		listener, err = memory.NewMemoryEventListenerFromEnvironment()
+		if err != nil {
+			return nil, err
+		}
+	} else if url := os.Getenv("AMQP_URL"); url != "" {
 		log.Printf("connecting to AMQP broker at %s", url)
 
 		listener, err = amqp.NewAMQPEventListenerFromEnvironment()
@@ -23,7 +31,7 @@ func NewEventListenerFromEnvironment() (msgqueue.EventListener, error) {
 	} else if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
 		log.Printf("connecting to Kafka brokers at %s", brokers)
 	} else {
-		return nil, errors.New("Neither AMQP_URL nor KAFKA_BROKERS specified")
+		return nil, errors.New("Neither MSGQUEUE_BACKEND=memory, AMQP_URL nor KAFKA_BROKERS specified")
 	}
 
 	return listener, nil
@@ -0,0 +1,24 @@
+package msgqueue
+
+// DeadLetter is a message that could not be decoded or handled and has been
+// moved out of its queue.
+type DeadLetter struct {
+	EventName      string `json:"event_name"`
+	Queue          string `json:"queue"`
+	Reason         string `json:"reason"`
+	Attempts       int    `json:"attempts"`
+	Payload        string `json:"payload"`
+	DeadLetteredAt int64  `json:"dead_lettered_at"`
+}
+
+// DeadLetterQueue gives access to the dead-lettered messages of a listener's
+// queue. A limit of zero or less means all messages.
+type DeadLetterQueue interface {
+	// Inspect returns dead-lettered messages without removing them.
+	Inspect(limit int) ([]DeadLetter, error)
+	// Replay moves dead-lettered messages back onto the listener's queue with
+	// a fresh retry budget and returns how many were moved.
+	Replay(limit int) (int, error)
+	// Purge drops all dead-lettered messages and returns how many were dropped.
+	Purge() (int, error)
+}
@@ -0,0 +1,17 @@
+package msgqueue
+
+// Delivery is an event received by an EventListener together with the means
+// to settle it. Every delivery must be settled exactly once: Ack when the event
+// has been handled, Reject when handling failed. Rejected deliveries are
+// retried according to the listener's RetryPolicy or dead-lettered when the
+// error is permanent or the retries are exhausted.
+type Delivery interface {
+	Event() Event
+	// Attempt returns how many times this delivery has already been retried.
+	Attempt() int
+	Ack() error
+	Reject(err error) error
+	// WillRetry reports whether Reject(err) would schedule another attempt
+	// rather than dead-letter the delivery.
+	WillRetry(err error) bool
+}
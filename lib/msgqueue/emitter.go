@@ -1,5 +1,15 @@
 package msgqueue
 
+import "errors"
+
+// ErrUnroutable is returned by Emit when no queue is bound for the event, so
+// that it would otherwise be silently dropped.
+var ErrUnroutable = errors.New("event could not be routed to any queue")
+
+// ErrBrokerUnavailable is returned by Emit while the connection to the
+// message broker is down, instead of blocking until it comes back.
+var ErrBrokerUnavailable = errors.New("message broker is unavailable")
+
 // EventEmitter describes an interface for a class that emits events
 type EventEmitter interface {
 	Emit(e Event) error
@@ -1,8 +1,30 @@
 package msgqueue
 
+import "github.com/WilfredDube/fxtract-backend/lib/contracts"
+
 // Interface definition for events that are emitted using an EventEmitter
 // Currently, the only requirement is that events are self-describing so that
 // event emitter and listeners can infer an event's name.
 type Event interface {
 	EventName() string
 }
+
+// IdentifiableEvent is an event that carries a unique ID. Listeners use it to
+// recognise redelivered events and handle each of them only once.
+type IdentifiableEvent interface {
+	Event
+	EventID() string
+}
+
+// VersionedEvent is an event with a versioned payload schema. Events that do
+// not implement it are assumed to be at version 1.
+type VersionedEvent interface {
+	Event
+	EventVersion() int
+}
+
+// EnvelopedEvent is an event that carries the standard contracts.Envelope.
+type EnvelopedEvent interface {
+	Event
+	Metadata() contracts.Envelope
+}
@@ -1,9 +1,37 @@
 package msgqueue
 
+import (
+	"reflect"
+
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+)
+
 type EventMapper interface {
 	MapEvent(string, interface{}) (Event, error)
 }
 
+// NewEventMapper returns a mapper for all events in lib/contracts. Payloads
+// from producers that predate the event envelope are upcast to version 1, and
+// older versions of changed events to their current version.
 func NewEventMapper() EventMapper {
-	return &StaticEventMapper{}
-}
\ No newline at end of file
+	mapper := newDynamicEventMapper()
+
+	for _, event := range []Event{
+		&contracts.FeatureRecognitionStarted{},
+		&contracts.FeatureRecognitionComplete{},
+		&contracts.FeatureRecognitionFailed{},
+		&contracts.FeatureRecognitionProgress{},
+		&contracts.ProcessPlanningStarted{},
+		&contracts.ProcessPlanningComplete{},
+		&contracts.ProcessPlanningFailed{},
+		&contracts.ProcessPlanningProgress{},
+		&contracts.ProjectReportRequested{},
+	} {
+		mapper.RegisterMapping(reflect.TypeOf(event).Elem())
+		mapper.RegisterUpcaster(event.EventName(), 0, contracts.UpcastUnversioned)
+	}
+
+	mapper.RegisterUpcaster("processPlanningStarted", 1, contracts.UpcastProcessPlanningStartedV1)
+
+	return mapper
+}
@@ -7,22 +7,50 @@ import (
 	"reflect"
 )
 
+// Upcaster converts the payload of an event from one schema version to the
+// next. Payloads without a schema_version are at version 0.
+type Upcaster func(payload map[string]interface{}) (map[string]interface{}, error)
+
 type DynamicEventMapper struct {
-	typeMap map[string]reflect.Type
+	typeMap   map[string]map[int]reflect.Type
+	upcasters map[string]map[int]Upcaster
 }
 
 func NewDynamicEventMapper() EventMapper {
+	return newDynamicEventMapper()
+}
+
+func newDynamicEventMapper() *DynamicEventMapper {
 	return &DynamicEventMapper{
-		typeMap: make(map[string]reflect.Type),
+		typeMap:   make(map[string]map[int]reflect.Type),
+		upcasters: make(map[string]map[int]Upcaster),
 	}
 }
 
+// MapEvent decodes the payload into the type registered for its schema
+// version. Payloads of a version without a registered type are upcast until
+// they reach one.
 func (e *DynamicEventMapper) MapEvent(eventName string, serialized interface{}) (Event, error) {
-	typ, ok := e.typeMap[eventName]
+	versions, ok := e.typeMap[eventName]
 	if !ok {
 		return nil, fmt.Errorf("no mapping configured for event %s", eventName)
 	}
 
+	version, err := schemaVersion(serialized)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema version of event %s: %s", eventName, err)
+	}
+
+	typ, ok := versions[version]
+	if !ok {
+		serialized, version, err = e.upcast(eventName, serialized, version)
+		if err != nil {
+			return nil, err
+		}
+
+		typ = versions[version]
+	}
+
 	instance := reflect.New(typ)
 	iface := instance.Interface()
 
@@ -41,6 +69,7 @@ func (e *DynamicEventMapper) MapEvent(eventName string, serialized interface{})
 		cfg := mapstructure.DecoderConfig{
 			Result:  event,
 			TagName: "json",
+			Squash:  true,
 		}
 		dec, err := mapstructure.NewDecoder(&cfg)
 		if err != nil {
@@ -56,6 +85,8 @@ func (e *DynamicEventMapper) MapEvent(eventName string, serialized interface{})
 	return event, nil
 }
 
+// RegisterMapping registers a type for the schema version reported by its
+// EventVersion method. Several versions of the same event may be registered.
 func (e *DynamicEventMapper) RegisterMapping(eventType reflect.Type) error {
 	instance := reflect.New(eventType).Interface()
 	event, ok := instance.(Event)
@@ -63,6 +94,92 @@ func (e *DynamicEventMapper) RegisterMapping(eventType reflect.Type) error {
 		return fmt.Errorf("type %T does not implement the Event interface", instance)
 	}
 
-	e.typeMap[event.EventName()] = eventType
+	version := 1
+	if versioned, ok := event.(VersionedEvent); ok {
+		version = versioned.EventVersion()
+	}
+
+	if _, ok := e.typeMap[event.EventName()]; !ok {
+		e.typeMap[event.EventName()] = make(map[int]reflect.Type)
+	}
+
+	e.typeMap[event.EventName()][version] = eventType
 	return nil
 }
+
+// RegisterUpcaster registers a conversion of an event's payload from the
+// given schema version to the next one.
+func (e *DynamicEventMapper) RegisterUpcaster(eventName string, fromVersion int, upcaster Upcaster) {
+	if _, ok := e.upcasters[eventName]; !ok {
+		e.upcasters[eventName] = make(map[int]Upcaster)
+	}
+
+	e.upcasters[eventName][fromVersion] = upcaster
+}
+
+// upcast applies upcasters until the payload reaches a version with a
+// registered type. The result is returned as JSON.
+func (e *DynamicEventMapper) upcast(eventName string, serialized interface{}, version int) ([]byte, int, error) {
+	var payload map[string]interface{}
+
+	switch s := serialized.(type) {
+	case []byte:
+		if err := json.Unmarshal(s, &payload); err != nil {
+			return nil, 0, fmt.Errorf("could not unmarshal event %s: %s", eventName, err)
+		}
+	case map[string]interface{}:
+		payload = s
+	default:
+		return nil, 0, fmt.Errorf("cannot upcast event %s from %T", eventName, serialized)
+	}
+
+	for {
+		if _, ok := e.typeMap[eventName][version]; ok {
+			break
+		}
+
+		upcaster, ok := e.upcasters[eventName][version]
+		if !ok {
+			return nil, 0, fmt.Errorf("no mapping configured for version %d of event %s", version, eventName)
+		}
+
+		var err error
+		if payload, err = upcaster(payload); err != nil {
+			return nil, 0, fmt.Errorf("could not upcast event %s from version %d: %s", eventName, version, err)
+		}
+
+		version++
+		payload["schema_version"] = version
+	}
+
+	upcast, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not marshal event %s: %s", eventName, err)
+	}
+
+	return upcast, version, nil
+}
+
+func schemaVersion(serialized interface{}) (int, error) {
+	switch s := serialized.(type) {
+	case []byte:
+		var header struct {
+			SchemaVersion int `json:"schema_version"`
+		}
+
+		if err := json.Unmarshal(s, &header); err != nil {
+			return 0, err
+		}
+
+		return header.SchemaVersion, nil
+	case map[string]interface{}:
+		switch version := s["schema_version"].(type) {
+		case float64:
+			return int(version), nil
+		case int:
+			return version, nil
+		}
+	}
+
+	return 0, nil
+}
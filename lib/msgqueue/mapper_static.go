@@ -18,10 +18,20 @@ func (e *StaticEventMapper) MapEvent(eventName string, serialized interface{}) (
 		event = &contracts.FeatureRecognitionStarted{}
 	case "featureRecognitionComplete":
 		event = &contracts.FeatureRecognitionComplete{}
+	case "featureRecognitionFailed":
+		event = &contracts.FeatureRecognitionFailed{}
+	case "featureRecognitionProgress":
+		event = &contracts.FeatureRecognitionProgress{}
 	case "processPlanningStarted":
 		event = &contracts.ProcessPlanningStarted{}
 	case "processPlanningComplete":
 		event = &contracts.ProcessPlanningComplete{}
+	case "processPlanningFailed":
+		event = &contracts.ProcessPlanningFailed{}
+	case "processPlanningProgress":
+		event = &contracts.ProcessPlanningProgress{}
+	case "projectReportRequested":
+		event = &contracts.ProjectReportRequested{}
 	default:
 		return nil, fmt.Errorf("unknown event type %s", eventName)
 	}
@@ -36,6 +46,7 @@ func (e *StaticEventMapper) MapEvent(eventName string, serialized interface{}) (
 		cfg := mapstructure.DecoderConfig{
 			Result:  event,
 			TagName: "json",
+			Squash:  true,
 		}
 		dec, err := mapstructure.NewDecoder(&cfg)
 		if err != nil {
@@ -0,0 +1,127 @@
+package msgqueue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+)
+
+func TestEventMapperUpcastsOldPayloads(t *testing.T) {
+	constraints := &entity.PlanningConstraints{FirstBendID: 2}
+
+	tests := []struct {
+		name       string
+		eventName  string
+		serialized interface{}
+		want       Event
+	}{
+		{
+			"unversioned",
+			"processPlanningStarted",
+			[]byte(`{"task_id":"task-1","cadfile_id":"cadfile-1","bend_count":4}`),
+			&contracts.ProcessPlanningStarted{
+				Envelope:  contracts.Envelope{SchemaVersion: 2, CorrelationID: "task-1"},
+				TaskID:    "task-1",
+				CADFileID: "cadfile-1",
+				BendCount: 4,
+			},
+		},
+		{
+			"version 1",
+			"processPlanningStarted",
+			[]byte(`{"schema_version":1,"correlation_id":"task-0","task_id":"task-1","constraints":{"first_bend_id":2}}`),
+			&contracts.ProcessPlanningStarted{
+				Envelope: contracts.Envelope{SchemaVersion: 2, CorrelationID: "task-0"},
+				TaskID:   "task-1",
+			},
+		},
+		{
+			"current version",
+			"processPlanningStarted",
+			[]byte(`{"schema_version":2,"task_id":"task-1","constraints":{"first_bend_id":2}}`),
+			&contracts.ProcessPlanningStarted{
+				Envelope:    contracts.Envelope{SchemaVersion: 2},
+				TaskID:      "task-1",
+				Constraints: constraints,
+			},
+		},
+		{
+			"unversioned map",
+			"processPlanningComplete",
+			map[string]interface{}{"task_id": "task-1", "cadfile_id": "cadfile-1"},
+			&contracts.ProcessPlanningComplete{
+				Envelope:  contracts.Envelope{SchemaVersion: 1, CorrelationID: "task-1"},
+				TaskID:    "task-1",
+				CADFileID: "cadfile-1",
+			},
+		},
+	}
+
+	mapper := NewEventMapper()
+	for _, test := range tests {
+		event, err := mapper.MapEvent(test.eventName, test.serialized)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(event, test.want) {
+			t.Errorf("%s: MapEvent = %+v, want %+v", test.name, event, test.want)
+		}
+	}
+}
+
+func TestEventMapperRejectsUnknownVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventName string
+		payload   string
+		wantErr   string
+	}{
+		{"unknown event", "unknownEvent", `{}`, "no mapping configured for event unknownEvent"},
+		{"newer version", "processPlanningStarted", `{"schema_version":3}`, "no mapping configured for version 3"},
+		{"malformed version", "processPlanningStarted", `{"schema_version":"2"}`, "could not read schema version"},
+	}
+
+	mapper := NewEventMapper()
+	for _, test := range tests {
+		_, err := mapper.MapEvent(test.eventName, []byte(test.payload))
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: MapEvent returned %v, want %q", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestUpcastersRunInOrder(t *testing.T) {
+	mapper := newDynamicEventMapper()
+	mapper.RegisterMapping(reflect.TypeOf(contracts.ProcessPlanningStarted{}))
+
+	steps := []string{}
+	for version := 0; version < 2; version++ {
+		version := version
+		mapper.RegisterUpcaster("processPlanningStarted", version, func(payload map[string]interface{}) (map[string]interface{}, error) {
+			if payload["schema_version"] != nil && payload["schema_version"] != version {
+				t.Errorf("upcaster from version %d got a payload at version %v", version, payload["schema_version"])
+			}
+
+			steps = append(steps, payload["task_id"].(string))
+			payload["task_id"] = payload["task_id"].(string) + "+"
+			return payload, nil
+		})
+	}
+
+	event, err := mapper.MapEvent("processPlanningStarted", []byte(`{"task_id":"task-1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := event.(*contracts.ProcessPlanningStarted).TaskID; got != "task-1++" {
+		t.Errorf("upcast task ID is %q, want task-1++", got)
+	}
+
+	if !reflect.DeepEqual(steps, []string{"task-1", "task-1+"}) {
+		t.Errorf("upcasters saw %v, want them to run from version 0 to 1", steps)
+	}
+}
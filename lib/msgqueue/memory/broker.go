@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+)
+
+// Broker is an in-process stand-in for an AMQP topic exchange. Emitters publish
+// serialized events to the broker, which copies every message into each queue
+// that has a binding matching the event name. Listeners that consume from the
+// same queue compete for its messages, just like AMQP consumers do.
+type Broker struct {
+	mutex  sync.RWMutex
+	queues map[string]*queue
+}
+
+type message struct {
+	eventName string
+	body      []byte
+	attempt   int
+}
+
+type queue struct {
+	name     string
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	bindings map[string]bool
+	pending  []message
+	dead     []msgqueue.DeadLetter
+}
+
+var defaultBroker = NewBroker()
+
+// NewBroker creates an empty broker without any queues.
+func NewBroker() *Broker {
+	return &Broker{
+		queues: make(map[string]*queue),
+	}
+}
+
+// DefaultBroker returns the process-wide broker that is used by the
+// *FromEnvironment constructors.
+func DefaultBroker() *Broker {
+	return defaultBroker
+}
+
+// declareQueue returns the queue with the given name, creating it if needed.
+func (b *Broker) declareQueue(name string) *queue {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{
+			name:     name,
+			bindings: make(map[string]bool),
+		}
+		q.cond = sync.NewCond(&q.mutex)
+		b.queues[name] = q
+	}
+
+	return q
+}
+
+// publish routes a message to every queue with a matching binding and
+// reports whether any queue received it.
+func (b *Broker) publish(msg message) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	routed := false
+	for _, q := range b.queues {
+		if q.matches(msg.eventName) {
+			q.push(msg)
+			routed = true
+		}
+	}
+
+	return routed
+}
+
+func (q *queue) bind(pattern string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.bindings[pattern] = true
+}
+
+func (q *queue) matches(routingKey string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for pattern := range q.bindings {
+		if topicMatch(strings.Split(pattern, "."), strings.Split(routingKey, ".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *queue) push(msg message) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pending = append(q.pending, msg)
+	q.cond.Signal()
+}
+
+// pop blocks until a message is available and removes it from the queue.
+func (q *queue) pop() message {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.pending) == 0 {
+		q.cond.Wait()
+	}
+
+	msg := q.pending[0]
+	q.pending = q.pending[1:]
+
+	return msg
+}
+
+func (q *queue) deadLetter(msg message, reason error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.dead = append(q.dead, msgqueue.DeadLetter{
+		EventName:      msg.eventName,
+		Queue:          q.name,
+		Reason:         reason.Error(),
+		Attempts:       msg.attempt,
+		Payload:        string(msg.body),
+		DeadLetteredAt: time.Now().Unix(),
+	})
+}
+
+// topicMatch implements AMQP topic exchange matching, where "*" matches
+// exactly one word and "#" matches zero or more words.
+func topicMatch(pattern []string, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if topicMatch(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && topicMatch(pattern[1:], key[1:])
+	default:
+		return len(key) > 0 && pattern[0] == key[0] && topicMatch(pattern[1:], key[1:])
+	}
+}
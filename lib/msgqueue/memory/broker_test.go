@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopicMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"processPlanningStarted", "processPlanningStarted", true},
+		{"processPlanningStarted", "processPlanningComplete", false},
+		{"task.*", "task.started", true},
+		{"task.*", "task", false},
+		{"task.*", "task.started.cad", false},
+		{"*.started", "task.started", true},
+		{"*.*", "task.started", true},
+		{"*", "task.started", false},
+		{"#", "task", true},
+		{"#", "task.started.cad", true},
+		{"task.#", "task", true},
+		{"task.#", "task.started.cad", true},
+		{"task.#", "report.started", false},
+		{"#.cad", "task.started.cad", true},
+		{"#.cad", "cad", true},
+		{"#.cad", "task.started", false},
+		{"task.#.cad", "task.cad", true},
+		{"task.#.cad", "task.started.cad", true},
+		{"task.*.cad", "task.cad", false},
+		{"#.*", "task", true},
+		{"#.*", "", true},
+		{"*.#.*", "task", false},
+	}
+
+	for _, test := range tests {
+		got := topicMatch(strings.Split(test.pattern, "."), strings.Split(test.key, "."))
+		if got != test.want {
+			t.Errorf("topicMatch(%q, %q) = %v, want %v", test.pattern, test.key, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package memory
+
+import "github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+
+type memoryDeadLetterQueue struct {
+	queue *queue
+}
+
+func (d *memoryDeadLetterQueue) Inspect(limit int) ([]msgqueue.DeadLetter, error) {
+	d.queue.mutex.Lock()
+	defer d.queue.mutex.Unlock()
+
+	n := len(d.queue.dead)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	deadLetters := make([]msgqueue.DeadLetter, n)
+	copy(deadLetters, d.queue.dead[:n])
+
+	return deadLetters, nil
+}
+
+func (d *memoryDeadLetterQueue) Replay(limit int) (int, error) {
+	d.queue.mutex.Lock()
+
+	n := len(d.queue.dead)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	replay := d.queue.dead[:n]
+	d.queue.dead = append([]msgqueue.DeadLetter{}, d.queue.dead[n:]...)
+	d.queue.mutex.Unlock()
+
+	for _, deadLetter := range replay {
+		d.queue.push(message{eventName: deadLetter.EventName, body: []byte(deadLetter.Payload)})
+	}
+
+	return n, nil
+}
+
+func (d *memoryDeadLetterQueue) Purge() (int, error) {
+	d.queue.mutex.Lock()
+	defer d.queue.mutex.Unlock()
+
+	n := len(d.queue.dead)
+	d.queue.dead = nil
+
+	return n, nil
+}
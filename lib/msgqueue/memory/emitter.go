@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+)
+
+type memoryEventEmitter struct {
+	broker *Broker
+}
+
+// NewMemoryEventEmitterFromEnvironment creates an event emitter that publishes
+// to the process-wide default broker.
+func NewMemoryEventEmitterFromEnvironment() (msgqueue.EventEmitter, error) {
+	return NewMemoryEventEmitter(DefaultBroker())
+}
+
+// NewMemoryEventEmitter creates a new event emitter that publishes to the given
+// in-process broker.
+func NewMemoryEventEmitter(broker *Broker) (msgqueue.EventEmitter, error) {
+	return &memoryEventEmitter{broker: broker}, nil
+}
+
+// Emit serializes the event exactly like the AMQP emitter does, so that
+// listeners have to go through an EventMapper to get it back. Like a
+// mandatory AMQP publish, it fails when no queue is bound for the event.
+func (m *memoryEventEmitter) Emit(event msgqueue.Event) error {
+	jsonBody, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not JSON-serialize event: %s", err)
+	}
+
+	if !m.broker.publish(message{eventName: event.EventName(), body: jsonBody}) {
+		return fmt.Errorf("%w: %s", msgqueue.ErrUnroutable, event.EventName())
+	}
+
+	return nil
+}
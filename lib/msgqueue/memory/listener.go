@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+)
+
+type memoryEventListener struct {
+	broker *Broker
+	queue  string
+	mapper msgqueue.EventMapper
+	retry  msgqueue.RetryPolicy
+}
+
+// NewMemoryEventListenerFromEnvironment creates an event listener on the
+// process-wide default broker. The queue name is read from MEMORY_QUEUE and
+// defaults to "example".
+func NewMemoryEventListenerFromEnvironment() (msgqueue.EventListener, error) {
+	var queue string
+
+	if queue = os.Getenv("MEMORY_QUEUE"); queue == "" {
+		queue = "example"
+	}
+
+	return NewMemoryEventListener(DefaultBroker(), queue, msgqueue.DefaultRetryPolicy)
+}
+
+// NewMemoryEventListener creates a new event listener that consumes the named
+// queue on the given broker. Several listeners on the same queue compete for
+// its messages. Rejected deliveries are put back on the queue after the
+// policy's backoff, or dead-lettered once the retries are exhausted.
+func NewMemoryEventListener(broker *Broker, queue string, retry msgqueue.RetryPolicy) (msgqueue.EventListener, error) {
+	listener := memoryEventListener{
+		broker: broker,
+		queue:  queue,
+		mapper: msgqueue.NewEventMapper(),
+		retry:  retry,
+	}
+
+	broker.declareQueue(queue)
+
+	return &listener, nil
+}
+
+// Listen binds the listener's queue to the given event names and starts
+// consuming it. Successfully decoded events are sent on the first channel,
+// messages that could not be decoded are dead-lettered and produce an error
+// on the second one.
+func (l *memoryEventListener) Listen(eventNames ...string) (<-chan msgqueue.Delivery, <-chan error, error) {
+	q := l.broker.declareQueue(l.queue)
+
+	for _, event := range eventNames {
+		q.bind(event)
+	}
+
+	deliveries := make(chan msgqueue.Delivery)
+	errors := make(chan error)
+
+	go func() {
+		for {
+			msg := q.pop()
+
+			event, err := l.mapper.MapEvent(msg.eventName, msg.body)
+			if err != nil {
+				err = fmt.Errorf("could not unmarshal event %s: %s", msg.eventName, err)
+				q.deadLetter(msg, err)
+				errors <- err
+				continue
+			}
+
+			deliveries <- &memoryDelivery{listener: l, queue: q, msg: msg, event: event}
+		}
+	}()
+
+	return deliveries, errors, nil
+}
+
+func (l *memoryEventListener) Mapper() msgqueue.EventMapper {
+	return l.mapper
+}
+
+func (l *memoryEventListener) DeadLetters() msgqueue.DeadLetterQueue {
+	return &memoryDeadLetterQueue{queue: l.broker.declareQueue(l.queue)}
+}
+
+type memoryDelivery struct {
+	listener *memoryEventListener
+	queue    *queue
+	msg      message
+	event    msgqueue.Event
+}
+
+func (d *memoryDelivery) Event() msgqueue.Event {
+	return d.event
+}
+
+func (d *memoryDelivery) Attempt() int {
+	return d.msg.attempt
+}
+
+func (d *memoryDelivery) Ack() error {
+	return nil
+}
+
+func (d *memoryDelivery) Reject(err error) error {
+	if !d.WillRetry(err) {
+		d.queue.deadLetter(d.msg, err)
+		return nil
+	}
+
+	retry := d.msg
+	retry.attempt++
+
+	time.AfterFunc(d.listener.retry.Backoff(retry.attempt), func() {
+		d.queue.push(retry)
+	})
+
+	return nil
+}
+
+func (d *memoryDelivery) WillRetry(err error) bool {
+	return d.listener.retry.ShouldRetry(d.msg.attempt, err)
+}
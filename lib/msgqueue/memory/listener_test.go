@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+)
+
+var testRetryPolicy = msgqueue.RetryPolicy{
+	MaxRetries:     2,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     time.Millisecond,
+}
+
+func newTestListener(t *testing.T, broker *Broker, eventNames ...string) (msgqueue.EventListener, <-chan msgqueue.Delivery, <-chan error) {
+	listener, err := NewMemoryEventListener(broker, "test", testRetryPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, errs, err := listener.Listen(eventNames...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener, deliveries, errs
+}
+
+func receive(t *testing.T, deliveries <-chan msgqueue.Delivery) msgqueue.Delivery {
+	t.Helper()
+
+	select {
+	case delivery := <-deliveries:
+		return delivery
+	case <-time.After(time.Second):
+		t.Fatal("no delivery received")
+		return nil
+	}
+}
+
+func waitForDeadLetters(t *testing.T, listener msgqueue.EventListener, n int) []msgqueue.DeadLetter {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		deadLetters, err := listener.DeadLetters().Inspect(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(deadLetters) >= n || time.Now().After(deadline) {
+			return deadLetters
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	broker := NewBroker()
+	_, deliveries, _ := newTestListener(t, broker, "processPlanningStarted")
+
+	emitter, err := NewMemoryEventEmitter(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &contracts.ProcessPlanningStarted{
+		UserID:    "user-1",
+		CADFileID: "cadfile-1",
+		TaskID:    "task-1",
+		BendCount: 4,
+	}
+	sent.Envelope = contracts.NewEnvelope(sent.EventVersion(), sent.TaskID, "")
+	if err := emitter.Emit(sent); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	received, ok := delivery.Event().(*contracts.ProcessPlanningStarted)
+	if !ok {
+		t.Fatalf("received %T, want *contracts.ProcessPlanningStarted", delivery.Event())
+	}
+
+	if *received != *sent {
+		t.Errorf("received %+v, want %+v", received, sent)
+	}
+
+	if delivery.Attempt() != 0 {
+		t.Errorf("first delivery is attempt %d, want 0", delivery.Attempt())
+	}
+}
+
+func TestCompetingListeners(t *testing.T) {
+	broker := NewBroker()
+	_, first, _ := newTestListener(t, broker, "processPlanningStarted")
+	_, second, _ := newTestListener(t, broker, "processPlanningStarted")
+	emitter, _ := NewMemoryEventEmitter(broker)
+
+	for _, taskID := range []string{"task-1", "task-2"} {
+		if err := emitter.Emit(&contracts.ProcessPlanningStarted{TaskID: taskID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case delivery := <-first:
+			received[delivery.Event().(*contracts.ProcessPlanningStarted).TaskID]++
+		case delivery := <-second:
+			received[delivery.Event().(*contracts.ProcessPlanningStarted).TaskID]++
+		case <-time.After(time.Second):
+			t.Fatal("no event received")
+		}
+	}
+
+	if received["task-1"] != 1 || received["task-2"] != 1 {
+		t.Errorf("received %v, want each task once", received)
+	}
+}
+
+func TestEmitUnroutable(t *testing.T) {
+	emitter, _ := NewMemoryEventEmitter(NewBroker())
+
+	err := emitter.Emit(&contracts.ProcessPlanningStarted{TaskID: "task-1"})
+	if !errors.Is(err, msgqueue.ErrUnroutable) {
+		t.Errorf("Emit without a bound queue returned %v, want ErrUnroutable", err)
+	}
+}
+
+func TestRejectRetriesThenDeadLetters(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"transient", errors.New("database unavailable"), testRetryPolicy.MaxRetries},
+		{"permanent", msgqueue.Permanent(errors.New("CAD file not found")), 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broker := NewBroker()
+			listener, deliveries, _ := newTestListener(t, broker, "processPlanningStarted")
+			emitter, _ := NewMemoryEventEmitter(broker)
+
+			if err := emitter.Emit(&contracts.ProcessPlanningStarted{TaskID: "task-1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			for attempt := 0; attempt <= test.attempts; attempt++ {
+				delivery := receive(t, deliveries)
+				if delivery.Attempt() != attempt {
+					t.Fatalf("delivery is attempt %d, want %d", delivery.Attempt(), attempt)
+				}
+
+				willRetry := attempt < test.attempts
+				if delivery.WillRetry(test.err) != willRetry {
+					t.Errorf("WillRetry on attempt %d = %v, want %v", attempt, !willRetry, willRetry)
+				}
+
+				if err := delivery.Reject(test.err); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			deadLetters := waitForDeadLetters(t, listener, 1)
+			if len(deadLetters) != 1 {
+				t.Fatalf("%d dead letters, want 1", len(deadLetters))
+			}
+
+			deadLetter := deadLetters[0]
+			if deadLetter.EventName != "processPlanningStarted" || deadLetter.Queue != "test" ||
+				deadLetter.Attempts != test.attempts || deadLetter.Reason != test.err.Error() {
+				t.Errorf("dead letter %+v does not match the rejected delivery", deadLetter)
+			}
+
+			select {
+			case delivery := <-deliveries:
+				t.Errorf("dead-lettered event was delivered again on attempt %d", delivery.Attempt())
+			case <-time.After(10 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	broker := NewBroker()
+	listener, deliveries, _ := newTestListener(t, broker, "processPlanningStarted")
+	emitter, _ := NewMemoryEventEmitter(broker)
+
+	if err := emitter.Emit(&contracts.ProcessPlanningStarted{TaskID: "task-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	delivery.Reject(msgqueue.Permanent(errors.New("CAD file not found")))
+	waitForDeadLetters(t, listener, 1)
+
+	replayed, err := listener.DeadLetters().Replay(0)
+	if err != nil || replayed != 1 {
+		t.Fatalf("Replay = %d, %v, want 1, nil", replayed, err)
+	}
+
+	delivery = receive(t, deliveries)
+	if event := delivery.Event().(*contracts.ProcessPlanningStarted); event.TaskID != "task-1" {
+		t.Errorf("replayed event is for task %q, want task-1", event.TaskID)
+	}
+
+	if deadLetters, _ := listener.DeadLetters().Inspect(0); len(deadLetters) != 0 {
+		t.Errorf("%d dead letters left after replay, want 0", len(deadLetters))
+	}
+}
+
+func TestUndecodableEventIsDeadLettered(t *testing.T) {
+	broker := NewBroker()
+	listener, _, errs := newTestListener(t, broker, "#")
+
+	if !broker.publish(message{eventName: "unknownEvent", body: []byte("{}")}) {
+		t.Fatal("message was not routed")
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("no error reported for an unknown event")
+	}
+
+	deadLetters := waitForDeadLetters(t, listener, 1)
+	if len(deadLetters) != 1 || deadLetters[0].EventName != "unknownEvent" {
+		t.Errorf("dead letters %+v, want the unknown event", deadLetters)
+	}
+}
@@ -0,0 +1,97 @@
+package msgqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConsumerMetrics counts what a consumer does with the deliveries it receives.
+// All methods are safe for concurrent use.
+type ConsumerMetrics struct {
+	received     int64
+	succeeded    int64
+	failed       int64
+	skipped      int64
+	queued       int64
+	inFlight     int64
+	handleNanos  int64
+	blockedNanos int64
+	workers      int64
+}
+
+// ConsumerStats is a point-in-time copy of ConsumerMetrics.
+//
+//   - Queued; deliveries waiting for a worker
+//   - InFlight; deliveries being handled right now
+//   - BlockedMillis; total time spent waiting for a worker with a full queue,
+//     during which no further deliveries were taken from the broker
+type ConsumerStats struct {
+	Workers             int     `json:"workers"`
+	Received            int64   `json:"received"`
+	Succeeded           int64   `json:"succeeded"`
+	Failed              int64   `json:"failed"`
+	Skipped             int64   `json:"skipped"`
+	Queued              int64   `json:"queued"`
+	InFlight            int64   `json:"in_flight"`
+	AverageHandleMillis float64 `json:"average_handle_millis"`
+	BlockedMillis       int64   `json:"blocked_millis"`
+}
+
+func NewConsumerMetrics() *ConsumerMetrics {
+	return &ConsumerMetrics{}
+}
+
+func (m *ConsumerMetrics) SetWorkers(workers int) {
+	atomic.StoreInt64(&m.workers, int64(workers))
+}
+
+// Enqueued records a delivery handed to a worker queue and how long that took.
+func (m *ConsumerMetrics) Enqueued(blocked time.Duration) {
+	atomic.AddInt64(&m.received, 1)
+	atomic.AddInt64(&m.queued, 1)
+	atomic.AddInt64(&m.blockedNanos, int64(blocked))
+}
+
+// Started records a worker taking a delivery off its queue.
+func (m *ConsumerMetrics) Started() {
+	atomic.AddInt64(&m.queued, -1)
+	atomic.AddInt64(&m.inFlight, 1)
+}
+
+// Finished records a worker being done with a delivery.
+func (m *ConsumerMetrics) Finished(took time.Duration) {
+	atomic.AddInt64(&m.inFlight, -1)
+	atomic.AddInt64(&m.handleNanos, int64(took))
+}
+
+func (m *ConsumerMetrics) Succeeded() {
+	atomic.AddInt64(&m.succeeded, 1)
+}
+
+func (m *ConsumerMetrics) Failed() {
+	atomic.AddInt64(&m.failed, 1)
+}
+
+// Skipped records a duplicate delivery that was not handled again.
+func (m *ConsumerMetrics) Skipped() {
+	atomic.AddInt64(&m.skipped, 1)
+}
+
+func (m *ConsumerMetrics) Snapshot() ConsumerStats {
+	stats := ConsumerStats{
+		Workers:       int(atomic.LoadInt64(&m.workers)),
+		Received:      atomic.LoadInt64(&m.received),
+		Succeeded:     atomic.LoadInt64(&m.succeeded),
+		Failed:        atomic.LoadInt64(&m.failed),
+		Skipped:       atomic.LoadInt64(&m.skipped),
+		Queued:        atomic.LoadInt64(&m.queued),
+		InFlight:      atomic.LoadInt64(&m.inFlight),
+		BlockedMillis: atomic.LoadInt64(&m.blockedNanos) / int64(time.Millisecond),
+	}
+
+	if done := stats.Succeeded + stats.Failed + stats.Skipped; done > 0 {
+		stats.AverageHandleMillis = float64(atomic.LoadInt64(&m.handleNanos)) / float64(done) / float64(time.Millisecond)
+	}
+
+	return stats
+}
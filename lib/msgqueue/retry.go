@@ -0,0 +1,71 @@
+package msgqueue
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryPolicy controls how often a rejected delivery is redelivered and how
+// long the listener waits between attempts. The delay doubles with every
+// attempt, starting at InitialBackoff and capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is used by the *FromEnvironment listener constructors.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// Backoff returns the delay before the given (1-based) retry attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	return delay
+}
+
+// ShouldRetry reports whether a delivery that failed with err on the given
+// attempt is retried rather than dead-lettered.
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	return !IsPermanent(err) && attempt < p.MaxRetries
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error as not worth retrying. Deliveries rejected with a
+// permanent error are dead-lettered straight away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error, or any error it wraps, was marked
+// with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,236 @@
+// Package xlsx writes simple Office Open XML workbooks: sheets of rows whose
+// first row is a bold header. Cells that parse as numbers are stored as
+// numbers so spreadsheets and ERP imports can compute with them.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxSheetName is the longest sheet name spreadsheet programs accept.
+const maxSheetName = 31
+
+// Sheet is a named table of a workbook.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// Workbook is a list of sheets.
+type Workbook struct {
+	Sheets []Sheet
+}
+
+// AddSheet appends a sheet. Names are shortened and made unique as needed.
+func (wb *Workbook) AddSheet(name string, header []string, rows [][]string) {
+	wb.Sheets = append(wb.Sheets, Sheet{Name: wb.sheetName(name), Header: header, Rows: rows})
+}
+
+// Write writes the workbook as an .xlsx file.
+func (wb *Workbook) Write(w io.Writer) error {
+	if len(wb.Sheets) == 0 {
+		return fmt.Errorf("workbook has no sheets")
+	}
+
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", wb.workbook()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+
+	for _, file := range files {
+		if err := writeFile(archive, file.name, file.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range wb.Sheets {
+		if err := writeFile(archive, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Bytes returns the workbook as an .xlsx file.
+func (wb *Workbook) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (wb *Workbook) sheetName(name string) string {
+	name = strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", "\\", "-").Replace(name)
+	if name == "" {
+		name = "Sheet"
+	}
+
+	unique := truncate(name, maxSheetName)
+	for i := 2; wb.hasSheet(unique); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		unique = truncate(name, maxSheetName-len(suffix)) + suffix
+	}
+
+	return unique
+}
+
+func (wb *Workbook) hasSheet(name string) bool {
+	for _, sheet := range wb.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+
+	return s
+}
+
+func (wb *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+
+	return b.String()
+}
+
+func (wb *Workbook) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range wb.Sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+
+	return b.String()
+}
+
+func (wb *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.Sheets)+1)
+	b.WriteString(`</Relationships>`)
+
+	return b.String()
+}
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow(&b, 1, s.Header, true)
+	for i, row := range s.Rows {
+		writeRow(&b, i+2, row, false)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+
+	return b.String()
+}
+
+func writeRow(b *strings.Builder, number int, cells []string, header bool) {
+	fmt.Fprintf(b, `<row r="%d">`, number)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(number)
+
+		if header {
+			fmt.Fprintf(b, `<c r="%s" s="1" t="inlineStr"><is><t>%s</t></is></c>`, ref, escape(cell))
+		} else if isNumber(cell) {
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, cell)
+		} else {
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escape(cell))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// isNumber reports whether a cell holds a plain decimal number. Codes with
+// leading zeros, such as part numbers, are kept as text.
+func isNumber(cell string) bool {
+	if strings.ContainsAny(cell, "xXpP_iInN") || (len(cell) > 1 && cell[0] == '0' && cell[1] != '.') {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(cell, 64)
+	return err == nil
+}
+
+// columnName returns the letters of a zero-based column: A, B, ..., Z, AA.
+func columnName(column int) string {
+	name := ""
+	for column >= 0 {
+		name = string(rune('A'+column%26)) + name
+		column = column/26 - 1
+	}
+
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+func writeFile(archive *zip.Writer, name string, content string) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles holds the default cell style and a bold one for headers.
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,155 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestIsNumber(t *testing.T) {
+	tests := []struct {
+		cell string
+		want bool
+	}{
+		{"0", true},
+		{"12", true},
+		{"12.50", true},
+		{"-3.2", true},
+		{"0.5", true},
+		{"", false},
+		{"abc", false},
+		{" 12", false},
+		{"007", false},
+		{"0123.5", false},
+		{"0x1F", false},
+		{"1_000", false},
+		{"Inf", false},
+		{"NaN", false},
+		{"0x1p-2", false},
+	}
+
+	for _, test := range tests {
+		if got := isNumber(test.cell); got != test.want {
+			t.Errorf("isNumber(%q) = %v, want %v", test.cell, got, test.want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	long := strings.Repeat("x", 40)
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"Plans"}, []string{"Plans"}},
+		{[]string{""}, []string{"Sheet"}},
+		{[]string{"a/b: [c]?"}, []string{"a-b- (c)"}},
+		{[]string{"Plans", "plans", "Plans"}, []string{"Plans", "plans (2)", "Plans (3)"}},
+		{[]string{long, long}, []string{long[:31], long[:27] + " (2)"}},
+	}
+
+	for _, test := range tests {
+		wb := &Workbook{}
+		for _, name := range test.names {
+			wb.AddSheet(name, nil, nil)
+		}
+
+		for i, sheet := range wb.Sheets {
+			if sheet.Name != test.want[i] {
+				t.Errorf("sheet %d of %q is named %q, want %q", i+1, test.names, sheet.Name, test.want[i])
+			}
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		column int
+		want   string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, test := range tests {
+		if got := columnName(test.column); got != test.want {
+			t.Errorf("columnName(%d) = %q, want %q", test.column, got, test.want)
+		}
+	}
+}
+
+func TestWorkbookWrite(t *testing.T) {
+	wb := &Workbook{}
+	wb.AddSheet("Plans", []string{"Part no", "Tools", "Time"}, [][]string{
+		{"007", "2", "40.5"},
+		{"A<1>", "3", "n/a"},
+	})
+	wb.AddSheet("Bends", []string{"Bend"}, nil)
+
+	content, err := wb.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[file.Name] = string(body)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("workbook has no %s", name)
+		}
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"xl/workbook.xml", `<sheet name="Plans" sheetId="1" r:id="rId1"/><sheet name="Bends" sheetId="2" r:id="rId2"/>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`},
+		{"xl/worksheets/sheet1.xml", `<c r="A1" s="1" t="inlineStr"><is><t>Part no</t></is></c>`},
+		{"xl/worksheets/sheet1.xml", `<c r="A2" t="inlineStr"><is><t>007</t></is></c>`},
+		{"xl/worksheets/sheet1.xml", `<c r="B2"><v>2</v></c><c r="C2"><v>40.5</v></c>`},
+		{"xl/worksheets/sheet1.xml", `<c r="A3" t="inlineStr"><is><t>A&lt;1&gt;</t></is></c>`},
+		{"xl/worksheets/sheet1.xml", `<c r="C3" t="inlineStr"><is><t>n/a</t></is></c>`},
+		{"xl/worksheets/sheet2.xml", `<sheetData><row r="1"><c r="A1" s="1" t="inlineStr"><is><t>Bend</t></is></c></row></sheetData>`},
+	}
+
+	for _, test := range tests {
+		if !strings.Contains(files[test.file], test.want) {
+			t.Errorf("%s does not contain %s", test.file, test.want)
+		}
+	}
+}
+
+func TestWorkbookWriteWithoutSheets(t *testing.T) {
+	if _, err := (&Workbook{}).Bytes(); err == nil {
+		t.Error("a workbook without sheets was written")
+	}
+}
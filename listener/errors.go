@@ -0,0 +1,72 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error codes recorded on a task for CAD files whose events could not be
+// handled.
+const (
+	CADFileNotFound     = "CADFILE_NOT_FOUND"
+	ToolNotFound        = "TOOL_NOT_FOUND"
+	MaterialNotFound    = "MATERIAL_NOT_FOUND"
+	ProjectNotFound     = "PROJECT_NOT_FOUND"
+	MachineNotFound     = "MACHINE_NOT_FOUND"
+	UserNotFound        = "USER_NOT_FOUND"
+	PlanNotFound        = "PROCESSING_PLAN_NOT_FOUND"
+	PDFGenerationFailed = "PDF_GENERATION_FAILED"
+	UploadFailed        = "UPLOAD_FAILED"
+	StorageFailed       = "STORAGE_FAILED"
+	InternalError       = "INTERNAL_ERROR"
+)
+
+// EventError is returned by event handlers. Its code tells the user why a
+// CAD file failed once the event is given up on.
+type EventError struct {
+	Code string
+	Err  error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// transient wraps an error that may go away on its own, such as a storage
+// outage. The event is retried.
+func transient(code string, err error) error {
+	return &EventError{Code: code, Err: err}
+}
+
+// permanent wraps an error that retrying cannot fix, such as missing master
+// data. The event is dead-lettered straight away.
+func permanent(code string, err error) error {
+	return msgqueue.Permanent(&EventError{Code: code, Err: err})
+}
+
+func errorCode(err error) string {
+	var eventError *EventError
+	if errors.As(err, &eventError) {
+		return eventError.Code
+	}
+
+	return InternalError
+}
+
+// lookupFailed classifies a failed lookup. A document that does not exist
+// will not appear by retrying, but the database being unreachable may pass.
+func lookupFailed(code string, err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+		return permanent(code, err)
+	}
+
+	return transient(code, err)
+}
@@ -1,8 +1,11 @@
 package listener
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"runtime/debug"
 	"time"
 
 	"github.com/WilfredDube/fxtract-backend/controller"
@@ -13,6 +16,7 @@ import (
 	"github.com/WilfredDube/fxtract-backend/service"
 	"github.com/teris-io/shortid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type EventProcessor struct {
@@ -20,14 +24,27 @@ type EventProcessor struct {
 	CadFileService        service.CadFileService
 	TaskService           service.TaskService
 	ToolService           service.ToolService
+	ToolSelectionService  service.ToolSelectionService
 	ProcessingPlanService service.ProcessingPlanService
 	MaterialService       service.MaterialService
+	CostingService        service.CostingService
+	MachineService        service.MachineService
 	ProjectService        service.ProjectService
 	UserService           service.UserService
+	PDFTemplateService    service.PDFTemplateService
 	Processor             *service.Processor
-	pdfService            service.PDFService
+	EventLedger           service.ProcessedEventService
+	Workers               int
+	Metrics               *msgqueue.ConsumerMetrics
 }
 
+// workerQueueSize is how many deliveries may wait for each worker. Once a
+// worker's queue is full the dispatcher stops taking deliveries from the
+// listener, leaving the rest to the broker's prefetch limit.
+const workerQueueSize = 1
+
+// ProcessEvents dispatches deliveries to a pool of Workers. Events of the same
+// task always go to the same worker, so they are handled in order.
 func (p *EventProcessor) ProcessEvents(events ...string) {
 	log.Println("listening for events")
 
@@ -37,216 +54,649 @@ func (p *EventProcessor) ProcessEvents(events ...string) {
 		panic(err)
 	}
 
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	if p.Metrics == nil {
+		p.Metrics = msgqueue.NewConsumerMetrics()
+	}
+	p.Metrics.SetWorkers(workers)
+
+	queues := make([]chan msgqueue.Delivery, workers)
+	for i := range queues {
+		queues[i] = make(chan msgqueue.Delivery, workerQueueSize)
+		go p.work(queues[i])
+	}
+
 	for {
 		select {
-		case evt := <-received:
-			fmt.Printf("got event %T: \n", evt)
-			p.handleEvent(evt)
+		case delivery := <-received:
+			queue := queues[partition(delivery.Event(), workers)]
+
+			start := time.Now()
+			queue <- delivery
+			p.Metrics.Enqueued(time.Since(start))
 		case err = <-errors:
 			fmt.Printf("got error while receiving event: %s\n", err)
 		}
 	}
 }
 
-func (p *EventProcessor) handleEvent(event msgqueue.Event) {
+func (p *EventProcessor) work(queue <-chan msgqueue.Delivery) {
+	for delivery := range queue {
+		p.Metrics.Started()
+
+		start := time.Now()
+		p.process(delivery)
+		p.Metrics.Finished(time.Since(start))
+	}
+}
+
+// partition picks the worker for an event by hashing the task it belongs to.
+func partition(event msgqueue.Event, workers int) int {
+	key := event.EventName()
+	if enveloped, ok := event.(msgqueue.EnvelopedEvent); ok && enveloped.Metadata().CorrelationID != "" {
+		key = enveloped.Metadata().CorrelationID
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	return int(hash.Sum32() % uint32(workers))
+}
+
+// process handles a delivery at most once per event ID. Events already in the
+// ledger are acknowledged without being handled again.
+func (p *EventProcessor) process(delivery msgqueue.Delivery) {
+	event := delivery.Event()
+	fmt.Printf("got event %T: \n", event)
+
+	identifiable, ok := event.(msgqueue.IdentifiableEvent)
+	if ok {
+		claimed, err := p.EventLedger.Claim(identifiable.EventID(), event.EventName())
+		if err != nil {
+			log.Printf("failed to claim event %s: %s", identifiable.EventID(), err)
+			p.Metrics.Failed()
+			if err := delivery.Reject(err); err != nil {
+				log.Printf("failed to reject event %s: %s", event.EventName(), err)
+			}
+			return
+		}
+
+		if !claimed {
+			log.Printf("skipping event %s (%s): already handled", identifiable.EventID(), event.EventName())
+			p.Metrics.Skipped()
+			if err := delivery.Ack(); err != nil {
+				log.Printf("failed to acknowledge event %s: %s", event.EventName(), err)
+			}
+			return
+		}
+	}
+
+	if err := p.safelyHandleEvent(event); err != nil {
+		log.Printf("failed to handle event %s (attempt %d): %s", event.EventName(), delivery.Attempt()+1, err)
+		p.Metrics.Failed()
+
+		if !delivery.WillRetry(err) {
+			p.giveUp(event, err)
+		}
+		if ok {
+			if err := p.EventLedger.Release(identifiable.EventID()); err != nil {
+				log.Printf("failed to release event %s: %s", identifiable.EventID(), err)
+			}
+		}
+
+		if err := delivery.Reject(err); err != nil {
+			log.Printf("failed to reject event %s: %s", event.EventName(), err)
+		}
+		return
+	}
+
+	p.Metrics.Succeeded()
+
+	if ok {
+		if err := p.EventLedger.Complete(identifiable.EventID()); err != nil {
+			log.Printf("failed to record event %s as handled: %s", identifiable.EventID(), err)
+		}
+	}
+
+	if err := delivery.Ack(); err != nil {
+		log.Printf("failed to acknowledge event %s: %s", event.EventName(), err)
+	}
+}
+
+// safelyHandleEvent turns a panicking handler into a failed one.
+func (p *EventProcessor) safelyHandleEvent(event msgqueue.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("recovered from panic while handling %s: %v\n%s", event.EventName(), r, debug.Stack())
+			err = permanent(InternalError, fmt.Errorf("handler panicked: %v", r))
+		}
+	}()
+
+	return p.handleEvent(event)
+}
+
+// giveUp marks the CAD file of an event that will not be retried as failed, so
+// that its task does not wait for it forever.
+func (p *EventProcessor) giveUp(event msgqueue.Event, cause error) {
+	var err error
+	switch e := event.(type) {
+	case *contracts.FeatureRecognitionComplete:
+		err = p.handleFailure(e.UserID, e.TaskID, e.CADFileID, entity.FeatureRecognition, errorCode(cause), cause.Error())
+	case *contracts.ProcessPlanningComplete:
+		err = p.handleFailure(e.UserID, e.TaskID, e.CADFileID, entity.ProcessPlanning, errorCode(cause), cause.Error())
+	case *contracts.ProjectReportRequested:
+		err = p.handleFailure(e.UserID, e.TaskID, e.ProjectID, entity.ProjectReport, errorCode(cause), cause.Error())
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("failed to mark CAD file of event %s as failed: %s", event.EventName(), err)
+	}
+}
+
+func (p *EventProcessor) handleEvent(event msgqueue.Event) error {
 	switch e := event.(type) {
 	case *contracts.FeatureRecognitionComplete:
 		log.Printf("event %s created: %s", e.CADFileID, e.TaskID)
 
 		cadFile, err := p.CadFileService.Find(e.CADFileID)
 		if err != nil {
-			log.Fatalf("%s: %s", "Failed to unmarshal data: ", err)
+			return lookupFailed(CADFileNotFound, err)
 		}
 
 		cadFile.FeatureProps = e.FeatureProps
 		cadFile.BendFeatures = []entity.BendFeature{}
 		cadFile.BendFeatures = e.BendFeatures
 
-		for i, bend := range cadFile.BendFeatures {
-			tool, err := p.ToolService.FindByAngle(int64(bend.Angle))
-			if err != nil {
-				log.Fatalf("%s: %s", "Failed to retrieve tool data: ", err)
-			}
-
-			cadFile.BendFeatures[i].ToolID = tool.ToolID
+		cadFile.BendFeatures, err = p.ToolSelectionService.Select(e.BendFeatures)
+		if err != nil {
+			return transient(StorageFailed, err)
 		}
 
 		material, err := p.MaterialService.Find(cadFile.Material)
 		if err != nil {
-			log.Fatalf("%s: %s", "Failed to retrieve material data: ", err)
+			return lookupFailed(MaterialNotFound, fmt.Errorf("material %s: %w", cadFile.Material, err))
+		}
+
+		project, err := p.ProjectService.Find(cadFile.ProjectID.Hex())
+		if err != nil {
+			return lookupFailed(ProjectNotFound, err)
+		}
+
+		machine, err := p.findMachine(project)
+		if err != nil {
+			return transient(MachineNotFound, err)
 		}
 
-		maxLength := 0.0
-		for _, file := range cadFile.BendFeatures {
-			if file.Length > maxLength {
-				maxLength = file.Length
+		model := project.BendingForceModel
+		if model == "" && machine != nil {
+			model = machine.BendingForceModel
+		}
+
+		calculator, err := service.NewBendingForceCalculator(model)
+		if err != nil {
+			return permanent(InternalError, err)
+		}
+
+		tools := make(map[string]*entity.Tool)
+		for _, bend := range cadFile.BendFeatures {
+			// Bends no tool can form are reported by the DFM check.
+			if _, ok := tools[bend.ToolID]; ok || bend.ToolID == "" {
+				continue
 			}
+
+			tool, err := p.ToolService.Find(bend.ToolID)
+			if err != nil {
+				return lookupFailed(ToolNotFound, fmt.Errorf("tool %s: %w", bend.ToolID, err))
+			}
+			tools[bend.ToolID] = tool
 		}
 
-		bendingForce := (maxLength * cadFile.FeatureProps.Thickness * material.KFactor * material.TensileStrength) / 8
-		cadFile.FeatureProps.BendingForce = bendingForce
+		service.ApplyBendingForce(calculator, cadFile, material, tools)
+		service.DevelopFlatPattern(cadFile, material.KFactor)
+		cadFile.DFMFindings = service.CheckManufacturability(cadFile, material, tools)
 
 		_, err = p.CadFileService.Update(*cadFile)
 		if err != nil {
-			log.Fatalf("%s: %s", "Failed to update data: ", err)
+			return transient(StorageFailed, err)
 		}
 
 		PROJECTCADFILES := controller.CADFILECACHE + cadFile.ProjectID.Hex()
 		go persistence.ClearCache(cadFile.ProjectID.Hex())
 		go persistence.ClearCache(PROJECTCADFILES)
 
-		task, err := p.TaskService.Find(e.TaskID)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed to retrieve task data: ", err)
-		}
+		returedTask, err := p.TaskService.Mutate(e.TaskID, func(task *entity.Task) error {
+			if task.HasProcessed(cadFile.ID, entity.FeatureRecognition) {
+				return nil
+			}
 
-		task.ProcessingTime = e.FeatureProps.FRETime
-		task.ProcessedCADFiles = append(task.ProcessedCADFiles, entity.Processed{ID: cadFile.ID, FileName: cadFile.FileName, ProcessType: entity.FeatureRecognition, Status: entity.Complete})
+			task.ProcessingTime = e.FeatureProps.FRETime
+			task.ProcessedCADFiles = append(task.ProcessedCADFiles, entity.Processed{ID: cadFile.ID, FileName: cadFile.FileName, ProcessType: entity.FeatureRecognition, Status: entity.Complete})
 
-		if task.Quantity == (int64(len(task.ProcessedCADFiles))) {
-			task.Status = entity.Complete
-		}
+			task.Settle()
 
-		returedTask, err := p.TaskService.Update(task)
+			return nil
+		})
 		if err != nil {
-			log.Fatalf("%s: %s", "Failed to update data: ", err)
+			return transient(StorageFailed, err)
 		}
 
 		log.Printf("[ User: %s > TaskID: %s > Task status: %s]: CAD file (%s) features saved successfully!", e.UserID, returedTask.TaskID, returedTask.Status, e.CADFileID)
 		log.Printf("==========================================================")
 
-		if returedTask.Status == entity.Complete {
+		if returedTask.Status != entity.Processing {
 			go func() {
+				defer recoverPanic("sending a task")
 				p.Processor.TaskChannel <- returedTask
 			}()
 
-			go func() {
-				project, err := p.ProjectService.Find(cadFile.ProjectID.Hex())
-				if err != nil {
-					log.Fatalf("%s: %s", "Project does not exist ", err)
-				}
-
-				cadFiles, err := p.CadFileService.FindAll(project.ID.Hex())
-				if err != nil {
-					log.Fatalf("%s: %s", "Failed to retrieve cad files data: ", err)
-				}
-
-				p.Processor.CADFilesChannel <- service.CADFileResponse{UserID: e.UserID, CadFiles: cadFiles}
-			}()
+			go p.sendCADFiles(e.UserID, cadFile.ProjectID.Hex())
 		}
 	case *contracts.ProcessPlanningComplete:
 		log.Printf("event %s created: %s", e.CADFileID, e.TaskID)
 		log.Printf("==========================================================")
 		fmt.Printf("Received a Processing plan for CAD file ID: %v\n", e.ProcessingPlan.CADFileID)
 
-		p.pdfService = service.NewPDFService()
-		processingPlan := entity.ProcessingPlan{}
-		processingPlan.ID = primitive.NewObjectID()
-		processingPlan.CADFileID = e.ProcessingPlan.CADFileID
-
-		cadFile, err := p.CadFileService.Find(processingPlan.CADFileID.Hex())
+		cadFile, err := p.CadFileService.Find(e.ProcessingPlan.CADFileID.Hex())
 		if err != nil {
-			log.Fatalf("%s: %s", "Cadfile does not exist ", err)
+			return lookupFailed(CADFileNotFound, err)
 		}
 
 		project, err := p.ProjectService.Find(cadFile.ProjectID.Hex())
 		if err != nil {
-			log.Fatalf("%s: %s", "Project does not exist ", err)
-		}
-
-		user, err := p.UserService.Profile(project.OwnerID.Hex())
-		if err != nil {
-			log.Fatalf("%s: %s", "User does not exist ", err)
-		}
-
-		processingPlan.Rotations = e.ProcessingPlan.Rotations
-		processingPlan.Flips = e.ProcessingPlan.Flips
-		processingPlan.Tools = e.ProcessingPlan.Tools
-		processingPlan.Modules = e.ProcessingPlan.Modules
-		processingPlan.ProcessingTime = e.ProcessingPlan.ProcessingTime
-		processingPlan.EstimatedManufacturingTime = e.ProcessingPlan.EstimatedManufacturingTime
-		processingPlan.TotalToolDistance = e.ProcessingPlan.TotalToolDistance
-		processingPlan.BendingSequences = e.ProcessingPlan.BendingSequences
-		processingPlan.Quantity = e.ProcessingPlan.Quantity
-		processingPlan.BendingForce = cadFile.FeatureProps.BendingForce
-		processingPlan.FileName = cadFile.FileName
-		processingPlan.Material = cadFile.Material
-		processingPlan.ProjectTitle = project.Title
-		processingPlan.Engineer = user.FullName()
-		processingPlan.BendFeatures = cadFile.BendFeatures
-
-		sid, err := shortid.New(1, shortid.DefaultABC, 2342)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed to save processing plan: ", err)
+			return lookupFailed(ProjectNotFound, err)
 		}
 
-		processingPlan.PartNo = sid.MustGenerate()
-		processingPlan.CreatedAt = time.Now().Unix()
-
-		pdfBuff, err := p.pdfService.GeneratePDF(&processingPlan)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed generate pdf: ", err.Error())
-		}
-
-		pdfBlob := service.NewAzureBlobService()
-		filename := fmt.Sprintf(project.ID.Hex()+"/%s.pdf", processingPlan.ID.Hex())
-		_, url, err := pdfBlob.UploadFromBuffer(&pdfBuff, filename)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed to upload processing plan: ", err.Error())
+		// A redelivered event finds the plan stored by an earlier attempt, and
+		// keeps its version, part number and PDF.
+		planID := service.PlanID(e.TaskID, cadFile.ID.Hex())
+		processingPlan, err := p.ProcessingPlanService.FindByID(planID.Hex())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			processingPlan, err = p.createProcessingPlan(e, planID, cadFile, project)
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return transient(StorageFailed, err)
 		}
 
-		processingPlan.PdfURL = url
-
-		_, err = p.ProcessingPlanService.Create(&processingPlan)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed to save processing plan: ", err)
+		// New plans are stored as the active version. A redelivered event only
+		// finishes an activation that failed, and leaves a plan alone once
+		// another version has been activated.
+		if processingPlan.Active {
+			_, err = p.ProcessingPlanService.Activate(processingPlan.CADFileID.Hex(), processingPlan.Version)
+			if err != nil {
+				return transient(StorageFailed, err)
+			}
 		}
 
 		cadFile.FeatureProps.ProcessLevel = e.ProcessLevel
 		_, err = p.CadFileService.Update(*cadFile)
 		if err != nil {
-			log.Fatalf("%s: %s", "Cadfile update failed ", err)
+			return transient(StorageFailed, err)
 		}
 
 		PROJECTCADFILES := controller.CADFILECACHE + cadFile.ProjectID.Hex()
 		go persistence.ClearCache(cadFile.ProjectID.Hex())
 		go persistence.ClearCache(processingPlan.ID.Hex())
+		go persistence.ClearCache(cadFile.ID.Hex())
 		go persistence.ClearCache(PROJECTCADFILES)
 
-		task, err := p.TaskService.Find(e.TaskID)
-		if err != nil {
-			log.Fatalf("%s: %s", "Failed to retrieve task data: ", err)
-		}
+		returedTask, err := p.TaskService.Mutate(e.TaskID, func(task *entity.Task) error {
+			if task.HasProcessed(cadFile.ID, entity.ProcessPlanning) {
+				return nil
+			}
 
-		task.ProcessingTime = e.ProcessingPlan.EstimatedManufacturingTime
-		task.ProcessedCADFiles = append(task.ProcessedCADFiles, entity.Processed{ID: cadFile.ID, FileName: cadFile.FileName, ProcessType: entity.ProcessPlanning, Status: entity.Complete})
+			task.ProcessingTime = e.ProcessingPlan.EstimatedManufacturingTime
+			task.EstimatedManufacturingTime += processingPlan.EstimatedManufacturingTime * float64(processingPlan.Cost.Quantity)
+			task.TotalCost += processingPlan.Cost.TotalCost
+			task.ProcessedCADFiles = append(task.ProcessedCADFiles, entity.Processed{ID: cadFile.ID, FileName: cadFile.FileName, ProcessType: entity.ProcessPlanning, Status: entity.Complete, PlanID: processingPlan.ID})
 
-		if task.Quantity == (int64(len(task.ProcessedCADFiles))) {
-			task.Status = entity.Complete
-		}
+			task.Settle()
 
-		returedTask, err := p.TaskService.Update(task)
+			return nil
+		})
 		if err != nil {
-			log.Fatalf("%s: %s", "Failed to update data: ", err)
+			return transient(StorageFailed, err)
 		}
 
-		if returedTask.Status == entity.Complete {
+		if returedTask.Status != entity.Processing {
 			go func() {
+				defer recoverPanic("sending a task")
 				p.Processor.TaskChannel <- returedTask
 				log.Printf("[ User: %s > TaskID: %s > Task status: %s]: CAD file (%s) processing plan saved successfully!", e.UserID, returedTask.ID, returedTask.Status, e.CADFileID)
 				log.Printf("==========================================================")
 			}()
 
-			go func() {
-				cadFiles, err := p.CadFileService.FindAll(project.ID.Hex())
-				if err != nil {
-					log.Fatalf("%s: %s", "Failed to retrieve cad files data: ", err)
-				}
-
-				p.Processor.CADFilesChannel <- service.CADFileResponse{UserID: e.UserID, CadFiles: cadFiles}
-			}()
+			go p.sendCADFiles(e.UserID, project.ID.Hex())
 		}
+	case *contracts.FeatureRecognitionFailed:
+		return p.handleFailure(e.UserID, e.TaskID, e.CADFileID, entity.FeatureRecognition, e.ErrorCode, e.ErrorMessage)
+	case *contracts.ProcessPlanningFailed:
+		return p.handleFailure(e.UserID, e.TaskID, e.CADFileID, entity.ProcessPlanning, e.ErrorCode, e.ErrorMessage)
+	case *contracts.FeatureRecognitionProgress:
+		return p.handleProgress(e.UserID, e.TaskID, e.CADFileID, entity.Progress{ProcessType: entity.FeatureRecognition, Stage: e.Stage, Percent: e.Percent, Message: e.Message, UpdatedAt: e.Timestamp})
+	case *contracts.ProcessPlanningProgress:
+		return p.handleProgress(e.UserID, e.TaskID, e.CADFileID, entity.Progress{ProcessType: entity.ProcessPlanning, Stage: e.Stage, Percent: e.Percent, Message: e.Message, UpdatedAt: e.Timestamp})
+	case *contracts.ProjectReportRequested:
+		return p.handleProjectReport(e)
 	default:
-		log.Printf("unknown event type: %T", e)
+		return msgqueue.Permanent(fmt.Errorf("unknown event type: %T", e))
+	}
+
+	return nil
+}
+
+// createProcessingPlan stores the plan of a planning event with its PDF and a
+// new version number.
+func (p *EventProcessor) createProcessingPlan(e *contracts.ProcessPlanningComplete, planID primitive.ObjectID, cadFile *entity.CADFile, project *entity.Project) (*entity.ProcessingPlan, error) {
+	pdfService := service.NewPDFService()
+	processingPlan := &entity.ProcessingPlan{}
+	processingPlan.ID = planID
+	processingPlan.CADFileID = cadFile.ID
+
+	user, err := p.UserService.Profile(project.OwnerID.Hex())
+	if err != nil {
+		return nil, lookupFailed(UserNotFound, err)
+	}
+
+	processingPlan.Rotations = e.ProcessingPlan.Rotations
+	processingPlan.Flips = e.ProcessingPlan.Flips
+	processingPlan.Tools = e.ProcessingPlan.Tools
+	processingPlan.Modules = e.ProcessingPlan.Modules
+	processingPlan.ProcessingTime = e.ProcessingPlan.ProcessingTime
+	processingPlan.EstimatedManufacturingTime = e.ProcessingPlan.EstimatedManufacturingTime
+	processingPlan.TotalToolDistance = e.ProcessingPlan.TotalToolDistance
+	processingPlan.BendingSequences = e.ProcessingPlan.BendingSequences
+	processingPlan.Quantity = e.ProcessingPlan.Quantity
+	processingPlan.BendingForce = cadFile.FeatureProps.BendingForce
+	processingPlan.BendingForceModel = cadFile.FeatureProps.BendingForceModel
+	processingPlan.FileName = cadFile.FileName
+	processingPlan.Material = cadFile.Material
+	processingPlan.ProjectTitle = project.Title
+	processingPlan.Engineer = user.FullName()
+	processingPlan.BendFeatures = cadFile.BendFeatures
+	processingPlan.FlatPattern = cadFile.FlatPattern
+	// The planner echoes the constraints it planned a re-plan under.
+	processingPlan.Constraints = e.ProcessingPlan.Constraints
+
+	sid, err := shortid.New(1, shortid.DefaultABC, 2342)
+	if err != nil {
+		return nil, permanent(InternalError, err)
+	}
+
+	processingPlan.PartNo, err = sid.Generate()
+	if err != nil {
+		return nil, transient(InternalError, err)
+	}
+	processingPlan.CreatedAt = time.Now().Unix()
+
+	// A new plan, including a re-plan, becomes the active version.
+	processingPlan.Active = true
+	processingPlan.Status = entity.PlanDraft
+	processingPlan.Reviewers = []entity.Reviewer{}
+	processingPlan.ReviewComments = []entity.ReviewComment{}
+
+	material, err := p.MaterialService.Find(cadFile.Material)
+	if err != nil {
+		return nil, lookupFailed(MaterialNotFound, fmt.Errorf("material %s: %w", cadFile.Material, err))
+	}
+
+	machine, err := p.findMachine(project)
+	if err != nil {
+		return nil, transient(MachineNotFound, err)
+	}
+
+	if machine != nil {
+		processingPlan.MachineID = machine.ID
+		processingPlan.MachineName = machine.Name
+		processingPlan.CapacityIssues = p.MachineService.CheckCapacity(machine, cadFile, processingPlan)
+	}
+
+	processingPlan.Cost, err = p.CostingService.Estimate(processingPlan, cadFile, material, machine, processingPlan.Quantity)
+	if err != nil {
+		return nil, transient(StorageFailed, err)
+	}
+
+	pdfBlob := service.NewAzureBlobService()
+
+	// Plans are still printed, without illustrations, when the mesh is unavailable.
+	part, err := service.LoadPartMesh(pdfBlob, cadFile.ObjpURL)
+	if err != nil {
+		log.Printf("failed to load mesh of CAD file %s: %s", cadFile.ID.Hex(), err)
+	}
+
+	// Plans are branded for the customer that owns the project.
+	template, err := p.PDFTemplateService.ForCustomer(project.OwnerID.Hex())
+	if err != nil {
+		return nil, transient(StorageFailed, err)
+	}
+	processingPlan.PDFTemplateID = template.ID
+
+	// The version is printed on the PDF, so it is allocated once everything
+	// else the plan needs is at hand; an event that fails earlier uses none.
+	processingPlan.Version, err = p.ProcessingPlanService.NextVersion(processingPlan.CADFileID.Hex())
+	if err != nil {
+		return nil, transient(StorageFailed, err)
+	}
+
+	pdfBuff, err := pdfService.GeneratePDF(processingPlan, part, template)
+	if err != nil {
+		return nil, permanent(PDFGenerationFailed, err)
+	}
+
+	filename := fmt.Sprintf(project.ID.Hex()+"/%s.pdf", processingPlan.ID.Hex())
+	_, url, err := pdfBlob.UploadFromBuffer(&pdfBuff, filename)
+	if err != nil {
+		return nil, transient(UploadFailed, err)
+	}
+
+	processingPlan.PdfURL = url
+
+	_, err = p.ProcessingPlanService.Create(processingPlan)
+	if err != nil {
+		return nil, transient(StorageFailed, err)
+	}
+
+	return processingPlan, nil
+}
+
+// handleFailure records a CAD file that a worker could not process on its task
+// and reports the failure to the user.
+func (p *EventProcessor) handleFailure(userID string, taskID string, cadFileID string, processType entity.ProcessType, errorCode string, errorMessage string) error {
+	log.Printf("[ User: %s > TaskID: %s ]: %s of CAD file (%s) failed: %s %s", userID, taskID, processType, cadFileID, errorCode, errorMessage)
+
+	failure := entity.Processed{ProcessType: processType, Status: entity.Failed, ErrorCode: errorCode, ErrorMessage: errorMessage}
+
+	// The failure may be that the CAD file is gone, so only its ID is certain.
+	cadFile, err := p.CadFileService.Find(cadFileID)
+	if err == nil {
+		failure.ID = cadFile.ID
+		failure.FileName = cadFile.FileName
+	} else if failure.ID, err = primitive.ObjectIDFromHex(cadFileID); err != nil {
+		return permanent(CADFileNotFound, err)
+	}
+
+	task, err := p.TaskService.Mutate(taskID, func(task *entity.Task) error {
+		if task.HasProcessed(failure.ID, processType) {
+			return nil
+		}
+
+		task.ProcessedCADFiles = append(task.ProcessedCADFiles, failure)
+		task.Settle()
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go persistence.ClearCache(controller.TASKCACHE)
+
+	go func() {
+		defer recoverPanic("sending a failure")
+		p.Processor.FailureChannel <- service.FailureResponse{UserID: userID, TaskID: taskID, Failure: failure}
+	}()
+
+	if task.Status != entity.Processing {
+		go func() {
+			defer recoverPanic("sending a task")
+			p.Processor.TaskChannel <- task
+		}()
+	}
+
+	return nil
+}
+
+// handleProgress stores the latest progress of a CAD file on its task and
+// forwards it to the user.
+func (p *EventProcessor) handleProgress(userID string, taskID string, cadFileID string, progress entity.Progress) error {
+	cadFile, err := p.CadFileService.Find(cadFileID)
+	if err != nil {
+		return lookupFailed(CADFileNotFound, err)
+	}
+
+	progress.CADFileID = cadFile.ID
+	progress.FileName = cadFile.FileName
+	if progress.UpdatedAt == 0 {
+		progress.UpdatedAt = time.Now().Unix()
+	}
+
+	_, err = p.TaskService.Mutate(taskID, func(task *entity.Task) error {
+		// Progress arriving after the file was done is stale.
+		if !task.HasProcessed(cadFile.ID, progress.ProcessType) {
+			task.UpdateProgress(progress)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return transient(StorageFailed, err)
+	}
+
+	go persistence.ClearCache(controller.TASKCACHE)
+
+	go func() {
+		defer recoverPanic("sending progress")
+		p.Processor.ProgressChannel <- service.ProgressResponse{UserID: userID, TaskID: taskID, Progress: progress}
+	}()
+
+	return nil
+}
+
+// findMachine returns the machine chosen for a project, or nil if there is
+// none. A machine that has since been deleted counts as none so the project's
+// parts can still be processed.
+func (p *EventProcessor) findMachine(project *entity.Project) (*entity.Machine, error) {
+	if project.MachineID.IsZero() {
+		return nil, nil
+	}
+
+	machine, err := p.MachineService.Find(project.MachineID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("machine %s of project %s no longer exists", project.MachineID.Hex(), project.ID.Hex())
+		return nil, nil
+	}
+
+	return machine, err
+}
+
+// handleProjectReport draws the report of a project's processing plans,
+// uploads it and completes the task that asked for it.
+func (p *EventProcessor) handleProjectReport(e *contracts.ProjectReportRequested) error {
+	log.Printf("[ User: %s > TaskID: %s ]: report of project (%s) requested", e.UserID, e.TaskID, e.ProjectID)
+
+	project, err := p.ProjectService.Find(e.ProjectID)
+	if err != nil {
+		return lookupFailed(ProjectNotFound, err)
+	}
+
+	cadFiles, err := p.CadFileService.FindAll(e.ProjectID)
+	if err != nil {
+		return transient(StorageFailed, err)
+	}
+
+	processingPlans := []entity.ProcessingPlan{}
+	for _, cadFile := range cadFiles {
+		if cadFile.FeatureProps.ProcessLevel != 2 {
+			continue
+		}
+
+		processingPlan, err := p.ProcessingPlanService.Find(cadFile.ID.Hex())
+		if err != nil {
+			return lookupFailed(PlanNotFound, fmt.Errorf("CAD file %s: %w", cadFile.ID.Hex(), err))
+		}
+
+		processingPlans = append(processingPlans, *processingPlan)
+	}
+
+	template, err := p.PDFTemplateService.ForCustomer(project.OwnerID.Hex())
+	if err != nil {
+		return transient(StorageFailed, err)
+	}
+
+	pdfBuff, err := service.NewPDFService().GenerateProjectReport(project, processingPlans, template)
+	if err != nil {
+		return permanent(PDFGenerationFailed, err)
+	}
+
+	filename := fmt.Sprintf(project.ID.Hex()+"/report-%s.pdf", e.TaskID)
+	_, url, err := service.NewAzureBlobService().UploadFromBuffer(&pdfBuff, filename)
+	if err != nil {
+		return transient(UploadFailed, err)
+	}
+
+	totals := service.ProjectReportTotals(processingPlans)
+
+	task, err := p.TaskService.Mutate(e.TaskID, func(task *entity.Task) error {
+		if task.HasProcessed(project.ID, entity.ProjectReport) {
+			return nil
+		}
+
+		task.ReportURL = url
+		task.EstimatedManufacturingTime = totals.ProductionTime
+		task.TotalCost = totals.Cost
+		task.ProcessedCADFiles = append(task.ProcessedCADFiles, entity.Processed{ID: project.ID, FileName: project.Title, ProcessType: entity.ProjectReport, Status: entity.Complete})
+
+		task.Settle()
+
+		return nil
+	})
+	if err != nil {
+		return transient(StorageFailed, err)
+	}
+
+	go persistence.ClearCache(controller.TASKCACHE)
+
+	go func() {
+		defer recoverPanic("sending a task")
+		p.Processor.TaskChannel <- task
+	}()
+
+	return nil
+}
+
+// sendCADFiles pushes the project's CAD files to the user once a task is done.
+func (p *EventProcessor) sendCADFiles(userID string, projectID string) {
+	defer recoverPanic("sending CAD files")
+
+	cadFiles, err := p.CadFileService.FindAll(projectID)
+	if err != nil {
+		log.Printf("failed to retrieve CAD files of project %s: %s", projectID, err)
+		return
+	}
+
+	p.Processor.CADFilesChannel <- service.CADFileResponse{UserID: userID, CadFiles: cadFiles}
+}
+
+// recoverPanic keeps a panicking goroutine from taking down the process.
+func recoverPanic(what string) {
+	if r := recover(); r != nil {
+		log.Printf("recovered from panic while %s: %v\n%s", what, r, debug.Stack())
 	}
 }
@@ -0,0 +1,222 @@
+package listener
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/WilfredDube/fxtract-backend/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type testEvent struct {
+	id string
+}
+
+func (e *testEvent) EventName() string {
+	return "testEvent"
+}
+
+func (e *testEvent) EventID() string {
+	return e.id
+}
+
+type deliveryStub struct {
+	event    msgqueue.Event
+	acked    bool
+	rejected error
+}
+
+func (d *deliveryStub) Event() msgqueue.Event {
+	return d.event
+}
+
+func (d *deliveryStub) Attempt() int {
+	return 0
+}
+
+func (d *deliveryStub) Ack() error {
+	d.acked = true
+	return nil
+}
+
+func (d *deliveryStub) Reject(err error) error {
+	d.rejected = err
+	return nil
+}
+
+func (d *deliveryStub) WillRetry(err error) bool {
+	return !msgqueue.IsPermanent(err)
+}
+
+type ledgerStub struct {
+	claimed  bool
+	claimErr error
+	calls    []string
+}
+
+func (l *ledgerStub) Claim(eventID string, eventName string) (bool, error) {
+	l.calls = append(l.calls, "claim")
+	return l.claimed, l.claimErr
+}
+
+func (l *ledgerStub) Complete(eventID string) error {
+	l.calls = append(l.calls, "complete")
+	return nil
+}
+
+func (l *ledgerStub) Release(eventID string) error {
+	l.calls = append(l.calls, "release")
+	return nil
+}
+
+func TestProcessClaimsEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		ledger    *ledgerStub
+		wantCalls []string
+		wantAck   bool
+	}{
+		// The test event has no handler, so a claimed event fails and is
+		// released for a later attempt.
+		{"claimed", &ledgerStub{claimed: true}, []string{"claim", "release"}, false},
+		{"already handled", &ledgerStub{claimed: false}, []string{"claim"}, true},
+		{"ledger unavailable", &ledgerStub{claimErr: errors.New("database unavailable")}, []string{"claim"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor := &EventProcessor{EventLedger: test.ledger, Metrics: msgqueue.NewConsumerMetrics()}
+			delivery := &deliveryStub{event: &testEvent{id: "event-1"}}
+
+			processor.process(delivery)
+
+			if len(test.ledger.calls) != len(test.wantCalls) {
+				t.Fatalf("ledger calls %v, want %v", test.ledger.calls, test.wantCalls)
+			}
+			for i := range test.wantCalls {
+				if test.ledger.calls[i] != test.wantCalls[i] {
+					t.Fatalf("ledger calls %v, want %v", test.ledger.calls, test.wantCalls)
+				}
+			}
+
+			if delivery.acked != test.wantAck || (delivery.rejected == nil) != test.wantAck {
+				t.Errorf("delivery acked = %v, rejected with %v; want acked = %v", delivery.acked, delivery.rejected, test.wantAck)
+			}
+		})
+	}
+}
+
+type cadFileServiceStub struct {
+	service.CadFileService
+	cadFile *entity.CADFile
+	err     error
+}
+
+func (s *cadFileServiceStub) Find(id string) (*entity.CADFile, error) {
+	return s.cadFile, s.err
+}
+
+func (s *cadFileServiceStub) Update(cadFile entity.CADFile) (*entity.CADFile, error) {
+	return &cadFile, nil
+}
+
+type projectServiceStub struct {
+	service.ProjectService
+	project *entity.Project
+}
+
+func (s *projectServiceStub) Find(id string) (*entity.Project, error) {
+	return s.project, nil
+}
+
+type processingPlanServiceStub struct {
+	service.ProcessingPlanService
+	plan      *entity.ProcessingPlan
+	activated []int64
+}
+
+func (s *processingPlanServiceStub) FindByID(id string) (*entity.ProcessingPlan, error) {
+	return s.plan, nil
+}
+
+func (s *processingPlanServiceStub) Activate(cadFileID string, version int64) (int64, error) {
+	s.activated = append(s.activated, version)
+	return 1, nil
+}
+
+type taskServiceStub struct {
+	service.TaskService
+}
+
+func (s *taskServiceStub) Mutate(id string, mutate func(task *entity.Task) error) (*entity.Task, error) {
+	task := &entity.Task{Status: entity.Processing, Quantity: 2}
+	return task, mutate(task)
+}
+
+func TestHandleProgressClassifiesLookupFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantPermanent bool
+	}{
+		{"missing CAD file", mongo.ErrNoDocuments, true},
+		{"malformed ID", primitive.ErrInvalidHex, true},
+		{"database unavailable", errors.New("connection refused"), false},
+	}
+
+	for _, test := range tests {
+		processor := &EventProcessor{CadFileService: &cadFileServiceStub{err: test.err}}
+
+		err := processor.handleProgress("user-1", "task-1", "cadfile-1", entity.Progress{})
+		if errorCode(err) != CADFileNotFound || msgqueue.IsPermanent(err) != test.wantPermanent {
+			t.Errorf("%s: handleProgress returned %v, want a %s error that is permanent = %v", test.name, err, CADFileNotFound, test.wantPermanent)
+		}
+	}
+}
+
+func TestRedeliveredPlanKeepsTheActiveVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		active        bool
+		wantActivated bool
+	}{
+		// The first attempt stored the plan but failed to activate it.
+		{"activation interrupted", true, true},
+		// Another version was activated since the plan was stored.
+		{"another version active", false, false},
+	}
+
+	for _, test := range tests {
+		cadFile := &entity.CADFile{ID: primitive.NewObjectID(), ProjectID: primitive.NewObjectID()}
+		plans := &processingPlanServiceStub{plan: &entity.ProcessingPlan{
+			ID:        service.PlanID("task-1", cadFile.ID.Hex()),
+			CADFileID: cadFile.ID,
+			Version:   3,
+			Active:    test.active,
+		}}
+
+		processor := &EventProcessor{
+			CadFileService:        &cadFileServiceStub{cadFile: cadFile},
+			ProjectService:        &projectServiceStub{project: &entity.Project{ID: cadFile.ProjectID}},
+			ProcessingPlanService: plans,
+			TaskService:           &taskServiceStub{},
+		}
+
+		event := &contracts.ProcessPlanningComplete{
+			TaskID:         "task-1",
+			CADFileID:      cadFile.ID.Hex(),
+			ProcessingPlan: entity.ProcessingPlan{CADFileID: cadFile.ID},
+		}
+		if err := processor.handleEvent(event); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if activated := len(plans.activated) == 1 && plans.activated[0] == 3; activated != test.wantActivated {
+			t.Errorf("%s: activated versions %v, want version 3 activated = %v", test.name, plans.activated, test.wantActivated)
+		}
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/WilfredDube/fxtract-backend/configuration"
 	"github.com/WilfredDube/fxtract-backend/controller"
 	"github.com/WilfredDube/fxtract-backend/lib/helper"
+	amqphelper "github.com/WilfredDube/fxtract-backend/lib/helper/amqp"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
 	msgqueue_amqp "github.com/WilfredDube/fxtract-backend/lib/msgqueue/amqp"
+	msgqueue_memory "github.com/WilfredDube/fxtract-backend/lib/msgqueue/memory"
 	"github.com/WilfredDube/fxtract-backend/listener"
 	"github.com/WilfredDube/fxtract-backend/middleware"
 	"github.com/WilfredDube/fxtract-backend/repository"
@@ -19,7 +23,6 @@ import (
 	"github.com/WilfredDube/fxtract-backend/service"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
-	"github.com/streadway/amqp"
 	"gopkg.in/boj/redistore.v1"
 )
 
@@ -35,24 +38,65 @@ func main() {
 	config, _ := configuration.ExtractConfiguration(*configPath)
 	repo := persistence.NewPersistenceLayer(config)
 
-	conn, err := amqp.Dial(config.AMQPMessageBroker)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	eventEmitter, err := msgqueue_amqp.NewAMQPEventEmitter(conn, "processes")
-	if err != nil {
-		panic(err)
-	}
+	var eventEmitter msgqueue.EventEmitter
+	var eventListener, processPlannerEventListener, reportEventListener msgqueue.EventListener
+	var err error
 
-	eventListener, err := msgqueue_amqp.NewAMQPEventListener(conn, "processes", "FEATURERECOGNITIONCOMPLETE")
-	if err != nil {
-		panic(err)
+	retryPolicy := msgqueue.RetryPolicy{
+		MaxRetries:     config.EventMaxRetries,
+		InitialBackoff: time.Duration(config.EventRetryBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(config.EventMaxBackoff) * time.Millisecond,
 	}
 
-	processPlannerEventListener, err := msgqueue_amqp.NewAMQPEventListener(conn, "processes", "PROCESSPLANNINGCOMPLETE")
-	if err != nil {
-		panic(err)
+	switch config.MessageBrokerType {
+	case configuration.MEMORYBROKER:
+		broker := msgqueue_memory.NewBroker()
+
+		eventEmitter, err = msgqueue_memory.NewMemoryEventEmitter(broker)
+		if err != nil {
+			panic(err)
+		}
+
+		eventListener, err = msgqueue_memory.NewMemoryEventListener(broker, "FEATURERECOGNITIONCOMPLETE", retryPolicy)
+		if err != nil {
+			panic(err)
+		}
+
+		processPlannerEventListener, err = msgqueue_memory.NewMemoryEventListener(broker, "PROCESSPLANNINGCOMPLETE", retryPolicy)
+		if err != nil {
+			panic(err)
+		}
+
+		reportEventListener, err = msgqueue_memory.NewMemoryEventListener(broker, "PROJECTREPORTS", retryPolicy)
+		if err != nil {
+			panic(err)
+		}
+	default:
+		conn := amqphelper.Dial(config.AMQPMessageBroker, 5*time.Second)
+
+		emitterOptions := msgqueue_amqp.DefaultEmitterOptions
+		emitterOptions.PoolSize = config.EmitterPoolSize
+		emitterOptions.BatchSize = config.EmitterBatchSize
+
+		eventEmitter, err = msgqueue_amqp.NewAMQPEventEmitter(conn, "processes", emitterOptions)
+		if err != nil {
+			panic(err)
+		}
+
+		eventListener, err = msgqueue_amqp.NewAMQPEventListener(conn, "processes", "FEATURERECOGNITIONCOMPLETE", retryPolicy, config.ListenerPrefetch)
+		if err != nil {
+			panic(err)
+		}
+
+		processPlannerEventListener, err = msgqueue_amqp.NewAMQPEventListener(conn, "processes", "PROCESSPLANNINGCOMPLETE", retryPolicy, config.ListenerPrefetch)
+		if err != nil {
+			panic(err)
+		}
+
+		reportEventListener, err = msgqueue_amqp.NewAMQPEventListener(conn, "processes", "PROJECTREPORTS", retryPolicy, config.ListenerPrefetch)
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	sessionStore, err := redistore.NewRediStore(0, "tcp", config.RedisHost+":"+config.RedisPort, "", []byte("oNrT10hnwnnUTeiwqm1ISP6W5qXmHWkT"))
@@ -85,12 +129,13 @@ func main() {
 	projectService := service.NewProjectService(projectRepo)
 	projectController := controller.NewProjectController(projectService, userService, cadFileService, processingPlanService, JWTService, redisCache)
 
-	cadFileController := controller.NewCADFileController(cadFileService, projectService, JWTService, processingPlanService, redisCache)
-
 	toolRepo := repository.NewToolRepository(*repo)
 	toolService := service.NewToolService(toolRepo)
+	toolSelectionService := service.NewToolSelectionService(toolService)
 	toolController := controller.NewToolController(toolService, userService, JWTService, redisCache)
 
+	cadFileController := controller.NewCADFileController(cadFileService, projectService, JWTService, processingPlanService, toolSelectionService, redisCache)
+
 	materialRepo := repository.NewMaterialRepository(*repo)
 	materialService := service.NewMaterialService(materialRepo)
 	materialController := controller.NewMaterialController(materialService, userService, JWTService, redisCache)
@@ -99,8 +144,44 @@ func main() {
 	taskService := service.NewTaskService(taskRepo)
 	taskController := controller.NewTaskController(taskService, JWTService, redisCache)
 
+	machineRepo := repository.NewMachineRepository(*repo)
+	machineService := service.NewMachineService(machineRepo)
+	machineController := controller.NewMachineController(machineService, JWTService, redisCache)
+
+	costingRepo := repository.NewCostingRepository(*repo)
+	costingService := service.NewCostingService(costingRepo)
+	costingController := controller.NewCostingController(costingService, taskService, cadFileService, processingPlanService, materialService, machineService, JWTService)
+	pdfTemplateRepo := repository.NewPDFTemplateRepository(*repo)
+	pdfTemplateService := service.NewPDFTemplateService(pdfTemplateRepo)
+	pdfTemplateController := controller.NewPDFTemplateController(pdfTemplateService, JWTService)
+
+	processingPlanController := controller.NewProcessingPlanController(processingPlanService, cadFileService, projectService, userService, pdfTemplateService, JWTService)
+	exportController := controller.NewExportController(service.NewExportService(), processingPlanService, cadFileService, projectService, toolService, JWTService)
+
+	processedEventRepo := repository.NewProcessedEventRepository(*repo)
+	processedEventService := service.NewProcessedEventService(processedEventRepo)
+
 	processorController := service.NewProcessor(JWTService)
-	freController := controller.NewFREController(config, cadFileService, processingPlanService, userService, JWTService, taskService, redisCache, eventEmitter, processorController)
+	deadLetterController := controller.NewDeadLetterController(map[string]msgqueue.DeadLetterQueue{
+		"featureRecognitionComplete": eventListener.DeadLetters(),
+		"processPlanningComplete":    processPlannerEventListener.DeadLetters(),
+		"projectReportRequested":     reportEventListener.DeadLetters(),
+	}, JWTService)
+
+	outboxRepo := repository.NewOutboxRepository(*repo)
+	outboxRelay := service.NewOutboxRelay(outboxRepo, taskService, controller.TASKCACHE, eventEmitter, time.Duration(config.OutboxPollInterval)*time.Millisecond, config.OutboxMaxAttempts)
+
+	featureRecognitionMetrics := msgqueue.NewConsumerMetrics()
+	processPlanningMetrics := msgqueue.NewConsumerMetrics()
+	projectReportMetrics := msgqueue.NewConsumerMetrics()
+	eventMetricsController := controller.NewEventMetricsController(map[string]*msgqueue.ConsumerMetrics{
+		"featureRecognitionComplete": featureRecognitionMetrics,
+		"processPlanningComplete":    processPlanningMetrics,
+		"projectReportRequested":     projectReportMetrics,
+	}, JWTService)
+
+	freController := controller.NewFREController(config, cadFileService, processingPlanService, userService, JWTService, taskService, redisCache, outboxRelay, processorController)
+	reportController := controller.NewReportController(projectService, cadFileService, taskService, outboxRelay, JWTService)
 
 	r := mux.NewRouter()
 
@@ -120,10 +201,18 @@ func main() {
 
 	// Feature recognition / processing plan API based on the CAD file's process level
 	r.HandleFunc("/api/user/projects/{pid}/files/{id}", freController.ProcessCADFile).Methods("POST").Queries("operation", "{process}")
+	r.HandleFunc("/api/user/projects/{pid}/files/{id}/replan", freController.ReplanCADFile).Methods("POST")
 	r.HandleFunc("/api/user/ws", processorController.Handler(freController.BatchProcessCADFiles)).Methods("GET") //.Methods("POST").Queries("operation", "{process}")
 	// r.HandleFunc("/api/user/ws", freController.BatchProcessCADFiles).Methods("GET") //.Methods("POST").Queries("operation", "{process}")
 	r.HandleFunc("/api/user/process/{id}", projectController.FindProcessPlan).Methods("GET")
 
+	// Processing plan exports
+	r.HandleFunc("/api/user/process/{id}/export", exportController.ExportPlan).Methods("GET")
+	r.HandleFunc("/api/user/projects/{id}/export", exportController.ExportProject).Methods("GET")
+	r.HandleFunc("/api/user/projects/{id}/report", reportController.GenerateProjectReport).Methods("GET")
+	r.HandleFunc("/api/user/process/{id}/program", exportController.ExportProgram).Methods("GET")
+	r.HandleFunc("/api/user/program-dialects", exportController.ProgramDialects).Methods("GET")
+
 	r.HandleFunc("/api/user/materials", materialController.FindAll).Methods("GET")
 
 	// User registration and login
@@ -157,9 +246,40 @@ func main() {
 	r.HandleFunc("/api/admin/materials/{id}", middleware.CheckAdminRole(JWTService, materialController.Find)).Methods("GET")
 	r.HandleFunc("/api/admin/materials/{id}", middleware.CheckAdminRole(JWTService, materialController.Delete)).Methods("DELETE")
 
+	// Press brake machines
+	r.HandleFunc("/api/admin/machines", middleware.CheckAdminRole(JWTService, machineController.AddMachine)).Methods("POST")
+	r.HandleFunc("/api/admin/machines", middleware.CheckAdminRole(JWTService, machineController.FindAll)).Methods("GET")
+	r.HandleFunc("/api/admin/machines/{id}", middleware.CheckAdminRole(JWTService, machineController.FindByID)).Methods("GET")
+	r.HandleFunc("/api/admin/machines/{id}", middleware.CheckAdminRole(JWTService, machineController.UpdateMachine)).Methods("PUT")
+	r.HandleFunc("/api/admin/machines/{id}", middleware.CheckAdminRole(JWTService, machineController.Delete)).Methods("DELETE")
+
+	// PDF templates and customer branding
+	r.HandleFunc("/api/admin/pdf-templates", middleware.CheckAdminRole(JWTService, pdfTemplateController.AddTemplate)).Methods("POST")
+	r.HandleFunc("/api/admin/pdf-templates", middleware.CheckAdminRole(JWTService, pdfTemplateController.FindAll)).Methods("GET")
+	r.HandleFunc("/api/admin/pdf-templates/{id}", middleware.CheckAdminRole(JWTService, pdfTemplateController.FindByID)).Methods("GET")
+	r.HandleFunc("/api/admin/pdf-templates/{id}", middleware.CheckAdminRole(JWTService, pdfTemplateController.UpdateTemplate)).Methods("PUT")
+	r.HandleFunc("/api/admin/pdf-templates/{id}", middleware.CheckAdminRole(JWTService, pdfTemplateController.Delete)).Methods("DELETE")
+	r.HandleFunc("/api/admin/pdf-templates/{id}/logo", middleware.CheckAdminRole(JWTService, pdfTemplateController.UploadLogo)).Methods("PUT")
+	r.HandleFunc("/api/admin/pdf-templates/{id}/logo", middleware.CheckAdminRole(JWTService, pdfTemplateController.RemoveLogo)).Methods("DELETE")
+
 	// Files uploaded
 	r.HandleFunc("/api/admin/files", middleware.CheckAdminRole(JWTService, cadFileController.FindAllFiles)).Methods("GET")
 
+	// Tools that can form the bends of a CAD file
+	r.HandleFunc("/api/user/files/{id}/tool-candidates", cadFileController.FindToolCandidates).Methods("GET")
+
+	// Processing plan versions
+	r.HandleFunc("/api/user/files/{id}/plans", processingPlanController.FindVersions).Methods("GET")
+	r.HandleFunc("/api/user/files/{id}/plans/compare", processingPlanController.Compare).Methods("GET")
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}", processingPlanController.FindVersion).Methods("GET")
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}/activate", processingPlanController.Activate).Methods("PUT")
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}/pdf", processingPlanController.RenderPDF).Methods("GET")
+
+	// Processing plan reviews
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}/reviewers", processingPlanController.AssignReviewers).Methods("PUT")
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}/comments", processingPlanController.AddComment).Methods("POST")
+	r.HandleFunc("/api/user/files/{id}/plans/{version:[0-9]+}/status", processingPlanController.ChangeStatus).Methods("PUT")
+
 	// Download OBJ file
 	r.HandleFunc("/api/user/projects/files", cadFileController.DownloadOBJ).Methods("POST").Queries("url", "{url}")
 
@@ -168,6 +288,18 @@ func main() {
 	r.HandleFunc("/api/user/tasks", taskController.FindByUserID).Methods("GET")
 	r.HandleFunc("/api/tasks/{id}", taskController.Find).Methods("GET")
 
+	// Manufacturing costs
+	r.HandleFunc("/api/admin/costing", middleware.CheckAdminRole(JWTService, costingController.FindSettings)).Methods("GET")
+	r.HandleFunc("/api/admin/costing", middleware.CheckAdminRole(JWTService, costingController.UpdateSettings)).Methods("PUT")
+	r.HandleFunc("/api/user/tasks/{id}/cost", costingController.FindTaskCost).Methods("GET")
+	r.HandleFunc("/api/user/files/{id}/cost", costingController.Estimate).Methods("GET")
+
+	// Dead-lettered events
+	r.HandleFunc("/api/admin/events/{event}/dead-letters", middleware.CheckAdminRole(JWTService, deadLetterController.FindAll)).Methods("GET")
+	r.HandleFunc("/api/admin/events/{event}/dead-letters", middleware.CheckAdminRole(JWTService, deadLetterController.Replay)).Methods("POST")
+	r.HandleFunc("/api/admin/events/{event}/dead-letters", middleware.CheckAdminRole(JWTService, deadLetterController.Purge)).Methods("DELETE")
+	r.HandleFunc("/api/admin/events/metrics", middleware.CheckAdminRole(JWTService, eventMetricsController.FindAll)).Methods("GET")
+
 	// processes: type, status
 
 	originsObj := handlers.AllowedOrigins([]string{"*"})
@@ -180,6 +312,7 @@ func main() {
 	server := handlers.CORS(credentials, originsObj, methodsObj, headersObj)(r)
 
 	processorController.Start()
+	outboxRelay.Start()
 
 	errs := make(chan error, 3)
 	go func() {
@@ -199,12 +332,19 @@ func main() {
 	}()
 
 	processor := listener.EventProcessor{EventListener: eventListener, CadFileService: cadFileService,
-		TaskService: taskService, ProcessingPlanService: processingPlanService, ToolService: toolService, MaterialService: materialService, ProjectService: projectService, UserService: userService, Processor: processorController}
-	go processor.ProcessEvents("featureRecognitionComplete")
+		TaskService: taskService, ProcessingPlanService: processingPlanService, ToolService: toolService, ToolSelectionService: toolSelectionService, MaterialService: materialService, MachineService: machineService, ProjectService: projectService, UserService: userService, Processor: processorController, EventLedger: processedEventService,
+		Workers: config.ListenerWorkers, Metrics: featureRecognitionMetrics}
+	go processor.ProcessEvents("featureRecognitionComplete", "featureRecognitionFailed", "featureRecognitionProgress")
 
 	processPlanner := listener.EventProcessor{EventListener: processPlannerEventListener, CadFileService: cadFileService,
-		TaskService: taskService, ProcessingPlanService: processingPlanService, MaterialService: materialService, ProjectService: projectService, UserService: userService, Processor: processorController}
-	go processPlanner.ProcessEvents("processPlanningComplete")
+		TaskService: taskService, ProcessingPlanService: processingPlanService, MaterialService: materialService, CostingService: costingService, MachineService: machineService, ProjectService: projectService, UserService: userService, PDFTemplateService: pdfTemplateService, Processor: processorController, EventLedger: processedEventService,
+		Workers: config.ListenerWorkers, Metrics: processPlanningMetrics}
+	go processPlanner.ProcessEvents("processPlanningComplete", "processPlanningFailed", "processPlanningProgress")
+
+	reporter := listener.EventProcessor{EventListener: reportEventListener, CadFileService: cadFileService,
+		TaskService: taskService, ProcessingPlanService: processingPlanService, ProjectService: projectService, PDFTemplateService: pdfTemplateService, Processor: processorController, EventLedger: processedEventService,
+		Workers: config.ListenerWorkers, Metrics: projectReportMetrics}
+	go reporter.ProcessEvents("projectReportRequested")
 
 	fmt.Printf("Terminated %s\n", <-errs)
 }
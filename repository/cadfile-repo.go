@@ -67,6 +67,8 @@ func (r *cadFileRepoConnection) Create(cadFile *entity.CADFile) (*entity.CADFile
 			"filesize":      cadFile.Filesize,
 			"feature_props": cadFile.FeatureProps,
 			"bend_features": cadFile.BendFeatures,
+			"flat_pattern":  cadFile.FlatPattern,
+			"dfm_findings":  cadFile.DFMFindings,
 			"created_at":    cadFile.CreatedAt,
 		},
 	)
@@ -97,6 +99,8 @@ func (r *cadFileRepoConnection) Update(cadFile entity.CADFile) (*entity.CADFile,
 				"filesize":      cadFile.Filesize,
 				"feature_props": cadFile.FeatureProps,
 				"bend_features": cadFile.BendFeatures,
+				"flat_pattern":  cadFile.FlatPattern,
+				"dfm_findings":  cadFile.DFMFindings,
 				"created_at":    cadFile.CreatedAt,
 			}}},
 	)
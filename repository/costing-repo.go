@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WilfredDube/fxtract-backend/configuration"
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CostingRepository -
+type CostingRepository interface {
+	// Find the costing settings. Returns mongo.ErrNoDocuments if they were
+	// never saved.
+	Find() (*entity.CostingSettings, error)
+
+	// Save replaces the costing settings
+	Save(settings *entity.CostingSettings) (*entity.CostingSettings, error)
+}
+
+const (
+	costingCollectionName string = "costing"
+	// costingSettingsID is the ID of the only settings document.
+	costingSettingsID string = "settings"
+)
+
+// costingRepoConnection -
+type costingRepoConnection struct {
+	connection configuration.MongoRepository
+}
+
+// NewCostingRepository -
+func NewCostingRepository(db configuration.MongoRepository) CostingRepository {
+	return &costingRepoConnection{
+		connection: db,
+	}
+}
+
+func (r *costingRepoConnection) Find() (*entity.CostingSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	settings := &entity.CostingSettings{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(costingCollectionName)
+
+	err := collection.FindOne(ctx, bson.M{"_id": costingSettingsID}).Decode(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Costing.Find")
+	}
+
+	return settings, nil
+}
+
+func (r *costingRepoConnection) Save(settings *entity.CostingSettings) (*entity.CostingSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(costingCollectionName)
+	_, err := collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": costingSettingsID},
+		bson.M{
+			"_id":             costingSettingsID,
+			"hourly_rate":     settings.HourlyRate,
+			"setup_cost":      settings.SetupCost,
+			"quantity_breaks": settings.QuantityBreaks,
+			"updated_at":      settings.UpdatedAt,
+		},
+		options.Replace().SetUpsert(true),
+	)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Costing.Save")
+	}
+
+	return settings, nil
+}
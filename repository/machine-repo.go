@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WilfredDube/fxtract-backend/configuration"
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MachineRepository -
+type MachineRepository interface {
+	// Create a new machine
+	Create(machine *entity.Machine) (*entity.Machine, error)
+
+	// Update a machine's specification
+	Update(machine *entity.Machine) (*entity.Machine, error)
+
+	// Find a machine by its id
+	Find(id string) (*entity.Machine, error)
+
+	// Find all machines
+	FindAll() ([]entity.Machine, error)
+
+	// Delete a machine
+	Delete(id string) (int64, error)
+}
+
+const (
+	machineCollectionName string = "machines"
+)
+
+// machineRepoConnection -
+type machineRepoConnection struct {
+	connection configuration.MongoRepository
+}
+
+// NewMachineRepository -
+func NewMachineRepository(db configuration.MongoRepository) MachineRepository {
+	return &machineRepoConnection{
+		connection: db,
+	}
+}
+
+func machineDocument(machine *entity.Machine) bson.M {
+	return bson.M{
+		"_id":                 machine.ID,
+		"name":                machine.Name,
+		"max_tonnage":         machine.MaxTonnage,
+		"bed_length":          machine.BedLength,
+		"back_gauge_min":      machine.BackGaugeMin,
+		"back_gauge_max":      machine.BackGaugeMax,
+		"tool_stations":       machine.ToolStations,
+		"hourly_rate":         machine.HourlyRate,
+		"bending_force_model": machine.BendingForceModel,
+		"created_at":          machine.CreatedAt,
+	}
+}
+
+func (r *machineRepoConnection) Create(machine *entity.Machine) (*entity.Machine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(machineCollectionName)
+	_, err := collection.InsertOne(ctx, machineDocument(machine))
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.Create")
+	}
+
+	return machine, nil
+}
+
+func (r *machineRepoConnection) Update(machine *entity.Machine) (*entity.Machine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(machineCollectionName)
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": machine.ID}, machineDocument(machine))
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.Update")
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, errors.Wrap(errors.New("Machine not found"), "repository.Machine.Update")
+	}
+
+	return machine, nil
+}
+
+func (r *machineRepoConnection) Find(id string) (*entity.Machine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	machine := &entity.Machine{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(machineCollectionName)
+
+	mid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.Find")
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": mid}).Decode(machine)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.Find")
+	}
+
+	return machine, nil
+}
+
+func (r *machineRepoConnection) FindAll() ([]entity.Machine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	machines := []entity.Machine{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(machineCollectionName)
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.FindAll")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &machines); err != nil {
+		return nil, errors.Wrap(err, "repository.Machine.FindAll")
+	}
+
+	return machines, nil
+}
+
+func (r *machineRepoConnection) Delete(id string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(machineCollectionName)
+
+	mid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.Machine.Delete")
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": mid})
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.Machine.Delete")
+	}
+
+	return result.DeletedCount, nil
+}
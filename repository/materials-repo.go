@@ -52,6 +52,9 @@ func (r *materialRepoConnection) Create(material *entity.Material) (*entity.Mate
 			"name":             material.Name,
 			"tensile_strength": material.TensileStrength,
 			"k_factor":         material.KFactor,
+			"density":          material.Density,
+			"price_per_kg":     material.PricePerKg,
+			"min_radius_ratio": material.MinRadiusRatio,
 			"created_at":       material.CreatedAt,
 		},
 	)
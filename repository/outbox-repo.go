@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WilfredDube/fxtract-backend/configuration"
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OutboxRepository -
+type OutboxRepository interface {
+	// FindDue returns pending records whose next attempt is due, oldest first
+	FindDue(now int64, limit int64) ([]entity.OutboxRecord, error)
+
+	// Update the delivery state of a record
+	Update(record *entity.OutboxRecord) (*entity.OutboxRecord, error)
+}
+
+const (
+	outboxCollectionName string = "outbox"
+)
+
+// outboxRepoConnection -
+type outboxRepoConnection struct {
+	connection configuration.MongoRepository
+}
+
+// NewOutboxRepository -
+func NewOutboxRepository(db configuration.MongoRepository) OutboxRepository {
+	return &outboxRepoConnection{
+		connection: db,
+	}
+}
+
+func (r *outboxRepoConnection) FindDue(now int64, limit int64) ([]entity.OutboxRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	records := []entity.OutboxRecord{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(outboxCollectionName)
+
+	filter := bson.M{
+		"status":          entity.OutboxPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Outbox.FindDue")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, errors.Wrap(err, "repository.Outbox.FindDue")
+	}
+
+	return records, nil
+}
+
+func (r *outboxRepoConnection) Update(record *entity.OutboxRecord) (*entity.OutboxRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(outboxCollectionName)
+
+	filter := bson.M{"_id": record.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          record.Status,
+			"attempts":        record.Attempts,
+			"last_error":      record.LastError,
+			"next_attempt_at": record.NextAttemptAt,
+			"sent_at":         record.SentAt,
+		}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Outbox.Update")
+	}
+
+	return record, nil
+}
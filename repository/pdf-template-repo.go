@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WilfredDube/fxtract-backend/configuration"
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PDFTemplateRepository -
+type PDFTemplateRepository interface {
+	// Create a new template
+	Create(template *entity.PDFTemplate) (*entity.PDFTemplate, error)
+
+	// Update a template's branding and layout, keeping its logo
+	Update(template *entity.PDFTemplate) (*entity.PDFTemplate, error)
+
+	// UpdateLogo replaces a template's logo
+	UpdateLogo(id string, logo string, logoType string) (int64, error)
+
+	// Find a template by its id
+	Find(id string) (*entity.PDFTemplate, error)
+
+	// FindByCustomer finds the template assigned to a user
+	FindByCustomer(userID string) (*entity.PDFTemplate, error)
+
+	// FindDefault finds the template of customers without one
+	FindDefault() (*entity.PDFTemplate, error)
+
+	// Find all templates
+	FindAll() ([]entity.PDFTemplate, error)
+
+	// Delete a template
+	Delete(id string) (int64, error)
+}
+
+const (
+	pdfTemplateCollectionName string = "pdf_templates"
+)
+
+// pdfTemplateRepoConnection -
+type pdfTemplateRepoConnection struct {
+	connection configuration.MongoRepository
+}
+
+// NewPDFTemplateRepository -
+func NewPDFTemplateRepository(db configuration.MongoRepository) PDFTemplateRepository {
+	return &pdfTemplateRepoConnection{
+		connection: db,
+	}
+}
+
+func pdfTemplateDocument(template *entity.PDFTemplate) bson.M {
+	return bson.M{
+		"name":             template.Name,
+		"company_name":     template.CompanyName,
+		"primary_colour":   template.PrimaryColour,
+		"secondary_colour": template.SecondaryColour,
+		"header_text":      template.HeaderText,
+		"footer_text":      template.FooterText,
+		"qr_code_url":      template.QRCodeURL,
+		"fields":           template.Fields,
+		"paper_size":       template.PaperSize,
+		"customer_ids":     template.CustomerIDs,
+		"default":          template.Default,
+		"created_at":       template.CreatedAt,
+		"updated_at":       template.UpdatedAt,
+	}
+}
+
+// claim makes a template the only default, and takes its customers off other
+// templates, so that every customer has one template.
+func (r *pdfTemplateRepoConnection) claim(ctx context.Context, template *entity.PDFTemplate) error {
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	if template.Default {
+		_, err := collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$ne": template.ID}}, bson.M{"$set": bson.M{"default": false}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(template.CustomerIDs) > 0 {
+		_, err := collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$ne": template.ID}},
+			bson.M{"$pull": bson.M{"customer_ids": bson.M{"$in": template.CustomerIDs}}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *pdfTemplateRepoConnection) Create(template *entity.PDFTemplate) (*entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	document := pdfTemplateDocument(template)
+	document["_id"] = template.ID
+
+	_, err := collection.InsertOne(ctx, document)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Create")
+	}
+
+	if err := r.claim(ctx, template); err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Create")
+	}
+
+	return template, nil
+}
+
+func (r *pdfTemplateRepoConnection) Update(template *entity.PDFTemplate) (*entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": template.ID}, bson.M{"$set": pdfTemplateDocument(template)})
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Update")
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, errors.Wrap(errors.New("PDF template not found"), "repository.PDFTemplate.Update")
+	}
+
+	if err := r.claim(ctx, template); err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Update")
+	}
+
+	return template, nil
+}
+
+func (r *pdfTemplateRepoConnection) UpdateLogo(id string, logo string, logoType string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	tid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.PDFTemplate.UpdateLogo")
+	}
+
+	update := bson.M{"$set": bson.M{"logo": logo, "logo_type": logoType}}
+	if logo == "" {
+		update = bson.M{"$unset": bson.M{"logo": "", "logo_type": ""}}
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": tid}, update)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.PDFTemplate.UpdateLogo")
+	}
+
+	return result.MatchedCount, nil
+}
+
+func (r *pdfTemplateRepoConnection) Find(id string) (*entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	template := &entity.PDFTemplate{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	tid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Find")
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": tid}).Decode(template)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.Find")
+	}
+
+	return template, nil
+}
+
+func (r *pdfTemplateRepoConnection) FindByCustomer(userID string) (*entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	template := &entity.PDFTemplate{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.FindByCustomer")
+	}
+
+	err = collection.FindOne(ctx, bson.M{"customer_ids": uid}).Decode(template)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.FindByCustomer")
+	}
+
+	return template, nil
+}
+
+func (r *pdfTemplateRepoConnection) FindDefault() (*entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	template := &entity.PDFTemplate{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	err := collection.FindOne(ctx, bson.M{"default": true}).Decode(template)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.FindDefault")
+	}
+
+	return template, nil
+}
+
+func (r *pdfTemplateRepoConnection) FindAll() ([]entity.PDFTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	templates := []entity.PDFTemplate{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	// Logos are left out of listings; they are only needed to draw PDFs.
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"logo": 0}))
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.FindAll")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, errors.Wrap(err, "repository.PDFTemplate.FindAll")
+	}
+
+	return templates, nil
+}
+
+func (r *pdfTemplateRepoConnection) Delete(id string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(pdfTemplateCollectionName)
+
+	tid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.PDFTemplate.Delete")
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": tid})
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.PDFTemplate.Delete")
+	}
+
+	return result.DeletedCount, nil
+}
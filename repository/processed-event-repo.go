@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/WilfredDube/fxtract-backend/configuration"
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProcessedEventRepository -
+type ProcessedEventRepository interface {
+	// Claim records that an event is being handled. It returns false if the
+	// event was already handled, or is being handled under a claim that was
+	// made after staleBefore.
+	Claim(event *entity.ProcessedEvent, staleBefore int64) (bool, error)
+
+	// Complete marks a claimed event as handled
+	Complete(id string, completedAt int64) error
+
+	// Release drops a claim so that the event can be handled again
+	Release(id string) error
+}
+
+const (
+	processedEventCollectionName string = "processed_events"
+)
+
+// processedEventRepoConnection -
+type processedEventRepoConnection struct {
+	connection configuration.MongoRepository
+}
+
+// NewProcessedEventRepository -
+func NewProcessedEventRepository(db configuration.MongoRepository) ProcessedEventRepository {
+	return &processedEventRepoConnection{
+		connection: db,
+	}
+}
+
+func (r *processedEventRepoConnection) Claim(event *entity.ProcessedEvent, staleBefore int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processedEventCollectionName)
+
+	// The unique _id makes the insert fail for every event seen before.
+	_, err := collection.InsertOne(ctx, event)
+	if err == nil {
+		return true, nil
+	}
+
+	if !isDuplicateKeyError(err) {
+		return false, errors.Wrap(err, "repository.ProcessedEvent.Claim")
+	}
+
+	// Take over claims left behind by a handler that crashed.
+	filter := bson.M{
+		"_id":        event.ID,
+		"status":     entity.EventInProgress,
+		"claimed_at": bson.M{"$lt": staleBefore},
+	}
+	update := bson.M{"$set": bson.M{"claimed_at": event.ClaimedAt}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, errors.Wrap(err, "repository.ProcessedEvent.Claim")
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *processedEventRepoConnection) Complete(id string, completedAt int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processedEventCollectionName)
+
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"status": entity.EventDone, "completed_at": completedAt}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return errors.Wrap(err, "repository.ProcessedEvent.Complete")
+	}
+
+	return nil
+}
+
+func (r *processedEventRepoConnection) Release(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processedEventCollectionName)
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id, "status": entity.EventInProgress})
+	if err != nil {
+		return errors.Wrap(err, "repository.ProcessedEvent.Release")
+	}
+
+	return nil
+}
+
+func isDuplicateKeyError(err error) bool {
+	var writeException mongo.WriteException
+	if !errors.As(err, &writeException) {
+		return false
+	}
+
+	for _, writeError := range writeException.WriteErrors {
+		if writeError.Code == 11000 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"duplicate key", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, true},
+		{"duplicate key among others", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121}, {Code: 11000}}}, true},
+		{"other write error", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121}}}, false},
+		{"write concern error", mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64}}, false},
+		{"no documents", mongo.ErrNoDocuments, false},
+		{"other error", errors.New("connection refused"), false},
+	}
+
+	for _, test := range tests {
+		if got := isDuplicateKeyError(test.err); got != test.want {
+			t.Errorf("%s: isDuplicateKeyError = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // ProcessingPlanRepository -
@@ -16,11 +17,32 @@ type ProcessingPlanRepository interface {
 	// Create a new processingPlan
 	Create(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error)
 
+	// Update a processingPlan that has not been released
 	Update(processingPlan entity.ProcessingPlan) (*entity.ProcessingPlan, error)
 
-	// Find a processingPlan by its id
+	// Save the review of a processingPlan that has not been released, provided
+	// its review has not changed since it was read. Fails with
+	// ErrReviewConflict otherwise.
+	UpdateReview(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error)
+
+	// Find the active processingPlan of a CAD file
 	Find(id string) (*entity.ProcessingPlan, error)
 
+	// Find every version of a CAD file's processingPlan, oldest first
+	FindVersions(cadFileID string) ([]entity.ProcessingPlan, error)
+
+	// Find a version of a CAD file's processingPlan
+	FindVersion(cadFileID string, version int64) (*entity.ProcessingPlan, error)
+
+	// FindByID finds a processingPlan by its own id
+	FindByID(id string) (*entity.ProcessingPlan, error)
+
+	// NextVersion allocates the version number of a CAD file's next processingPlan
+	NextVersion(cadFileID string) (int64, error)
+
+	// Make a version the active processingPlan of its CAD file
+	Activate(cadFileID string, version int64) (int64, error)
+
 	// Find all projects
 	FindAll(processingPlanID string) ([]entity.ProcessingPlan, error)
 
@@ -32,8 +54,13 @@ type ProcessingPlanRepository interface {
 
 const (
 	processingPlanCollectionName string = "processing_plans"
+	planVersionCollectionName    string = "processing_plan_versions"
 )
 
+// ErrReviewConflict is returned by UpdateReview when the plan was reviewed by
+// someone else after it was read, or has been released.
+var ErrReviewConflict = errors.New("processing plan review was modified concurrently")
+
 // userRepoConnection -
 type processingPlanRepoConnection struct {
 	connection configuration.MongoRepository
@@ -74,6 +101,19 @@ func (r *processingPlanRepoConnection) Create(processingPlan *entity.ProcessingP
 			"estimated_manufacturing_time": processingPlan.EstimatedManufacturingTime,
 			"bend_sequences":               processingPlan.BendingSequences,
 			"bend_features":                processingPlan.BendFeatures,
+			"flat_pattern":                 processingPlan.FlatPattern,
+			"cost":                         processingPlan.Cost,
+			"machine_id":                   processingPlan.MachineID,
+			"machine_name":                 processingPlan.MachineName,
+			"capacity_issues":              processingPlan.CapacityIssues,
+			"constraints":                  processingPlan.Constraints,
+			"pdf_template_id":              processingPlan.PDFTemplateID,
+			"version":                      processingPlan.Version,
+			"active":                       processingPlan.Active,
+			"status":                       processingPlan.Status,
+			"reviewers":                    processingPlan.Reviewers,
+			"review_comments":              processingPlan.ReviewComments,
+			"approved_at":                  processingPlan.ApprovedAt,
 			"created_at":                   processingPlan.CreatedAt,
 		},
 	)
@@ -92,7 +132,7 @@ func (r *processingPlanRepoConnection) Update(processingPlan entity.ProcessingPl
 	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
 	_, err := collection.UpdateOne(
 		ctx,
-		bson.M{"_id": processingPlan.ID},
+		bson.M{"_id": processingPlan.ID, "status": bson.M{"$ne": entity.PlanReleased}},
 		bson.D{
 			{"$set", bson.M{
 				"_id":                          processingPlan.ID,
@@ -114,6 +154,19 @@ func (r *processingPlanRepoConnection) Update(processingPlan entity.ProcessingPl
 				"total_tool_distance":          processingPlan.TotalToolDistance,
 				"bend_sequences":               processingPlan.BendingSequences,
 				"bend_features":                processingPlan.BendFeatures,
+				"flat_pattern":                 processingPlan.FlatPattern,
+				"cost":                         processingPlan.Cost,
+				"machine_id":                   processingPlan.MachineID,
+				"machine_name":                 processingPlan.MachineName,
+				"capacity_issues":              processingPlan.CapacityIssues,
+				"constraints":                  processingPlan.Constraints,
+				"pdf_template_id":              processingPlan.PDFTemplateID,
+				"version":                      processingPlan.Version,
+				"active":                       processingPlan.Active,
+				"status":                       processingPlan.Status,
+				"reviewers":                    processingPlan.Reviewers,
+				"review_comments":              processingPlan.ReviewComments,
+				"approved_at":                  processingPlan.ApprovedAt,
 				"created_at":                   processingPlan.CreatedAt,
 			}}},
 	)
@@ -125,6 +178,53 @@ func (r *processingPlanRepoConnection) Update(processingPlan entity.ProcessingPl
 	return &processingPlan, nil
 }
 
+func (r *processingPlanRepoConnection) UpdateReview(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
+
+	// Plans reviewed before revisions were introduced have no revision field.
+	filter := bson.M{
+		"_id":             processingPlan.ID,
+		"status":          bson.M{"$ne": entity.PlanReleased},
+		"review_revision": processingPlan.ReviewRevision,
+	}
+	if processingPlan.ReviewRevision == 0 {
+		filter = bson.M{
+			"_id":    processingPlan.ID,
+			"status": bson.M{"$ne": entity.PlanReleased},
+			"$or":    []bson.M{{"review_revision": 0}, {"review_revision": bson.M{"$exists": false}}},
+		}
+	}
+
+	result, err := collection.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{
+			"status":          processingPlan.Status,
+			"reviewers":       processingPlan.Reviewers,
+			"review_comments": processingPlan.ReviewComments,
+			"review_revision": processingPlan.ReviewRevision + 1,
+			"moderator":       processingPlan.Moderator,
+			"approved_at":     processingPlan.ApprovedAt,
+			"pdf_url":         processingPlan.PdfURL,
+			"pdf_template_id": processingPlan.PDFTemplateID,
+		}},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.UpdateReview")
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, errors.Wrap(ErrReviewConflict, "repository.ProcessingPlan.UpdateReview")
+	}
+
+	processingPlan.ReviewRevision++
+
+	return processingPlan, nil
+}
+
 func (r *processingPlanRepoConnection) Find(id string) (*entity.ProcessingPlan, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
 	defer cancel()
@@ -140,8 +240,11 @@ func (r *processingPlanRepoConnection) Find(id string) (*entity.ProcessingPlan,
 		return nil, errors.Wrap(err, "repository.ProcessingPlan.Find")
 	}
 
+	// Plans stored before versioning are not marked active, so the newest plan
+	// is used when none is.
 	filter := bson.M{"cadfile_id": cid}
-	err = collection.FindOne(ctx, filter).Decode(&processingPlan)
+	opts := options.FindOne().SetSort(bson.D{{Key: "active", Value: -1}, {Key: "version", Value: -1}, {Key: "created_at", Value: -1}})
+	err = collection.FindOne(ctx, filter, opts).Decode(&processingPlan)
 	if err != nil {
 		if err == mongo.ErrNilDocument {
 			return nil, errors.Wrap(errors.New("ProcessingPlan not found"), "repository.ProcessingPlan.Find")
@@ -152,6 +255,171 @@ func (r *processingPlanRepoConnection) Find(id string) (*entity.ProcessingPlan,
 	return processingPlan, nil
 }
 
+func (r *processingPlanRepoConnection) FindVersions(cadFileID string) ([]entity.ProcessingPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	cid, err := primitive.ObjectIDFromHex(cadFileID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindVersions")
+	}
+
+	processingPlans := []entity.ProcessingPlan{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"cadfile_id": cid}, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindVersions")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &processingPlans); err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindVersions")
+	}
+
+	return processingPlans, nil
+}
+
+func (r *processingPlanRepoConnection) FindVersion(cadFileID string, version int64) (*entity.ProcessingPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	cid, err := primitive.ObjectIDFromHex(cadFileID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindVersion")
+	}
+
+	processingPlan := &entity.ProcessingPlan{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
+
+	err = collection.FindOne(ctx, bson.M{"cadfile_id": cid, "version": version}).Decode(processingPlan)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindVersion")
+	}
+
+	return processingPlan, nil
+}
+
+func (r *processingPlanRepoConnection) FindByID(id string) (*entity.ProcessingPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindByID")
+	}
+
+	processingPlan := &entity.ProcessingPlan{}
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
+
+	err = collection.FindOne(ctx, bson.M{"_id": pid}).Decode(processingPlan)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.ProcessingPlan.FindByID")
+	}
+
+	return processingPlan, nil
+}
+
+// NextVersion increments a per CAD file counter, so that plans completing at
+// the same time never get the same version. The counter of a CAD file whose
+// plans were stored before it existed starts from their highest version.
+func (r *processingPlanRepoConnection) NextVersion(cadFileID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	cid, err := primitive.ObjectIDFromHex(cadFileID)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.ProcessingPlan.NextVersion")
+	}
+
+	database := r.connection.Client.Database(r.connection.Database)
+	counters := database.Collection(planVersionCollectionName)
+
+	for {
+		counter := struct {
+			Version int64 `bson:"version"`
+		}{}
+
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = counters.FindOneAndUpdate(ctx, bson.M{"_id": cid}, bson.M{"$inc": bson.M{"version": 1}}, opts).Decode(&counter)
+		if err == nil {
+			return counter.Version, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return 0, errors.Wrap(err, "repository.ProcessingPlan.NextVersion")
+		}
+
+		latest := &entity.ProcessingPlan{}
+		version := int64(1)
+
+		findOpts := options.FindOne().SetSort(bson.M{"version": -1})
+		err = database.Collection(processingPlanCollectionName).FindOne(ctx, bson.M{"cadfile_id": cid}, findOpts).Decode(latest)
+		if err == nil {
+			version = latest.Version + 1
+		} else if err != mongo.ErrNoDocuments {
+			return 0, errors.Wrap(err, "repository.ProcessingPlan.NextVersion")
+		}
+
+		// Another plan may have started the counter meanwhile; increment it
+		// instead.
+		_, err = counters.InsertOne(ctx, bson.M{"_id": cid, "version": version})
+		if err == nil {
+			return version, nil
+		}
+
+		if !isDuplicateKeyError(err) {
+			return 0, errors.Wrap(err, "repository.ProcessingPlan.NextVersion")
+		}
+	}
+}
+
+// Activate marks a version active and every other version of the CAD file
+// inactive in one transaction, so that concurrent activations always leave
+// exactly one version active. Transactions require MongoDB to run as a
+// replica set.
+func (r *processingPlanRepoConnection) Activate(cadFileID string, version int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	cid, err := primitive.ObjectIDFromHex(cadFileID)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.ProcessingPlan.Activate")
+	}
+
+	collection := r.connection.Client.Database(r.connection.Database).Collection(processingPlanCollectionName)
+
+	session, err := r.connection.Client.StartSession()
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.ProcessingPlan.Activate")
+	}
+	defer session.EndSession(ctx)
+
+	matched, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		result, err := collection.UpdateOne(sc, bson.M{"cadfile_id": cid, "version": version}, bson.M{"$set": bson.M{"active": true}})
+		if err != nil {
+			return int64(0), err
+		}
+
+		if result.MatchedCount == 0 {
+			return int64(0), nil
+		}
+
+		_, err = collection.UpdateMany(sc, bson.M{"cadfile_id": cid, "version": bson.M{"$ne": version}}, bson.M{"$set": bson.M{"active": false}})
+		if err != nil {
+			return int64(0), err
+		}
+
+		return result.MatchedCount, nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.ProcessingPlan.Activate")
+	}
+
+	return matched.(int64), nil
+}
+
 func (r *processingPlanRepoConnection) FindAll(processingPlanID string) ([]entity.ProcessingPlan, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
 	defer cancel()
@@ -190,7 +458,7 @@ func (r *processingPlanRepoConnection) Delete(id string) (int64, error) {
 	}
 
 	filter := bson.M{"cadfile_id": cid}
-	cursor, err := collection.DeleteOne(ctx, filter)
+	cursor, err := collection.DeleteMany(ctx, filter)
 	if err != nil {
 		if err == mongo.ErrNilCursor {
 			return 0, errors.Wrap(errors.New("Delete failed"), "repository.ProcessingPlan.Delete")
@@ -55,11 +55,13 @@ func (r *projectRepoConnection) Create(project *entity.Project) (*entity.Project
 	_, err := collection.InsertOne(
 		ctx,
 		bson.M{
-			"_id":         project.ID,
-			"title":       project.Title,
-			"description": project.Description,
-			"owner_id":    project.OwnerID,
-			"created_at":  project.CreatedAt,
+			"_id":                 project.ID,
+			"title":               project.Title,
+			"description":         project.Description,
+			"owner_id":            project.OwnerID,
+			"created_at":          project.CreatedAt,
+			"bending_force_model": project.BendingForceModel,
+			"machine_id":          project.MachineID,
 		},
 	)
 
@@ -80,8 +82,10 @@ func (r *projectRepoConnection) Update(project *entity.Project) (*entity.Project
 		bson.M{"_id": project.ID},
 		bson.D{
 			{"$set", bson.M{
-				"title":       project.Title,
-				"description": project.Description,
+				"title":               project.Title,
+				"description":         project.Description,
+				"bending_force_model": project.BendingForceModel,
+				"machine_id":          project.MachineID,
 			}}},
 	)
 
@@ -9,7 +9,6 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // TaskRepository -
@@ -17,6 +16,11 @@ type TaskRepository interface {
 	// Create a new task
 	Create(task *entity.Task) (*entity.Task, error)
 
+	// CreateWithEvents creates a task and its outbox records atomically
+	CreateWithEvents(task *entity.Task, records []entity.OutboxRecord) (*entity.Task, error)
+
+	// Update a task, provided it has not changed since it was read. Fails with
+	// ErrVersionConflict otherwise.
 	Update(task *entity.Task) (*entity.Task, error)
 
 	// Find a task by its id
@@ -32,6 +36,10 @@ const (
 	taskCollectionName string = "tasks"
 )
 
+// ErrVersionConflict is returned by Update when the task was modified by
+// someone else after it was read.
+var ErrVersionConflict = errors.New("task was modified concurrently")
+
 // userRepoConnection -
 type taskRepoConnection struct {
 	connection configuration.MongoRepository
@@ -49,23 +57,7 @@ func (r *taskRepoConnection) Create(task *entity.Task) (*entity.Task, error) {
 	defer cancel()
 
 	collection := r.connection.Client.Database(r.connection.Database).Collection(taskCollectionName)
-	_, err := collection.InsertOne(
-		ctx,
-		bson.M{
-			"_id":                          task.ID,
-			"task_id":                      task.TaskID,
-			"user_id":                      task.UserID,
-			"description":                  task.Description,
-			"cadfiles":                     task.CADFiles,
-			"processed_cadfiles":           task.ProcessedCADFiles,
-			"status":                       task.Status,
-			"quantity":                     task.Quantity,
-			"processing_time":              task.ProcessingTime,
-			"estimated_manufacturing_time": task.EstimatedManufacturingTime,
-			"total_cost":                   task.TotalCost,
-			"created_at":                   task.CreatedAt,
-		},
-	)
+	_, err := collection.InsertOne(ctx, taskDocument(task))
 
 	if err != nil {
 		return nil, errors.Wrap(err, "repository.Task.Create")
@@ -74,6 +66,44 @@ func (r *taskRepoConnection) Create(task *entity.Task) (*entity.Task, error) {
 	return task, nil
 }
 
+// CreateWithEvents inserts the task and its outbox records in a single
+// transaction, so that a task is never stored without the events that start
+// its processing. Transactions require MongoDB to run as a replica set.
+func (r *taskRepoConnection) CreateWithEvents(task *entity.Task, records []entity.OutboxRecord) (*entity.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
+	defer cancel()
+
+	database := r.connection.Client.Database(r.connection.Database)
+
+	session, err := r.connection.Client.StartSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Task.CreateWithEvents")
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := database.Collection(taskCollectionName).InsertOne(sc, taskDocument(task)); err != nil {
+			return nil, err
+		}
+
+		if len(records) == 0 {
+			return nil, nil
+		}
+
+		documents := make([]interface{}, len(records))
+		for i := range records {
+			documents[i] = records[i]
+		}
+
+		return database.Collection(outboxCollectionName).InsertMany(sc, documents)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Task.CreateWithEvents")
+	}
+
+	return task, nil
+}
+
 // Update -
 func (r *taskRepoConnection) Update(task *entity.Task) (*entity.Task, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.connection.Timeout)
@@ -81,21 +111,35 @@ func (r *taskRepoConnection) Update(task *entity.Task) (*entity.Task, error) {
 
 	collection := r.connection.Client.Database(r.connection.Database).Collection(taskCollectionName)
 
-	filter := bson.D{{"_id", task.ID}}
-	update := bson.D{
-		{"$set", bson.M{
+	// Tasks created before versioning was introduced have no version field.
+	filter := bson.M{"_id": task.ID, "version": task.Version}
+	if task.Version == 0 {
+		filter = bson.M{"_id": task.ID, "$or": []bson.M{{"version": 0}, {"version": bson.M{"$exists": false}}}}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
 			"status":                       task.Status,
 			"processing_time":              task.ProcessingTime,
 			"processed_cadfiles":           task.ProcessedCADFiles,
 			"estimated_manufacturing_time": task.EstimatedManufacturingTime,
-		}}}
-	opts := options.Update().SetUpsert(true)
+			"total_cost":                   task.TotalCost,
+			"progress":                     task.Progress,
+			"report_url":                   task.ReportURL,
+			"version":                      task.Version + 1,
+		}}
 
-	_, err := collection.UpdateOne(ctx, filter, update, opts)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return nil, errors.Wrap(err, "repository.Task.Update")
 	}
 
+	if result.MatchedCount == 0 {
+		return nil, errors.Wrap(ErrVersionConflict, "repository.Task.Update")
+	}
+
+	task.Version++
+
 	return task, nil
 }
 
@@ -176,3 +220,21 @@ func (r *taskRepoConnection) FindAll() ([]entity.Task, error) {
 
 	return *tasks, nil
 }
+
+func taskDocument(task *entity.Task) bson.M {
+	return bson.M{
+		"_id":                          task.ID,
+		"task_id":                      task.TaskID,
+		"user_id":                      task.UserID,
+		"description":                  task.Description,
+		"cadfiles":                     task.CADFiles,
+		"processed_cadfiles":           task.ProcessedCADFiles,
+		"status":                       task.Status,
+		"quantity":                     task.Quantity,
+		"processing_time":              task.ProcessingTime,
+		"estimated_manufacturing_time": task.EstimatedManufacturingTime,
+		"total_cost":                   task.TotalCost,
+		"created_at":                   task.CreatedAt,
+		"version":                      task.Version,
+	}
+}
@@ -51,13 +51,14 @@ func (r *toolRepoConnection) Create(tool *entity.Tool) (*entity.Tool, error) {
 	_, err := collection.InsertOne(
 		ctx,
 		bson.M{
-			"tool_id":    tool.ToolID,
-			"tool_name":  tool.ToolName,
-			"angle":      tool.Angle,
-			"length":     tool.Length,
-			"min_radius": tool.MinRadius,
-			"max_radius": tool.MaxRadius,
-			"created_at": tool.CreatedAt,
+			"tool_id":     tool.ToolID,
+			"tool_name":   tool.ToolName,
+			"angle":       tool.Angle,
+			"length":      tool.Length,
+			"min_radius":  tool.MinRadius,
+			"max_radius":  tool.MaxRadius,
+			"die_opening": tool.DieOpening,
+			"created_at":  tool.CreatedAt,
 		},
 	)
 
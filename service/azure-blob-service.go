@@ -86,19 +86,26 @@ func (a *azureBlobService) UploadFromFile(file *multipart.File, filename string)
 
 func (a *azureBlobService) GetOBj(fileURL string) (string, string, error) {
 	cURL := a.serviceURL.NewContainerURL(cadFileContainer)
-	link, _ := url.Parse(fileURL)
+	link, err := url.Parse(fileURL)
+	if err != nil {
+		return "", "", err
+	}
+
 	urlParts := strings.Split(link.Path, "/")
+	if len(urlParts) < 4 {
+		return "", "", fmt.Errorf("not a CAD file URL: %s", fileURL)
+	}
 
 	bURL := cURL.NewBlockBlobURL(fmt.Sprintf("%s/%s", urlParts[2], urlParts[3]))
 
 	downloadResponse, err := bURL.Download(context.Background(), 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
-
-	// NOTE: automatically retries are performed if the connection fails
-	bodyStream := downloadResponse.Body(azblob.RetryReaderOptions{MaxRetryRequests: 20})
 	if err != nil {
 		return "", "", err
 	}
 
+	// NOTE: automatically retries are performed if the connection fails
+	bodyStream := downloadResponse.Body(azblob.RetryReaderOptions{MaxRetryRequests: 20})
+
 	// read the body into a buffer
 	downloadedData := bytes.Buffer{}
 	_, err = downloadedData.ReadFrom(bodyStream)
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/mesh"
+)
+
+// Size in pixels of a bend step illustration
+const (
+	illustrationWidth  = 480
+	illustrationHeight = 360
+)
+
+// LoadPartMesh downloads and reads the OBJ mesh of a CAD file.
+func LoadPartMesh(blob AzureBlobService, objURL string) (*mesh.Mesh, error) {
+	data, _, err := blob.GetOBj(objURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return mesh.ParseOBJ(strings.NewReader(data))
+}
+
+// BendIllustrations draws the part once for each step of the bending
+// sequence, with the two faces joined by the step's bend highlighted. The
+// images are base64 encoded PNGs, in sequence order.
+func BendIllustrations(part *mesh.Mesh, processingPlan *entity.ProcessingPlan) ([]string, error) {
+	features := bendFeatureMap(processingPlan.BendFeatures)
+
+	illustrations := []string{}
+	for _, sequence := range processingPlan.BendingSequences {
+		highlight := map[int64]bool{}
+		if feature, ok := features[int(sequence.BendID)]; ok {
+			highlight[feature.FirstFaceID] = true
+			highlight[feature.SecondFaceID] = true
+		}
+
+		img := part.Render(illustrationWidth, illustrationHeight, highlight)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+
+		illustrations = append(illustrations, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+
+	return illustrations, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// Constants of the bending force models.
+const (
+	// airBendingFactor is the usual press brake constant for air bending with
+	// a die opening of 6 to 12 times the sheet thickness.
+	airBendingFactor = 1.33
+	// dieOpeningRatio is the die opening, as a multiple of the sheet
+	// thickness, assumed for tools that do not record one.
+	dieOpeningRatio = 8.0
+	// bottomingFactor and coiningFactor are how many times the air bending
+	// force is needed to bottom or coin the bend.
+	bottomingFactor = 5.0
+	coiningFactor   = 10.0
+)
+
+// ErrUnknownBendingForceModel is returned for a model without a calculator.
+var ErrUnknownBendingForceModel = errors.New("unknown bending force model")
+
+// BendingForceCalculator estimates the force needed to form a bend. Lengths
+// are in mm and the material's tensile strength in MPa. The legacy model
+// reports N and the others kN; BendingForceKN converts between them.
+type BendingForceCalculator interface {
+	Model() entity.BendingForceModel
+	Calculate(bend entity.BendFeature, thickness float64, material *entity.Material, tool *entity.Tool) float64
+}
+
+// NewBendingForceCalculator returns the calculator for a model. The empty
+// model selects the legacy calculator.
+func NewBendingForceCalculator(model entity.BendingForceModel) (BendingForceCalculator, error) {
+	switch model {
+	case "", entity.LegacyBending:
+		return &legacyCalculator{}, nil
+	case entity.AirBending:
+		return &airBendingCalculator{}, nil
+	case entity.Bottoming:
+		return &multipliedCalculator{model: entity.Bottoming, factor: bottomingFactor}, nil
+	case entity.Coining:
+		return &multipliedCalculator{model: entity.Coining, factor: coiningFactor}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBendingForceModel, model)
+	}
+}
+
+// ApplyBendingForce sets the force of each bend of a CAD file and its part
+// force, which is that of the hardest bend since bends are formed one at a
+// time. tools holds the tools of the bends by ID.
+func ApplyBendingForce(calculator BendingForceCalculator, cadFile *entity.CADFile, material *entity.Material, tools map[string]*entity.Tool) {
+	partForce := 0.0
+	for i, bend := range cadFile.BendFeatures {
+		force := calculator.Calculate(bend, cadFile.FeatureProps.Thickness, material, tools[bend.ToolID])
+		cadFile.BendFeatures[i].BendingForce = force
+
+		if force > partForce {
+			partForce = force
+		}
+	}
+
+	cadFile.FeatureProps.BendingForce = partForce
+	cadFile.FeatureProps.BendingForceModel = calculator.Model()
+}
+
+// BendingForceKN converts a force calculated with the given model to kN. The
+// legacy model, which plans made before models were selectable used, reports
+// N.
+func BendingForceKN(force float64, model entity.BendingForceModel) float64 {
+	switch model {
+	case "", entity.LegacyBending:
+		return force / 1000
+	default:
+		return force
+	}
+}
+
+// DieOpening returns the width of a tool's V-die, taking it as 8 times the
+// sheet thickness when the tool does not record one.
+func DieOpening(tool *entity.Tool, thickness float64) float64 {
+	if tool != nil && tool.DieOpening > 0 {
+		return tool.DieOpening
+	}
+
+	return dieOpeningRatio * thickness
+}
+
+// legacyCalculator is the formula the API has always used, L * t * K * UTS / 8,
+// in N. It is kept so that existing projects report the same figures.
+type legacyCalculator struct{}
+
+func (*legacyCalculator) Model() entity.BendingForceModel {
+	return entity.LegacyBending
+}
+
+func (*legacyCalculator) Calculate(bend entity.BendFeature, thickness float64, material *entity.Material, tool *entity.Tool) float64 {
+	return (bend.Length * thickness * material.KFactor * material.TensileStrength) / 8
+}
+
+// airBendingCalculator gives the tonnage, in kN, for air bending over the
+// tool's V-die: F = 1.33 * UTS * L * t² / V.
+type airBendingCalculator struct{}
+
+func (*airBendingCalculator) Model() entity.BendingForceModel {
+	return entity.AirBending
+}
+
+func (*airBendingCalculator) Calculate(bend entity.BendFeature, thickness float64, material *entity.Material, tool *entity.Tool) float64 {
+	dieOpening := DieOpening(tool, thickness)
+	if dieOpening == 0 {
+		return 0
+	}
+
+	// MPa * mm² gives N, reported in kN.
+	return airBendingFactor * material.TensileStrength * bend.Length * thickness * thickness / dieOpening / 1000
+}
+
+// multipliedCalculator scales the air bending force for methods that press
+// the sheet into the die, such as bottoming and coining.
+type multipliedCalculator struct {
+	airBendingCalculator
+	model  entity.BendingForceModel
+	factor float64
+}
+
+func (c *multipliedCalculator) Model() entity.BendingForceModel {
+	return c.model
+}
+
+func (c *multipliedCalculator) Calculate(bend entity.BendFeature, thickness float64, material *entity.Material, tool *entity.Tool) float64 {
+	return c.factor * c.airBendingCalculator.Calculate(bend, thickness, material, tool)
+}
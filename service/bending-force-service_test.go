@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+func TestBendingForceCalculators(t *testing.T) {
+	material := &entity.Material{TensileStrength: 400, KFactor: 0.33}
+	bend := entity.BendFeature{Length: 100}
+	wideDie := &entity.Tool{DieOpening: 20}
+
+	tests := []struct {
+		model     entity.BendingForceModel
+		thickness float64
+		tool      *entity.Tool
+		want      float64
+	}{
+		// The legacy formula reports N, the others kN.
+		{"", 2, nil, 3300},
+		{entity.LegacyBending, 2, wideDie, 3300},
+		// Without a die opening the die is taken as 8 * 2 = 16 mm.
+		{entity.AirBending, 2, nil, 13.3},
+		{entity.AirBending, 2, &entity.Tool{}, 13.3},
+		{entity.AirBending, 2, wideDie, 10.64},
+		{entity.AirBending, 0, nil, 0},
+		{entity.Bottoming, 2, nil, 66.5},
+		{entity.Bottoming, 2, wideDie, 53.2},
+		{entity.Coining, 2, nil, 133},
+		{entity.Coining, 2, wideDie, 106.4},
+	}
+
+	for _, test := range tests {
+		calculator, err := NewBendingForceCalculator(test.model)
+		if err != nil {
+			t.Fatalf("NewBendingForceCalculator(%q): %s", test.model, err)
+		}
+
+		got := calculator.Calculate(bend, test.thickness, material, test.tool)
+		if !approxEqual(got, test.want) {
+			t.Errorf("%q with t = %v and tool %+v: force = %v, want %v", test.model, test.thickness, test.tool, got, test.want)
+		}
+	}
+}
+
+func TestNewBendingForceCalculatorModels(t *testing.T) {
+	tests := []struct {
+		model entity.BendingForceModel
+		want  entity.BendingForceModel
+	}{
+		{"", entity.LegacyBending},
+		{entity.LegacyBending, entity.LegacyBending},
+		{entity.AirBending, entity.AirBending},
+		{entity.Bottoming, entity.Bottoming},
+		{entity.Coining, entity.Coining},
+	}
+
+	for _, test := range tests {
+		calculator, err := NewBendingForceCalculator(test.model)
+		if err != nil || calculator.Model() != test.want {
+			t.Errorf("NewBendingForceCalculator(%q) = %v, %v; want a %q calculator", test.model, calculator, err, test.want)
+		}
+	}
+
+	if _, err := NewBendingForceCalculator("hydroforming"); !errors.Is(err, ErrUnknownBendingForceModel) {
+		t.Errorf("NewBendingForceCalculator of an unknown model returned %v, want ErrUnknownBendingForceModel", err)
+	}
+}
+
+func TestBendingForceKN(t *testing.T) {
+	tests := []struct {
+		force float64
+		model entity.BendingForceModel
+		want  float64
+	}{
+		{3300, "", 3.3},
+		{3300, entity.LegacyBending, 3.3},
+		{13.3, entity.AirBending, 13.3},
+		{66.5, entity.Bottoming, 66.5},
+		{133, entity.Coining, 133},
+	}
+
+	for _, test := range tests {
+		if got := BendingForceKN(test.force, test.model); !approxEqual(got, test.want) {
+			t.Errorf("BendingForceKN(%v, %q) = %v, want %v", test.force, test.model, got, test.want)
+		}
+	}
+}
+
+func TestApplyBendingForce(t *testing.T) {
+	material := &entity.Material{TensileStrength: 400, KFactor: 0.33}
+	cadFile := &entity.CADFile{
+		FeatureProps: entity.FeatureProperty{Thickness: 2},
+		BendFeatures: []entity.BendFeature{
+			{BendID: 1, Length: 100, ToolID: "wide"},
+			{BendID: 2, Length: 100},
+			{BendID: 3, Length: 50},
+		},
+	}
+	tools := map[string]*entity.Tool{"wide": {DieOpening: 20}}
+
+	calculator, _ := NewBendingForceCalculator(entity.AirBending)
+	ApplyBendingForce(calculator, cadFile, material, tools)
+
+	want := []float64{10.64, 13.3, 6.65}
+	for i, bend := range cadFile.BendFeatures {
+		if !approxEqual(bend.BendingForce, want[i]) {
+			t.Errorf("bend %d force = %v, want %v", bend.BendID, bend.BendingForce, want[i])
+		}
+	}
+
+	if !approxEqual(cadFile.FeatureProps.BendingForce, 13.3) || cadFile.FeatureProps.BendingForceModel != entity.AirBending {
+		t.Errorf("part force = %v (%q), want the hardest bend's 13.3 (air)", cadFile.FeatureProps.BendingForce, cadFile.FeatureProps.BendingForceModel)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	costingRepo repository.CostingRepository
+)
+
+// CostingService -
+type CostingService interface {
+	Validate(settings *entity.CostingSettings) error
+	Find() (*entity.CostingSettings, error)
+	Save(settings *entity.CostingSettings) (*entity.CostingSettings, error)
+	Estimate(plan *entity.ProcessingPlan, cadFile *entity.CADFile, material *entity.Material, machine *entity.Machine, quantity int64) (entity.CostBreakdown, error)
+}
+
+type costingService struct{}
+
+// NewCostingService -
+func NewCostingService(dbRepository repository.CostingRepository) CostingService {
+	costingRepo = dbRepository
+	return &costingService{}
+}
+
+func (*costingService) Validate(settings *entity.CostingSettings) error {
+	if settings == nil {
+		return errors.New("costing settings are empty")
+	}
+
+	if settings.HourlyRate < 0 || settings.SetupCost < 0 {
+		return errors.New("rates can not be negative")
+	}
+
+	for _, quantityBreak := range settings.QuantityBreaks {
+		if quantityBreak.MinQuantity < 1 || quantityBreak.Discount < 0 || quantityBreak.Discount >= 1 {
+			return errors.New("quantity breaks need a quantity of at least 1 and a discount between 0 and 1")
+		}
+	}
+
+	return nil
+}
+
+// Find returns the costing settings, which are all zero until an admin saves
+// them.
+func (*costingService) Find() (*entity.CostingSettings, error) {
+	settings, err := costingRepo.Find()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &entity.CostingSettings{}, nil
+	}
+
+	return settings, err
+}
+
+func (*costingService) Save(settings *entity.CostingSettings) (*entity.CostingSettings, error) {
+	sort.Slice(settings.QuantityBreaks, func(i, j int) bool {
+		return settings.QuantityBreaks[i].MinQuantity < settings.QuantityBreaks[j].MinQuantity
+	})
+
+	return costingRepo.Save(settings)
+}
+
+// Estimate works out the cost of making quantity parts to a processing plan.
+// Each part costs its blank of material and its time on the press brake, and
+// the batch pays for one tool change per tool used. The discount of the
+// largest quantity break the batch qualifies for comes off the total. Press
+// brake time is charged at the machine's rate when it has one.
+func (s *costingService) Estimate(plan *entity.ProcessingPlan, cadFile *entity.CADFile, material *entity.Material, machine *entity.Machine, quantity int64) (entity.CostBreakdown, error) {
+	settings, err := s.Find()
+	if err != nil {
+		return entity.CostBreakdown{}, err
+	}
+
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	// The blank is in mm and the density in kg/m³.
+	volume := cadFile.FlatPattern.BlankLength * cadFile.FlatPattern.BlankWidth * cadFile.FeatureProps.Thickness * 1e-9
+
+	cost := entity.CostBreakdown{Quantity: quantity}
+	cost.BlankMass = volume * material.Density
+	cost.MaterialCost = cost.BlankMass * material.PricePerKg
+
+	hourlyRate := settings.HourlyRate
+	if machine != nil && machine.HourlyRate > 0 {
+		hourlyRate = machine.HourlyRate
+	}
+
+	cost.MachineCost = plan.EstimatedManufacturingTime / 3600 * hourlyRate
+	cost.SetupCost = float64(plan.Tools) * settings.SetupCost
+
+	total := float64(quantity)*(cost.MaterialCost+cost.MachineCost) + cost.SetupCost
+	cost.Discount = total * quantityDiscount(settings.QuantityBreaks, quantity)
+	cost.TotalCost = roundCost(total - cost.Discount)
+	cost.UnitCost = roundCost(cost.TotalCost / float64(quantity))
+
+	cost.BlankMass = math.Round(cost.BlankMass*1000) / 1000
+	cost.MaterialCost = roundCost(cost.MaterialCost)
+	cost.MachineCost = roundCost(cost.MachineCost)
+	cost.SetupCost = roundCost(cost.SetupCost)
+	cost.Discount = roundCost(cost.Discount)
+
+	return cost, nil
+}
+
+func quantityDiscount(quantityBreaks []entity.QuantityBreak, quantity int64) float64 {
+	discount := 0.0
+	minQuantity := int64(0)
+	for _, quantityBreak := range quantityBreaks {
+		if quantity >= quantityBreak.MinQuantity && quantityBreak.MinQuantity >= minQuantity {
+			discount = quantityBreak.Discount
+			minQuantity = quantityBreak.MinQuantity
+		}
+	}
+
+	return discount
+}
+
+func roundCost(cost float64) float64 {
+	return math.Round(cost*100) / 100
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type costingRepoStub struct {
+	settings *entity.CostingSettings
+}
+
+func (r *costingRepoStub) Find() (*entity.CostingSettings, error) {
+	if r.settings == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return r.settings, nil
+}
+
+func (r *costingRepoStub) Save(settings *entity.CostingSettings) (*entity.CostingSettings, error) {
+	r.settings = settings
+	return settings, nil
+}
+
+func TestQuantityDiscount(t *testing.T) {
+	quantityBreaks := []entity.QuantityBreak{
+		{MinQuantity: 100, Discount: 0.15},
+		{MinQuantity: 10, Discount: 0.05},
+		{MinQuantity: 50, Discount: 0.1},
+	}
+
+	tests := []struct {
+		quantity int64
+		want     float64
+	}{
+		{1, 0},
+		{9, 0},
+		{10, 0.05},
+		{49, 0.05},
+		{50, 0.1},
+		{99, 0.1},
+		{100, 0.15},
+		{5000, 0.15},
+	}
+
+	for _, test := range tests {
+		if got := quantityDiscount(quantityBreaks, test.quantity); got != test.want {
+			t.Errorf("quantityDiscount(%d) = %v, want %v", test.quantity, got, test.want)
+		}
+	}
+
+	if got := quantityDiscount(nil, 100); got != 0 {
+		t.Errorf("quantityDiscount without breaks = %v, want 0", got)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	settings := &entity.CostingSettings{
+		HourlyRate:     100,
+		SetupCost:      5,
+		QuantityBreaks: []entity.QuantityBreak{{MinQuantity: 10, Discount: 0.1}},
+	}
+
+	// A 100 x 50 x 2 mm steel blank weighs 0.0785 kg.
+	cadFile := &entity.CADFile{
+		FeatureProps: entity.FeatureProperty{Thickness: 2},
+		FlatPattern:  entity.FlatPattern{BlankLength: 100, BlankWidth: 50},
+	}
+	material := &entity.Material{Density: 7850, PricePerKg: 2}
+	plan := &entity.ProcessingPlan{EstimatedManufacturingTime: 36, Tools: 2}
+
+	tests := []struct {
+		name     string
+		settings *entity.CostingSettings
+		machine  *entity.Machine
+		quantity int64
+		want     entity.CostBreakdown
+	}{
+		{
+			name:     "single part",
+			settings: settings,
+			quantity: 1,
+			want: entity.CostBreakdown{Quantity: 1, BlankMass: 0.079, MaterialCost: 0.16, MachineCost: 1, SetupCost: 10,
+				UnitCost: 11.16, TotalCost: 11.16},
+		},
+		{
+			name:     "no quantity",
+			settings: settings,
+			quantity: 0,
+			want: entity.CostBreakdown{Quantity: 1, BlankMass: 0.079, MaterialCost: 0.16, MachineCost: 1, SetupCost: 10,
+				UnitCost: 11.16, TotalCost: 11.16},
+		},
+		{
+			name:     "quantity break",
+			settings: settings,
+			quantity: 10,
+			want: entity.CostBreakdown{Quantity: 10, BlankMass: 0.079, MaterialCost: 0.16, MachineCost: 1, SetupCost: 10,
+				Discount: 2.16, UnitCost: 1.94, TotalCost: 19.41},
+		},
+		{
+			name:     "machine rate",
+			settings: settings,
+			machine:  &entity.Machine{HourlyRate: 200},
+			quantity: 1,
+			want: entity.CostBreakdown{Quantity: 1, BlankMass: 0.079, MaterialCost: 0.16, MachineCost: 2, SetupCost: 10,
+				UnitCost: 12.16, TotalCost: 12.16},
+		},
+		{
+			name:     "machine without a rate",
+			settings: settings,
+			machine:  &entity.Machine{},
+			quantity: 1,
+			want: entity.CostBreakdown{Quantity: 1, BlankMass: 0.079, MaterialCost: 0.16, MachineCost: 1, SetupCost: 10,
+				UnitCost: 11.16, TotalCost: 11.16},
+		},
+		{
+			name:     "settings never saved",
+			quantity: 1,
+			want:     entity.CostBreakdown{Quantity: 1, BlankMass: 0.079, MaterialCost: 0.16, UnitCost: 0.16, TotalCost: 0.16},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			costingService := NewCostingService(&costingRepoStub{settings: test.settings})
+
+			got, err := costingService.Estimate(plan, cadFile, material, test.machine, test.quantity)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("Estimate = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// defaultMinRadiusRatio is the smallest inside radius, as a multiple of the
+// sheet thickness, assumed for materials that do not record one.
+const defaultMinRadiusRatio = 1.0
+
+// CheckManufacturability runs the design-for-manufacturability rules on the
+// recognised bends of a CAD file. It must run after the tools have been
+// selected and the flat pattern developed. The rules are:
+//
+//   - the inside radius of a bend is at least the material's minimum. Breaking
+//     the material's own minimum is an error; breaking the default is only a
+//     warning.
+//   - an edge flange is at least half as long as the die opening, so it still
+//     rests on the die shoulder.
+//   - the flat between two bends is at least half as long as the larger of
+//     their die openings, or the die fouls the previous bend.
+//   - some tool in the library can form each bend.
+//
+// Flange rules are skipped for parts whose flange lengths were not reported.
+func CheckManufacturability(cadFile *entity.CADFile, material *entity.Material, tools map[string]*entity.Tool) []entity.DFMFinding {
+	findings := []entity.DFMFinding{}
+	thickness := cadFile.FeatureProps.Thickness
+
+	ratio, severity := material.MinRadiusRatio, entity.DFMError
+	if ratio <= 0 {
+		ratio, severity = defaultMinRadiusRatio, entity.DFMWarning
+	}
+
+	faces := []int64{}
+	bendsByFace := make(map[int64][]entity.BendFeature)
+
+	for _, bend := range cadFile.BendFeatures {
+		if minRadius := ratio * thickness; bend.Radius < minRadius {
+			findings = append(findings, entity.DFMFinding{
+				Rule:     entity.MinBendRadiusRule,
+				Severity: severity,
+				BendID:   bend.BendID,
+				Message:  fmt.Sprintf("inside radius %v is below the %.2f minimum for %s of thickness %v", bend.Radius, minRadius, material.Name, thickness),
+			})
+		}
+
+		if bend.ToolID == "" {
+			findings = append(findings, entity.DFMFinding{
+				Rule:     entity.UnsupportedBendRule,
+				Severity: entity.DFMError,
+				BendID:   bend.BendID,
+				Message:  fmt.Sprintf("no tool in the library can form a %v° bend with radius %v and length %v", bend.Angle, bend.Radius, bend.Length),
+			})
+		}
+
+		for _, face := range []int64{bend.FirstFaceID, bend.SecondFaceID} {
+			if _, ok := bendsByFace[face]; !ok {
+				faces = append(faces, face)
+			}
+			bendsByFace[face] = append(bendsByFace[face], bend)
+		}
+	}
+
+	flanges := make(map[int64]float64)
+	for _, flange := range cadFile.FeatureProps.Flanges {
+		flanges[flange.FaceID] = flange.Length
+	}
+
+	for _, face := range faces {
+		length, ok := flanges[face]
+		if !ok {
+			continue
+		}
+
+		bends := bendsByFace[face]
+		if len(bends) == 1 {
+			minLength := DieOpening(tools[bends[0].ToolID], thickness) / 2
+			if length < minLength {
+				findings = append(findings, entity.DFMFinding{
+					Rule:     entity.MinFlangeLengthRule,
+					Severity: entity.DFMError,
+					BendID:   bends[0].BendID,
+					FaceID:   face,
+					Message:  fmt.Sprintf("flange length %.2f is shorter than the %.2f needed to span the die", length, minLength),
+				})
+			}
+			continue
+		}
+
+		for i := 0; i < len(bends); i++ {
+			for j := i + 1; j < len(bends); j++ {
+				flat := length - bends[i].OutsideSetback - bends[j].OutsideSetback
+				minFlat := math.Max(DieOpening(tools[bends[i].ToolID], thickness), DieOpening(tools[bends[j].ToolID], thickness)) / 2
+				if flat < minFlat {
+					findings = append(findings, entity.DFMFinding{
+						Rule:     entity.BendProximityRule,
+						Severity: entity.DFMWarning,
+						BendID:   bends[i].BendID,
+						FaceID:   face,
+						Message:  fmt.Sprintf("bends %d and %d are %.2f apart, less than the %.2f the die needs", bends[i].BendID, bends[j].BendID, flat, minFlat),
+					})
+				}
+			}
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+func TestCheckManufacturability(t *testing.T) {
+	tools := map[string]*entity.Tool{
+		"V16": {ToolID: "V16"},
+		"V40": {ToolID: "V40", DieOpening: 40},
+	}
+
+	// A channel of thickness 2: flange 1, web 2 and flange 3, joined by
+	// bends 1 and 2 with outside setbacks of 4.
+	channel := func(radius float64, toolID string, flanges ...float64) *entity.CADFile {
+		cadFile := &entity.CADFile{
+			FeatureProps: entity.FeatureProperty{Thickness: 2},
+			BendFeatures: []entity.BendFeature{
+				{BendID: 1, FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: radius, ToolID: toolID, OutsideSetback: 4},
+				{BendID: 2, FirstFaceID: 2, SecondFaceID: 3, Angle: 90, Radius: radius, ToolID: toolID, OutsideSetback: 4},
+			},
+		}
+
+		for i, length := range flanges {
+			cadFile.FeatureProps.Flanges = append(cadFile.FeatureProps.Flanges, entity.Flange{FaceID: int64(i + 1), Length: length})
+		}
+
+		return cadFile
+	}
+
+	steel := &entity.Material{Name: "steel", MinRadiusRatio: 1.5}
+	unrated := &entity.Material{Name: "unrated"}
+
+	tests := []struct {
+		name     string
+		cadFile  *entity.CADFile
+		material *entity.Material
+		want     []entity.DFMFinding
+	}{
+		{
+			name:     "manufacturable",
+			cadFile:  channel(3, "V16", 20, 60, 20),
+			material: steel,
+			want:     []entity.DFMFinding{},
+		},
+		{
+			name:     "radius below the material's minimum",
+			cadFile:  channel(2, "V16", 20, 60, 20),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMError, BendID: 1},
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMError, BendID: 2},
+			},
+		},
+		{
+			name:     "radius below the default minimum",
+			cadFile:  channel(1, "V16", 20, 60, 20),
+			material: unrated,
+			want: []entity.DFMFinding{
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMWarning, BendID: 1},
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMWarning, BendID: 2},
+			},
+		},
+		{
+			name:     "no tool",
+			cadFile:  channel(3, "", 20, 60, 20),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.UnsupportedBendRule, Severity: entity.DFMError, BendID: 1},
+				{Rule: entity.UnsupportedBendRule, Severity: entity.DFMError, BendID: 2},
+			},
+		},
+		{
+			name:     "edge flange shorter than the default die opening",
+			cadFile:  channel(3, "V16", 5, 60, 20),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.MinFlangeLengthRule, Severity: entity.DFMError, BendID: 1, FaceID: 1},
+			},
+		},
+		{
+			name:     "edge flange shorter than the tool's die opening",
+			cadFile:  channel(3, "V40", 15, 60, 25),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.MinFlangeLengthRule, Severity: entity.DFMError, BendID: 1, FaceID: 1},
+			},
+		},
+		{
+			name:     "bends too close",
+			cadFile:  channel(3, "V16", 20, 15, 20),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.BendProximityRule, Severity: entity.DFMWarning, BendID: 1, FaceID: 2},
+			},
+		},
+		{
+			name:     "flanges not reported",
+			cadFile:  channel(2, "V16"),
+			material: steel,
+			want: []entity.DFMFinding{
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMError, BendID: 1},
+				{Rule: entity.MinBendRadiusRule, Severity: entity.DFMError, BendID: 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings := CheckManufacturability(test.cadFile, test.material, tools)
+
+			got := []entity.DFMFinding{}
+			for _, finding := range findings {
+				if finding.Message == "" {
+					t.Errorf("finding %+v has no message", finding)
+				}
+				finding.Message = ""
+				got = append(got, finding)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("findings %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/xlsx"
+)
+
+// Export formats
+const (
+	CSVExport  = "csv"
+	XLSXExport = "xlsx"
+	JSONExport = "json"
+)
+
+// ErrUnknownExportFormat is returned for a format without an exporter.
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// Export is an exported file.
+type Export struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// PlanSummary holds the summary metrics of a processing plan.
+type PlanSummary struct {
+	Rotations                  int64                    `json:"rotations"`
+	Flips                      int64                    `json:"flips"`
+	Tools                      int64                    `json:"tools"`
+	Modules                    int64                    `json:"modules"`
+	Quantity                   int64                    `json:"quantity"`
+	ProcessingTime             float64                  `json:"processing_time"`
+	EstimatedManufacturingTime float64                  `json:"estimated_manufacturing_time"`
+	TotalToolDistance          float64                  `json:"total_tool_distance"`
+	BendingForce               float64                  `json:"bending_force"`
+	BendingForceModel          entity.BendingForceModel `json:"bending_force_model"`
+	TotalCost                  float64                  `json:"total_cost"`
+}
+
+// BendStep is a step of the bending sequence.
+type BendStep struct {
+	Op        int     `json:"op"`
+	BendID    int64   `json:"bend_id"`
+	Angle     float64 `json:"angle"`
+	Length    float64 `json:"length"`
+	Radius    float64 `json:"radius"`
+	Direction string  `json:"direction"`
+	ToolID    string  `json:"tool_id"`
+	Force     float64 `json:"force"`
+}
+
+// ToolAssignment lists the bends formed with a tool.
+type ToolAssignment struct {
+	ToolID  string  `json:"tool_id"`
+	BendIDs []int64 `json:"bend_ids"`
+}
+
+// PlanExport is the machine-readable form of a processing plan.
+type PlanExport struct {
+	CADFileID    string            `json:"cadfile_id"`
+	PartNo       string            `json:"part_no"`
+	FileName     string            `json:"filename"`
+	ProjectTitle string            `json:"project_title"`
+	Material     string            `json:"material"`
+	Engineer     string            `json:"engineer"`
+	Version      int64             `json:"version"`
+	Status       entity.PlanStatus `json:"status"`
+	Summary      PlanSummary       `json:"summary"`
+	BendSequence []BendStep        `json:"bend_sequence"`
+	Tools        []ToolAssignment  `json:"tools"`
+}
+
+// ProjectExport is the machine-readable form of a project's processing plans.
+type ProjectExport struct {
+	ProjectID string       `json:"project_id"`
+	Title     string       `json:"title"`
+	Plans     []PlanExport `json:"plans"`
+}
+
+// ExportService -
+type ExportService interface {
+	ExportPlan(processingPlan *entity.ProcessingPlan, format string) (*Export, error)
+	ExportProject(project *entity.Project, processingPlans []entity.ProcessingPlan, format string) (*Export, error)
+}
+
+type exportService struct {
+	pdfService pdfService
+}
+
+// NewExportService -
+func NewExportService() ExportService {
+	return &exportService{}
+}
+
+// ExportPlan exports the summary, bend sequence and tool assignments of a
+// plan. CSV files hold each table under a row naming it, workbooks hold a
+// sheet per table.
+func (s *exportService) ExportPlan(processingPlan *entity.ProcessingPlan, format string) (*Export, error) {
+	filename := exportFilename(processingPlan.PartNo, strings.TrimSuffix(processingPlan.FileName, filepath.Ext(processingPlan.FileName)))
+
+	switch format {
+	case JSONExport:
+		return jsonExport(filename, s.planExport(processingPlan))
+	case CSVExport:
+		return csvExport(filename, s.planTables(processingPlan))
+	case XLSXExport:
+		workbook := &xlsx.Workbook{}
+		for _, table := range s.planTables(processingPlan) {
+			workbook.AddSheet(table.Name, table.Header, table.Rows)
+		}
+
+		return xlsxExport(filename, workbook)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExportFormat, format)
+	}
+}
+
+// ExportProject exports the plans of a project. CSV files hold one row per
+// part. Workbooks add a sheet with the bend sequence of each part.
+func (s *exportService) ExportProject(project *entity.Project, processingPlans []entity.ProcessingPlan, format string) (*Export, error) {
+	filename := exportFilename(project.Title, project.ID.Hex())
+
+	switch format {
+	case JSONExport:
+		export := ProjectExport{ProjectID: project.ID.Hex(), Title: project.Title, Plans: []PlanExport{}}
+		for i := range processingPlans {
+			export.Plans = append(export.Plans, s.planExport(&processingPlans[i]))
+		}
+
+		return jsonExport(filename, export)
+	case CSVExport:
+		return csvExport(filename, []xlsx.Sheet{partsTable(processingPlans)})
+	case XLSXExport:
+		parts := partsTable(processingPlans)
+
+		workbook := &xlsx.Workbook{}
+		workbook.AddSheet(parts.Name, parts.Header, parts.Rows)
+		for i := range processingPlans {
+			processingPlan := &processingPlans[i]
+			header, rows := s.pdfService.getSmallContent(bendFeatureMap(processingPlan.BendFeatures), processingPlan.BendingSequences)
+			workbook.AddSheet(processingPlan.PartNo+" "+processingPlan.FileName, header, rows)
+		}
+
+		return xlsxExport(filename, workbook)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExportFormat, format)
+	}
+}
+
+func (s *exportService) planExport(processingPlan *entity.ProcessingPlan) PlanExport {
+	export := PlanExport{
+		CADFileID:    processingPlan.CADFileID.Hex(),
+		PartNo:       processingPlan.PartNo,
+		FileName:     processingPlan.FileName,
+		ProjectTitle: processingPlan.ProjectTitle,
+		Material:     processingPlan.Material,
+		Engineer:     processingPlan.Engineer,
+		Version:      processingPlan.Version,
+		Status:       processingPlan.ReviewStatus(),
+		Summary: PlanSummary{
+			Rotations:                  processingPlan.Rotations,
+			Flips:                      processingPlan.Flips,
+			Tools:                      processingPlan.Tools,
+			Modules:                    processingPlan.Modules,
+			Quantity:                   processingPlan.Quantity,
+			ProcessingTime:             processingPlan.ProcessingTime,
+			EstimatedManufacturingTime: processingPlan.EstimatedManufacturingTime,
+			TotalToolDistance:          processingPlan.TotalToolDistance,
+			BendingForce:               processingPlan.BendingForce,
+			BendingForceModel:          processingPlan.BendingForceModel,
+			TotalCost:                  processingPlan.Cost.TotalCost,
+		},
+		BendSequence: []BendStep{},
+		Tools:        toolAssignments(processingPlan),
+	}
+
+	features := bendFeatureMap(processingPlan.BendFeatures)
+	for i, sequence := range processingPlan.BendingSequences {
+		feature := features[int(sequence.BendID)]
+		export.BendSequence = append(export.BendSequence, BendStep{
+			Op:        i + 1,
+			BendID:    feature.BendID,
+			Angle:     feature.Angle,
+			Length:    feature.Length,
+			Radius:    feature.Radius,
+			Direction: bendDirection(feature),
+			ToolID:    feature.ToolID,
+			Force:     feature.BendingForce,
+		})
+	}
+
+	return export
+}
+
+func (s *exportService) planTables(processingPlan *entity.ProcessingPlan) []xlsx.Sheet {
+	summary := xlsx.Sheet{
+		Name:   "Summary",
+		Header: []string{"Metric", "Value"},
+		Rows: [][]string{
+			{"Part no", processingPlan.PartNo},
+			{"Part name", processingPlan.FileName},
+			{"Project name", processingPlan.ProjectTitle},
+			{"Material", processingPlan.Material},
+			{"Engineer", processingPlan.Engineer},
+			{"Plan version", fmt.Sprint(processingPlan.Version)},
+			{"Status", string(processingPlan.ReviewStatus())},
+			{"Number of tools", fmt.Sprint(processingPlan.Tools)},
+			{"Number of rotations", fmt.Sprint(processingPlan.Rotations)},
+			{"Number of flips", fmt.Sprint(processingPlan.Flips)},
+			{"Modules", fmt.Sprint(processingPlan.Modules)},
+			{"Quantity", fmt.Sprint(processingPlan.Quantity)},
+			{"Planning time", fmt.Sprintf("%.3f", processingPlan.ProcessingTime)},
+			{"Estimated production time", fmt.Sprintf("%.1f", processingPlan.EstimatedManufacturingTime)},
+			{"Total tool distance", fmt.Sprintf("%.2f", processingPlan.TotalToolDistance)},
+			{"Bending force", fmt.Sprintf("%.2f", processingPlan.BendingForce)},
+			{"Bending force model", bendingForceModel(processingPlan.BendingForceModel)},
+			{"Total cost", fmt.Sprintf("%.2f", processingPlan.Cost.TotalCost)},
+		},
+	}
+
+	header, rows := s.pdfService.getSmallContent(bendFeatureMap(processingPlan.BendFeatures), processingPlan.BendingSequences)
+	sequence := xlsx.Sheet{Name: "Bend sequence", Header: header, Rows: rows}
+
+	tools := xlsx.Sheet{Name: "Tools", Header: []string{"Tool", "Bends", "Bend IDs"}, Rows: [][]string{}}
+	for _, assignment := range toolAssignments(processingPlan) {
+		bendIDs := make([]string, len(assignment.BendIDs))
+		for i, bendID := range assignment.BendIDs {
+			bendIDs[i] = fmt.Sprint(bendID)
+		}
+
+		tools.Rows = append(tools.Rows, []string{assignment.ToolID, fmt.Sprint(len(bendIDs)), strings.Join(bendIDs, " ")})
+	}
+
+	return []xlsx.Sheet{summary, sequence, tools}
+}
+
+func partsTable(processingPlans []entity.ProcessingPlan) xlsx.Sheet {
+	parts := xlsx.Sheet{
+		Name:   "Parts",
+		Header: []string{"Part no", "Part name", "Material", "Version", "Status", "Bends", "Tools", "Rotations", "Flips", "Estimated production time", "Total cost"},
+		Rows:   [][]string{},
+	}
+
+	for _, processingPlan := range processingPlans {
+		parts.Rows = append(parts.Rows, []string{processingPlan.PartNo, processingPlan.FileName, processingPlan.Material,
+			fmt.Sprint(processingPlan.Version), string(processingPlan.ReviewStatus()), fmt.Sprint(len(processingPlan.BendFeatures)),
+			fmt.Sprint(processingPlan.Tools), fmt.Sprint(processingPlan.Rotations), fmt.Sprint(processingPlan.Flips),
+			fmt.Sprintf("%.1f", processingPlan.EstimatedManufacturingTime), fmt.Sprintf("%.2f", processingPlan.Cost.TotalCost)})
+	}
+
+	return parts
+}
+
+// toolAssignments groups the bends of a plan by tool, in the order the tools
+// are first used.
+func toolAssignments(processingPlan *entity.ProcessingPlan) []ToolAssignment {
+	features := bendFeatureMap(processingPlan.BendFeatures)
+
+	assignments := []ToolAssignment{}
+	index := make(map[string]int)
+	for _, sequence := range processingPlan.BendingSequences {
+		feature, ok := features[int(sequence.BendID)]
+		if !ok {
+			continue
+		}
+
+		i, ok := index[feature.ToolID]
+		if !ok {
+			i = len(assignments)
+			index[feature.ToolID] = i
+			assignments = append(assignments, ToolAssignment{ToolID: feature.ToolID})
+		}
+
+		assignments[i].BendIDs = append(assignments[i].BendIDs, feature.BendID)
+	}
+
+	for _, assignment := range assignments {
+		sort.Slice(assignment.BendIDs, func(i, j int) bool { return assignment.BendIDs[i] < assignment.BendIDs[j] })
+	}
+
+	return assignments
+}
+
+func jsonExport(filename string, data interface{}) (*Export, error) {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{Filename: filename + ".json", ContentType: "application/json", Content: content}, nil
+}
+
+func csvExport(filename string, tables []xlsx.Sheet) (*Export, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	for i, table := range tables {
+		if len(tables) > 1 {
+			if i > 0 {
+				writer.Write([]string{})
+			}
+			writer.Write([]string{table.Name})
+		}
+
+		writer.Write(table.Header)
+		writer.WriteAll(table.Rows)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return &Export{Filename: filename + ".csv", ContentType: "text/csv", Content: buf.Bytes()}, nil
+}
+
+func xlsxExport(filename string, workbook *xlsx.Workbook) (*Export, error) {
+	content, err := workbook.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{Filename: filename + ".xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Content: content}, nil
+}
+
+// exportFilename names an export after its parts, without characters that
+// are unsafe in file names.
+func exportFilename(parts ...string) string {
+	name := strings.Join(parts, "-")
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, name)
+
+	return strings.TrimSuffix(name, ".")
+}
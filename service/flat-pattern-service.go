@@ -0,0 +1,77 @@
+package service
+
+import (
+	"math"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// DevelopFlatPattern works out the bend allowance, bend deduction and outside
+// setback of each bend of a CAD file from its angle A, inside radius R, the
+// sheet thickness T and the material's K-factor K:
+//
+//	BA   = π/180 · A · (R + K·T)
+//	OSSB = tan(A/2) · (R + T)
+//	BD   = 2 · OSSB − BA
+//
+// The developed length is the sum of the flange lengths less the deduction
+// of every bend, which assumes the bends are parallel as they are on parts
+// formed on a press brake. The blank is as long as the developed length and
+// as wide as the longest bend. Both are left at zero when the feature
+// recognition worker did not report the length of every flange.
+func DevelopFlatPattern(cadFile *entity.CADFile, kFactor float64) {
+	thickness := cadFile.FeatureProps.Thickness
+
+	flanges := make(map[int64]float64)
+	for _, flange := range cadFile.FeatureProps.Flanges {
+		flanges[flange.FaceID] = flange.Length
+	}
+
+	pattern := entity.FlatPattern{KFactor: kFactor}
+	faces := make(map[int64]bool)
+	deduction, width := 0.0, 0.0
+	complete := len(flanges) > 0
+
+	for i, bend := range cadFile.BendFeatures {
+		angle := math.Abs(bend.Angle)
+
+		allowance := math.Pi / 180 * angle * (bend.Radius + kFactor*thickness)
+		setback := 0.0
+		// The mold lines of a hem never meet, so it has no setback.
+		if angle < 180 {
+			setback = math.Tan(angle*math.Pi/360) * (bend.Radius + thickness)
+		}
+
+		cadFile.BendFeatures[i].BendAllowance = allowance
+		cadFile.BendFeatures[i].OutsideSetback = setback
+		cadFile.BendFeatures[i].BendDeduction = 2*setback - allowance
+
+		deduction += cadFile.BendFeatures[i].BendDeduction
+		width = math.Max(width, bend.Length)
+
+		for _, face := range []int64{bend.FirstFaceID, bend.SecondFaceID} {
+			if _, ok := flanges[face]; !ok {
+				complete = false
+			}
+			faces[face] = true
+		}
+	}
+
+	// A part without bends is a single flange.
+	if len(cadFile.BendFeatures) == 0 {
+		for face := range flanges {
+			faces[face] = true
+		}
+	}
+
+	if complete {
+		for face := range faces {
+			pattern.DevelopedLength += flanges[face]
+		}
+		pattern.DevelopedLength -= deduction
+		pattern.BlankLength = pattern.DevelopedLength
+		pattern.BlankWidth = width
+	}
+
+	cadFile.FlatPattern = pattern
+}
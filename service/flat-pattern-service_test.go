@@ -0,0 +1,106 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestDevelopFlatPattern(t *testing.T) {
+	// A 90° bend with R = 2 and T = 2 at K = 0.33.
+	allowance := math.Pi / 2 * (2 + 0.33*2)
+	deduction := 2*4 - allowance
+
+	tests := []struct {
+		name    string
+		flanges []entity.Flange
+		bends   []entity.BendFeature
+		want    entity.FlatPattern
+	}{
+		{
+			name:    "bracket",
+			flanges: []entity.Flange{{FaceID: 1, Length: 50}, {FaceID: 2, Length: 30}},
+			bends:   []entity.BendFeature{{FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: 2, Length: 100}},
+			want:    entity.FlatPattern{KFactor: 0.33, DevelopedLength: 80 - deduction, BlankLength: 80 - deduction, BlankWidth: 100},
+		},
+		{
+			name:    "channel",
+			flanges: []entity.Flange{{FaceID: 1, Length: 20}, {FaceID: 2, Length: 60}, {FaceID: 3, Length: 20}},
+			bends: []entity.BendFeature{
+				{FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: 2, Length: 120},
+				{FirstFaceID: 2, SecondFaceID: 3, Angle: -90, Radius: 2, Length: 150},
+			},
+			want: entity.FlatPattern{KFactor: 0.33, DevelopedLength: 100 - 2*deduction, BlankLength: 100 - 2*deduction, BlankWidth: 150},
+		},
+		{
+			name:    "flange length missing",
+			flanges: []entity.Flange{{FaceID: 1, Length: 50}},
+			bends:   []entity.BendFeature{{FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: 2, Length: 100}},
+			want:    entity.FlatPattern{KFactor: 0.33},
+		},
+		{
+			name:  "no flanges reported",
+			bends: []entity.BendFeature{{FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: 2, Length: 100}},
+			want:  entity.FlatPattern{KFactor: 0.33},
+		},
+		{
+			name:    "flat part",
+			flanges: []entity.Flange{{FaceID: 1, Length: 75}},
+			want:    entity.FlatPattern{KFactor: 0.33, DevelopedLength: 75, BlankLength: 75},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cadFile := &entity.CADFile{
+				FeatureProps: entity.FeatureProperty{Thickness: 2, Flanges: test.flanges},
+				BendFeatures: test.bends,
+			}
+
+			DevelopFlatPattern(cadFile, 0.33)
+
+			got := cadFile.FlatPattern
+			if got.KFactor != test.want.KFactor || !approxEqual(got.DevelopedLength, test.want.DevelopedLength) ||
+				!approxEqual(got.BlankLength, test.want.BlankLength) || !approxEqual(got.BlankWidth, test.want.BlankWidth) {
+				t.Errorf("flat pattern %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDevelopFlatPatternBends(t *testing.T) {
+	tests := []struct {
+		name      string
+		angle     float64
+		allowance float64
+		setback   float64
+	}{
+		{"right angle", 90, math.Pi / 2 * 2.66, 4},
+		{"reversed", -90, math.Pi / 2 * 2.66, 4},
+		{"obtuse", 135, math.Pi * 3 / 4 * 2.66, math.Tan(math.Pi*3/8) * 4},
+		{"hem", 180, math.Pi * 2.66, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cadFile := &entity.CADFile{
+				FeatureProps: entity.FeatureProperty{Thickness: 2},
+				BendFeatures: []entity.BendFeature{{Angle: test.angle, Radius: 2}},
+			}
+
+			DevelopFlatPattern(cadFile, 0.33)
+
+			bend := cadFile.BendFeatures[0]
+			if !approxEqual(bend.BendAllowance, test.allowance) || !approxEqual(bend.OutsideSetback, test.setback) ||
+				!approxEqual(bend.BendDeduction, 2*test.setback-test.allowance) {
+				t.Errorf("BA %v, OSSB %v, BD %v, want %v, %v, %v", bend.BendAllowance, bend.OutsideSetback, bend.BendDeduction,
+					test.allowance, test.setback, 2*test.setback-test.allowance)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/repository"
+)
+
+var (
+	machineRepo repository.MachineRepository
+)
+
+// MachineService -
+type MachineService interface {
+	Validate(machine *entity.Machine) error
+	Create(machine *entity.Machine) (*entity.Machine, error)
+	Update(machine *entity.Machine) (*entity.Machine, error)
+	Find(id string) (*entity.Machine, error)
+	FindAll() ([]entity.Machine, error)
+	Delete(id string) (int64, error)
+	CheckCapacity(machine *entity.Machine, cadFile *entity.CADFile, processingPlan *entity.ProcessingPlan) []entity.CapacityIssue
+}
+
+type machineService struct{}
+
+// NewMachineService -
+func NewMachineService(dbRepository repository.MachineRepository) MachineService {
+	machineRepo = dbRepository
+	return &machineService{}
+}
+
+func (*machineService) Validate(machine *entity.Machine) error {
+	if machine == nil {
+		return errors.New("machine is empty")
+	}
+
+	if machine.Name == "" || machine.MaxTonnage <= 0 || machine.BedLength <= 0 || machine.BackGaugeMax <= 0 || machine.ToolStations <= 0 {
+		return errors.New("fill in all the fields")
+	}
+
+	if machine.BackGaugeMin < 0 || machine.BackGaugeMin > machine.BackGaugeMax || machine.HourlyRate < 0 {
+		return errors.New("back gauge range or hourly rate is invalid")
+	}
+
+	if _, err := NewBendingForceCalculator(machine.BendingForceModel); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (*machineService) Create(machine *entity.Machine) (*entity.Machine, error) {
+	return machineRepo.Create(machine)
+}
+
+func (*machineService) Update(machine *entity.Machine) (*entity.Machine, error) {
+	return machineRepo.Update(machine)
+}
+
+func (*machineService) Find(id string) (*entity.Machine, error) {
+	return machineRepo.Find(id)
+}
+
+func (*machineService) FindAll() ([]entity.Machine, error) {
+	return machineRepo.FindAll()
+}
+
+func (*machineService) Delete(id string) (int64, error) {
+	return machineRepo.Delete(id)
+}
+
+// CheckCapacity lists the ways in which a part's processing plan exceeds what
+// the machine can do. A bend can be gauged when either of its flanges is in
+// the back gauge's range; bends of parts without flange lengths are not
+// checked against the back gauge.
+func (*machineService) CheckCapacity(machine *entity.Machine, cadFile *entity.CADFile, processingPlan *entity.ProcessingPlan) []entity.CapacityIssue {
+	issues := []entity.CapacityIssue{}
+
+	if force := BendingForceKN(processingPlan.BendingForce, processingPlan.BendingForceModel); force > machine.MaxTonnage {
+		issues = append(issues, entity.CapacityIssue{
+			Code:    entity.TonnageExceeded,
+			Message: fmt.Sprintf("bending force %.2f kN exceeds the %.2f kN tonnage of %s", force, machine.MaxTonnage, machine.Name),
+		})
+	}
+
+	if processingPlan.Tools > machine.ToolStations {
+		issues = append(issues, entity.CapacityIssue{
+			Code:    entity.ToolStationsExceeded,
+			Message: fmt.Sprintf("%d tools need more than the %d tool stations of %s", processingPlan.Tools, machine.ToolStations, machine.Name),
+		})
+	}
+
+	flanges := make(map[int64]float64)
+	for _, flange := range cadFile.FeatureProps.Flanges {
+		flanges[flange.FaceID] = flange.Length
+	}
+
+	gaugeable := func(face int64) (bool, bool) {
+		length, ok := flanges[face]
+		return ok, length >= machine.BackGaugeMin && length <= machine.BackGaugeMax
+	}
+
+	for _, bend := range cadFile.BendFeatures {
+		if bend.Length > machine.BedLength {
+			issues = append(issues, entity.CapacityIssue{
+				Code:    entity.BedLengthExceeded,
+				BendID:  bend.BendID,
+				Message: fmt.Sprintf("bend length %.2f exceeds the %.2f bed of %s", bend.Length, machine.BedLength, machine.Name),
+			})
+		}
+
+		firstKnown, firstFits := gaugeable(bend.FirstFaceID)
+		secondKnown, secondFits := gaugeable(bend.SecondFaceID)
+		if firstKnown && secondKnown && !firstFits && !secondFits {
+			issues = append(issues, entity.CapacityIssue{
+				Code:    entity.BackGaugeOutOfRange,
+				BendID:  bend.BendID,
+				Message: fmt.Sprintf("neither flange fits the %.2f-%.2f back gauge of %s", machine.BackGaugeMin, machine.BackGaugeMax, machine.Name),
+			})
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+func TestCheckCapacity(t *testing.T) {
+	machine := &entity.Machine{
+		Name:         "PB-100",
+		MaxTonnage:   100,
+		BedLength:    1000,
+		BackGaugeMin: 10,
+		BackGaugeMax: 500,
+		ToolStations: 2,
+	}
+
+	// Faces 1 and 2 can be gauged, faces 3 and 4 are too short and face 5 too
+	// long. Face 6 was not measured.
+	flanges := []entity.Flange{
+		{FaceID: 1, Length: 50},
+		{FaceID: 2, Length: 500},
+		{FaceID: 3, Length: 5},
+		{FaceID: 4, Length: 9.9},
+		{FaceID: 5, Length: 600},
+	}
+
+	type issue struct {
+		code   string
+		bendID int64
+	}
+
+	tests := []struct {
+		name    string
+		plan    entity.ProcessingPlan
+		bends   []entity.BendFeature
+		flanges []entity.Flange
+		want    []issue
+	}{
+		{
+			name:    "within capacity",
+			plan:    entity.ProcessingPlan{BendingForce: 100, BendingForceModel: entity.AirBending, Tools: 2},
+			bends:   []entity.BendFeature{{BendID: 1, Length: 1000, FirstFaceID: 1, SecondFaceID: 3}},
+			flanges: flanges,
+		},
+		{
+			name: "tonnage exceeded",
+			plan: entity.ProcessingPlan{BendingForce: 100.5, BendingForceModel: entity.Coining},
+			want: []issue{{entity.TonnageExceeded, 0}},
+		},
+		{
+			name: "legacy force within tonnage",
+			plan: entity.ProcessingPlan{BendingForce: 99000, BendingForceModel: entity.LegacyBending},
+		},
+		{
+			name: "legacy force exceeds tonnage",
+			plan: entity.ProcessingPlan{BendingForce: 101000},
+			want: []issue{{entity.TonnageExceeded, 0}},
+		},
+		{
+			name: "too many tools",
+			plan: entity.ProcessingPlan{Tools: 3},
+			want: []issue{{entity.ToolStationsExceeded, 0}},
+		},
+		{
+			name:  "bend too long",
+			bends: []entity.BendFeature{{BendID: 1, Length: 1000}, {BendID: 2, Length: 1000.5}},
+			want:  []issue{{entity.BedLengthExceeded, 2}},
+		},
+		{
+			name: "back gauge",
+			bends: []entity.BendFeature{
+				{BendID: 1, FirstFaceID: 3, SecondFaceID: 2},
+				{BendID: 2, FirstFaceID: 3, SecondFaceID: 4},
+				{BendID: 3, FirstFaceID: 4, SecondFaceID: 5},
+				{BendID: 4, FirstFaceID: 3, SecondFaceID: 6},
+			},
+			flanges: flanges,
+			want:    []issue{{entity.BackGaugeOutOfRange, 2}, {entity.BackGaugeOutOfRange, 3}},
+		},
+		{
+			name:  "flanges not reported",
+			bends: []entity.BendFeature{{BendID: 1, FirstFaceID: 3, SecondFaceID: 4}},
+		},
+		{
+			name:    "every check",
+			plan:    entity.ProcessingPlan{BendingForce: 150, BendingForceModel: entity.AirBending, Tools: 4},
+			bends:   []entity.BendFeature{{BendID: 1, Length: 1200, FirstFaceID: 3, SecondFaceID: 5}},
+			flanges: flanges,
+			want: []issue{
+				{entity.TonnageExceeded, 0},
+				{entity.ToolStationsExceeded, 0},
+				{entity.BedLengthExceeded, 1},
+				{entity.BackGaugeOutOfRange, 1},
+			},
+		},
+	}
+
+	machineService := NewMachineService(nil)
+	for _, test := range tests {
+		cadFile := &entity.CADFile{
+			FeatureProps: entity.FeatureProperty{Flanges: test.flanges},
+			BendFeatures: test.bends,
+		}
+
+		got := []issue{}
+		for _, capacityIssue := range machineService.CheckCapacity(machine, cadFile, &test.plan) {
+			if capacityIssue.Message == "" {
+				t.Errorf("%s: issue %s has no message", test.name, capacityIssue.Code)
+			}
+
+			got = append(got, issue{capacityIssue.Code, capacityIssue.BendID})
+		}
+
+		want := test.want
+		if want == nil {
+			want = []issue{}
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: CheckCapacity = %v, want %v", test.name, got, want)
+		}
+	}
+}
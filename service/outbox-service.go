@@ -0,0 +1,188 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/WilfredDube/fxtract-backend/repository"
+	persistence "github.com/WilfredDube/fxtract-backend/repository/reposelect"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+// OutboxRelay publishes the events staged in the outbox collection. Records
+// are retried with exponential backoff until MaxAttempts is reached, after
+// which the record and its task are marked as failed.
+type OutboxRelay struct {
+	PollInterval time.Duration
+	MaxAttempts  int
+	outboxRepo   repository.OutboxRepository
+	taskService  TaskService
+	taskCache    string
+	eventEmitter msgqueue.EventEmitter
+	wake         chan struct{}
+}
+
+// outboxEvent replays a stored payload through an EventEmitter unchanged.
+type outboxEvent struct {
+	name    string
+	payload string
+}
+
+func (e *outboxEvent) EventName() string {
+	return e.name
+}
+
+func (e *outboxEvent) MarshalJSON() ([]byte, error) {
+	return []byte(e.payload), nil
+}
+
+// NewOutboxRecords serializes events into outbox records for the given task.
+func NewOutboxRecords(taskID primitive.ObjectID, events ...msgqueue.Event) ([]entity.OutboxRecord, error) {
+	now := time.Now().Unix()
+
+	records := make([]entity.OutboxRecord, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, entity.OutboxRecord{
+			ID:            primitive.NewObjectID(),
+			TaskID:        taskID,
+			EventName:     event.EventName(),
+			Payload:       string(payload),
+			Status:        entity.OutboxPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	return records, nil
+}
+
+// NewOutboxRelay - taskCache is the cache key that is cleared when a task is
+// marked as failed; it is left alone when empty.
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, taskService TaskService, taskCache string, eventEmitter msgqueue.EventEmitter, pollInterval time.Duration, maxAttempts int) *OutboxRelay {
+	return &OutboxRelay{
+		PollInterval: pollInterval,
+		MaxAttempts:  maxAttempts,
+		outboxRepo:   outboxRepo,
+		taskService:  taskService,
+		taskCache:    taskCache,
+		eventEmitter: eventEmitter,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify asks the relay to publish right away instead of waiting for the next
+// poll. It is called after new records have been committed.
+func (o *OutboxRelay) Notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (o *OutboxRelay) Run() {
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.relay()
+
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+func (o *OutboxRelay) Start() {
+	go o.Run()
+}
+
+// relay publishes every due record, one batch at a time.
+func (o *OutboxRelay) relay() {
+	for {
+		records, err := o.outboxRepo.FindDue(time.Now().Unix(), outboxBatchSize)
+		if err != nil {
+			log.Printf("failed to read the outbox: %s", err)
+			return
+		}
+
+		for i := range records {
+			o.publish(&records[i])
+		}
+
+		if len(records) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (o *OutboxRelay) publish(record *entity.OutboxRecord) {
+	record.Attempts++
+
+	err := o.eventEmitter.Emit(&outboxEvent{name: record.EventName, payload: record.Payload})
+	if err == nil {
+		record.Status = entity.OutboxSent
+		record.LastError = ""
+		record.SentAt = time.Now().Unix()
+	} else {
+		log.Printf("failed to publish %s for task %s (attempt %d): %s", record.EventName, record.TaskID.Hex(), record.Attempts, err)
+
+		record.LastError = err.Error()
+		record.NextAttemptAt = time.Now().Add(o.backoff(record.Attempts)).Unix()
+		if record.Attempts >= o.MaxAttempts {
+			record.Status = entity.OutboxFailed
+		}
+	}
+
+	if _, err := o.outboxRepo.Update(record); err != nil {
+		// The record stays pending and is published again, which consumers
+		// have to tolerate anyway.
+		log.Printf("failed to update outbox record %s: %s", record.ID.Hex(), err)
+		return
+	}
+
+	if record.Status == entity.OutboxFailed {
+		o.failTask(record)
+	}
+}
+
+func (o *OutboxRelay) failTask(record *entity.OutboxRecord) {
+	_, err := o.taskService.Mutate(record.TaskID.Hex(), func(task *entity.Task) error {
+		task.Status = entity.Failed
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to mark task %s as failed: %s", record.TaskID.Hex(), err)
+		return
+	}
+
+	if o.taskCache != "" {
+		go persistence.ClearCache(o.taskCache)
+	}
+}
+
+func (o *OutboxRelay) backoff(attempt int) time.Duration {
+	delay := o.PollInterval
+	for i := 1; i < attempt && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/contracts"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue"
+	"github.com/WilfredDube/fxtract-backend/lib/msgqueue/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type outboxRepoStub struct {
+	records map[primitive.ObjectID]entity.OutboxRecord
+}
+
+func newOutboxRepoStub(records ...entity.OutboxRecord) *outboxRepoStub {
+	r := &outboxRepoStub{records: map[primitive.ObjectID]entity.OutboxRecord{}}
+	for _, record := range records {
+		r.records[record.ID] = record
+	}
+
+	return r
+}
+
+func (r *outboxRepoStub) FindDue(now int64, limit int64) ([]entity.OutboxRecord, error) {
+	records := []entity.OutboxRecord{}
+	for _, record := range r.records {
+		if record.Status == entity.OutboxPending && record.NextAttemptAt <= now {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt < records[j].CreatedAt })
+	if int64(len(records)) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+func (r *outboxRepoStub) Update(record *entity.OutboxRecord) (*entity.OutboxRecord, error) {
+	r.records[record.ID] = *record
+	return record, nil
+}
+
+type taskServiceStub struct {
+	TaskService
+	tasks map[string]*entity.Task
+}
+
+func (s *taskServiceStub) Mutate(id string, mutate func(task *entity.Task) error) (*entity.Task, error) {
+	task, ok := s.tasks[id]
+	if !ok {
+		task = &entity.Task{}
+		s.tasks[id] = task
+	}
+
+	return task, mutate(task)
+}
+
+type emitterStub struct {
+	emitted []msgqueue.Event
+}
+
+func (e *emitterStub) Emit(event msgqueue.Event) error {
+	e.emitted = append(e.emitted, event)
+	return nil
+}
+
+func TestOutboxRelayPublishes(t *testing.T) {
+	broker := memory.NewBroker()
+	listener, err := memory.NewMemoryEventListener(broker, "test", msgqueue.DefaultRetryPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, _, err := listener.Listen("processPlanningStarted")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emitter, _ := memory.NewMemoryEventEmitter(broker)
+
+	taskID := primitive.NewObjectID()
+	records, err := NewOutboxRecords(taskID, &contracts.ProcessPlanningStarted{TaskID: taskID.Hex(), BendCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outboxRepo := newOutboxRepoStub(records...)
+	relay := NewOutboxRelay(outboxRepo, &taskServiceStub{tasks: map[string]*entity.Task{}}, "", emitter, time.Second, 3)
+	relay.relay()
+
+	select {
+	case delivery := <-deliveries:
+		event, ok := delivery.Event().(*contracts.ProcessPlanningStarted)
+		if !ok || event.TaskID != taskID.Hex() || event.BendCount != 3 {
+			t.Errorf("received %+v, want the staged event", delivery.Event())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("staged event was not published")
+	}
+
+	record := outboxRepo.records[records[0].ID]
+	if record.Status != entity.OutboxSent || record.Attempts != 1 || record.SentAt == 0 || record.LastError != "" {
+		t.Errorf("record after publishing is %+v, want it sent on the first attempt", record)
+	}
+}
+
+func TestOutboxRelayRetriesThenFailsTask(t *testing.T) {
+	const maxAttempts = 3
+
+	taskID := primitive.NewObjectID()
+	records, _ := NewOutboxRecords(taskID, &contracts.ProcessPlanningStarted{TaskID: taskID.Hex()})
+
+	outboxRepo := newOutboxRepoStub(records...)
+	taskService := &taskServiceStub{tasks: map[string]*entity.Task{}}
+	emitter, _ := memory.NewMemoryEventEmitter(memory.NewBroker())
+	relay := NewOutboxRelay(outboxRepo, taskService, "", emitter, time.Second, maxAttempts)
+
+	record := records[0]
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		relay.publish(&record)
+
+		stored := outboxRepo.records[record.ID]
+		if stored.Attempts != attempt || stored.LastError == "" {
+			t.Fatalf("record after attempt %d is %+v", attempt, stored)
+		}
+
+		wantStatus := entity.OutboxPending
+		if attempt == maxAttempts {
+			wantStatus = entity.OutboxFailed
+		}
+
+		if stored.Status != wantStatus {
+			t.Errorf("status after attempt %d = %s, want %s", attempt, stored.Status, wantStatus)
+		}
+
+		if stored.NextAttemptAt <= time.Now().Unix() {
+			t.Errorf("next attempt after attempt %d is not in the future", attempt)
+		}
+
+		task, failed := taskService.tasks[taskID.Hex()]
+		if failed != (attempt == maxAttempts) || (failed && task.Status != entity.Failed) {
+			t.Errorf("task after attempt %d is %+v, want it failed only after the last attempt", attempt, task)
+		}
+	}
+
+	if due, _ := outboxRepo.FindDue(time.Now().Add(time.Hour).Unix(), outboxBatchSize); len(due) != 0 {
+		t.Errorf("%d failed records are still due", len(due))
+	}
+}
+
+func TestOutboxRelayDrainsEveryBatch(t *testing.T) {
+	taskID := primitive.NewObjectID()
+
+	events := make([]msgqueue.Event, outboxBatchSize+outboxBatchSize/2)
+	for i := range events {
+		events[i] = &contracts.ProcessPlanningStarted{TaskID: taskID.Hex(), BendCount: int64(i)}
+	}
+
+	records, _ := NewOutboxRecords(taskID, events...)
+	outboxRepo := newOutboxRepoStub(records...)
+	emitter := &emitterStub{}
+	relay := NewOutboxRelay(outboxRepo, &taskServiceStub{tasks: map[string]*entity.Task{}}, "", emitter, time.Second, 3)
+
+	relay.relay()
+
+	if len(emitter.emitted) != len(events) {
+		t.Errorf("relay published %d events, want %d", len(emitter.emitted), len(events))
+	}
+
+	if due, _ := outboxRepo.FindDue(time.Now().Unix(), outboxBatchSize); len(due) != 0 {
+		t.Errorf("%d records are still pending after relaying", len(due))
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, "", nil, time.Second, 10)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{8, 128 * time.Second},
+		{9, 256 * time.Second},
+		{10, outboxMaxBackoff},
+		{50, outboxMaxBackoff},
+	}
+
+	for _, test := range tests {
+		if got := relay.backoff(test.attempt); got != test.want {
+			t.Errorf("backoff(%d) = %v, want %v", test.attempt, got, test.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/lib/mesh"
 	"github.com/johnfercher/maroto/pkg/color"
 	"github.com/johnfercher/maroto/pkg/consts"
 	"github.com/johnfercher/maroto/pkg/pdf"
@@ -20,8 +21,12 @@ const (
 )
 
 type PDFService interface {
-	GeneratePDF(processingPlan *entity.ProcessingPlan) (bytes.Buffer, error)
+	GeneratePDF(processingPlan *entity.ProcessingPlan, part *mesh.Mesh, template *entity.PDFTemplate) (bytes.Buffer, error)
+	GenerateProjectReport(project *entity.Project, processingPlans []entity.ProcessingPlan, template *entity.PDFTemplate) (bytes.Buffer, error)
 	getSmallContent(configMap map[int]entity.BendFeature, bendingSequence []entity.BendingSequence) ([]string, [][]string)
+	getFlatPatternContent(bendFeatures []entity.BendFeature) ([]string, [][]string)
+	getCostContent(cost entity.CostBreakdown) ([]string, [][]string)
+	getReportSummaryContent(processingPlans []entity.ProcessingPlan) ([]string, [][]string)
 }
 
 type pdfService struct{}
@@ -39,13 +44,26 @@ func NewPDFService() PDFService {
 	return &pdfService{}
 }
 
-func (p *pdfService) GeneratePDF(processingPlan *entity.ProcessingPlan) (bytes.Buffer, error) {
-	confMap := map[int]entity.BendFeature{}
-	for _, v := range processingPlan.BendFeatures {
-		confMap[int(v.BendID)] = v
+// GeneratePDF draws the processing plan of a part with a template's branding,
+// or the Fxtract branding when the template is nil. When the part's mesh is
+// given, each step of the bending sequence is illustrated.
+func (p *pdfService) GeneratePDF(processingPlan *entity.ProcessingPlan, part *mesh.Mesh, template *entity.PDFTemplate) (bytes.Buffer, error) {
+	if template == nil {
+		template = DefaultPDFTemplate()
 	}
 
-	m := pdf.NewMaroto(consts.Portrait, consts.A4)
+	confMap := bendFeatureMap(processingPlan.BendFeatures)
+
+	illustrations := []string{}
+	if part != nil {
+		var err error
+		illustrations, err = BendIllustrations(part, processingPlan)
+		if err != nil {
+			return *bytes.NewBuffer([]byte{}), fmt.Errorf("could not illustrate bending sequence: %v", err)
+		}
+	}
+
+	m := pdf.NewMaroto(consts.Portrait, pageSize(template))
 	m.SetPageMargins(10, 15, 10)
 
 	headerSmall, smallContent := p.getSmallContent(confMap, processingPlan.BendingSequences)
@@ -53,201 +71,273 @@ func (p *pdfService) GeneratePDF(processingPlan *entity.ProcessingPlan) (bytes.B
 	m.SetAliasNbPages("{nb}")
 	m.SetFirstPageNb(1)
 
+	primary := templateColour(template.PrimaryColour)
+
+	// The template's fields are printed two to a row, in its order.
+	fields := [][2]string{}
+	for _, field := range template.Fields {
+		if label, value, ok := planField(field, processingPlan); ok {
+			fields = append(fields, [2]string{label, value})
+		}
+	}
+
 	m.RegisterHeader(func() {
 		m.Row(25, func() {
 			m.Col(7, func() {
-				m.Text("Fxtract", props.Text{
-					Size:  24,
-					Top:   8,
-					Style: consts.Bold,
-					Color: color.Color{
-						Red:   3,
-						Green: 166,
-						Blue:  166,
-					},
-				})
+				templateBrand(m, template, 24)
 			})
 
 			m.ColSpace(2)
 
-			m.Col(3, func() {
-				m.QrCode("https://fxtract.com/projects/"+processingPlan.CADFileID.Hex()+"/"+processingPlan.CADFileID.Hex(), props.Rect{
-					Center:  false,
-					Percent: 95,
-					Left:    23,
+			if template.QRCodeURL != "" {
+				m.Col(3, func() {
+					m.QrCode(qrCodeURL(template, processingPlan), props.Rect{
+						Center:  false,
+						Percent: 95,
+						Left:    23,
+					})
 				})
-			})
+			}
 		})
 
-		m.Line(0.2)
-
-		m.Row(12, func() {
-			m.Col(12, func() {
-				m.Text(fmt.Sprintf("Engineer:%43v.", processingPlan.Engineer), props.Text{
-					Top:   6,
-					Style: consts.Bold,
+		if template.HeaderText != "" {
+			m.Row(8, func() {
+				m.Col(12, func() {
+					m.Text(template.HeaderText, props.Text{
+						Top:   2,
+						Style: consts.Italic,
+						Size:  9,
+					})
 				})
 			})
-		})
+		}
 
-		layout := "02  October 2006"
-		t := time.Unix(processingPlan.CreatedAt, 0)
+		m.Line(0.2)
 
-		m.Row(10, func() {
-			m.Col(12, func() {
-				m.Text(fmt.Sprintf("Date:%54v.", t.Format(layout)), props.Text{
-					Top:   2,
-					Style: consts.Bold,
-				})
+		for start := 0; start < len(fields); start += 2 {
+			end := start + 2
+			if end > len(fields) {
+				end = len(fields)
+			}
+
+			m.Row(8, func() {
+				for _, field := range fields[start:end] {
+					text := field[0] + ": " + field[1]
+					m.Col(6, func() {
+						m.Text(text, props.Text{
+							Top:   2,
+							Style: consts.Bold,
+						})
+					})
+				}
 			})
-		})
+		}
 
 		m.Line(0.2)
+	})
 
-		m.Row(10, func() {
-			m.Col(7, func() {
-				m.Text(fmt.Sprintf("Project name:%36s", processingPlan.ProjectTitle), props.Text{
-					Top:   3,
-					Style: consts.Bold,
+	m.RegisterFooter(func() {
+		m.Row(40, func() {
+			m.Col(6, func() {
+				m.Signature("Checked by (Name)", props.Font{
+					// Family: consts.Courier,
+					Style: consts.BoldItalic,
+					Size:  8,
 				})
 			})
 
-			m.Col(5, func() {
-				m.Text(fmt.Sprintf("Modules:%30d", processingPlan.Modules), props.Text{
-					Top:   3,
-					Style: consts.Bold,
+			m.Col(6, func() {
+				m.Signature("Signature", props.Font{
+					// Family: consts.Courier,
+					Style: consts.BoldItalic,
+					Size:  8,
 				})
 			})
 		})
+		templateFooter(m, template)
+	})
 
-		m.Row(10, func() {
-			m.Col(7, func() {
-				m.Text(fmt.Sprintf("Part name:%40s", processingPlan.FileName), props.Text{
-					Top:   2,
-					Style: consts.Bold,
-				})
+	m.Row(20, func() {
+		m.Col(12, func() {
+			m.Text("Bending sequence", props.Text{
+				Top:   8,
+				Style: consts.Bold,
+				Align: consts.Center,
+				Size:  12,
+				Color: primary,
 			})
+		})
+	})
+
+	m.Line(0.2)
+	m.Row(10, func() {})
+	m.TableList(headerSmall, smallContent, props.TableList{
+		ContentProp: props.TableListContent{
+			GridSizes: []uint{1, 1, 2, 1, 1, 2, 2, 2},
+		},
+		HeaderProp: props.TableListContent{
+			GridSizes: []uint{1, 1, 2, 1, 1, 2, 2, 2},
+		},
+		Align:                consts.Center,
+		AlternatedBackground: alternatedBackground(template),
+	})
 
-			m.Col(5, func() {
-				m.Text(fmt.Sprintf("Part no:%40s", processingPlan.PartNo), props.Text{
-					Top:   2,
+	if len(illustrations) > 0 {
+		m.Row(20, func() {
+			m.Col(12, func() {
+				m.Text("Bending sequence illustrations", props.Text{
+					Top:   8,
 					Style: consts.Bold,
+					Align: consts.Center,
+					Size:  12,
+					Color: primary,
 				})
 			})
 		})
 
-		m.Row(10, func() {
-			m.Col(7, func() {
-				m.Text(fmt.Sprintf("Material:%42s", processingPlan.Material), props.Text{
-					Top:   2,
-					Style: consts.Bold,
-				})
+		m.Line(0.2)
+		// Three steps to a row, each captioned with its operation and bend.
+		for start := 0; start < len(illustrations); start += 3 {
+			end := start + 3
+			if end > len(illustrations) {
+				end = len(illustrations)
+			}
+
+			m.Row(50, func() {
+				for i := start; i < end; i++ {
+					illustration := illustrations[i]
+					m.Col(4, func() {
+						m.Base64Image(illustration, consts.Png, props.Rect{
+							Center:  true,
+							Percent: 95,
+						})
+					})
+				}
+			})
+
+			m.Row(8, func() {
+				for i := start; i < end; i++ {
+					caption := fmt.Sprintf("Op %d: bend %d", i+1, processingPlan.BendingSequences[i].BendID)
+					m.Col(4, func() {
+						m.Text(caption, props.Text{
+							Top:   1,
+							Size:  9,
+							Align: consts.Center,
+						})
+					})
+				}
 			})
+		}
+	}
 
-			m.Col(5, func() {
-				m.Text(fmt.Sprintf("Bending force:%28.2f", processingPlan.BendingForce), props.Text{
-					Top:   2,
+	if len(processingPlan.CapacityIssues) > 0 {
+		m.Row(20, func() {
+			m.Col(12, func() {
+				m.Text("Machine capacity warnings", props.Text{
+					Top:   8,
 					Style: consts.Bold,
+					Align: consts.Center,
+					Size:  12,
+					Color: color.Color{
+						Red: 200,
+					},
 				})
 			})
 		})
 
 		m.Line(0.2)
-		m.Row(10, func() {
-			m.Row(10, func() {
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Number of tools:%20d", processingPlan.Tools), props.Text{
-						Top:   3,
-						Style: consts.Bold,
-					})
-				})
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Number of rotations:%14d", processingPlan.Rotations), props.Text{
-						Top:   3,
-						Style: consts.Bold,
-					})
-				})
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Number of flips:%27d", processingPlan.Flips), props.Text{
-						Top:   3,
-						Style: consts.Bold,
+		for _, issue := range processingPlan.CapacityIssues {
+			message := issue.Message
+			if issue.BendID != 0 {
+				message = fmt.Sprintf("Bend %d: %s", issue.BendID, issue.Message)
+			}
+
+			m.Row(6, func() {
+				m.Col(12, func() {
+					m.Text(message, props.Text{
+						Top:  1,
+						Size: 9,
 					})
 				})
 			})
-			m.Row(10, func() {
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Quantity:%32d", processingPlan.Quantity), props.Text{
-						Top:   3,
-						Style: consts.Bold,
-					})
-				})
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Planning time:%25.3f", processingPlan.ProcessingTime), props.Text{
-						Top:   3,
-						Style: consts.Bold,
-					})
-				})
-				m.Col(4, func() {
-					m.Text(fmt.Sprintf("Estimated production time:%10.1f", processingPlan.EstimatedManufacturingTime), props.Text{
-						Top:   3,
-						Style: consts.Bold,
-					})
-				})
+		}
+	}
+
+	m.Row(20, func() {
+		m.Col(12, func() {
+			m.Text("Flat pattern", props.Text{
+				Top:   8,
+				Style: consts.Bold,
+				Align: consts.Center,
+				Size:  12,
+				Color: primary,
 			})
-			m.Line(0.2)
 		})
 	})
 
-	m.RegisterFooter(func() {
-		m.Row(40, func() {
-			m.Col(6, func() {
-				m.Signature("Checked by (Name)", props.Font{
-					// Family: consts.Courier,
-					Style: consts.BoldItalic,
-					Size:  8,
-				})
+	m.Line(0.2)
+	m.Row(10, func() {
+		m.Col(4, func() {
+			m.Text(fmt.Sprintf("K-factor:%26.2f", processingPlan.FlatPattern.KFactor), props.Text{
+				Top:   3,
+				Style: consts.Bold,
 			})
-
-			m.Col(6, func() {
-				m.Signature("Signature", props.Font{
-					// Family: consts.Courier,
-					Style: consts.BoldItalic,
-					Size:  8,
-				})
+		})
+		m.Col(4, func() {
+			m.Text(fmt.Sprintf("Developed length:%16s", flatPatternLength(processingPlan.FlatPattern.DevelopedLength)), props.Text{
+				Top:   3,
+				Style: consts.Bold,
 			})
 		})
-		m.Row(10, func() {
-			m.Col(12, func() {
-				m.Text(strconv.Itoa(m.GetCurrentPage())+"/{nb}", props.Text{
-					Style: consts.BoldItalic,
-					Align: consts.Right,
-					Size:  9,
-				})
+		m.Col(4, func() {
+			blankSize := flatPatternLength(processingPlan.FlatPattern.BlankLength)
+			if processingPlan.FlatPattern.BlankLength > 0 {
+				blankSize += fmt.Sprintf(" x %.2f", processingPlan.FlatPattern.BlankWidth)
+			}
+			m.Text(fmt.Sprintf("Blank size:%22s", blankSize), props.Text{
+				Top:   3,
+				Style: consts.Bold,
 			})
 		})
 	})
 
+	headerFlat, flatContent := p.getFlatPatternContent(processingPlan.BendFeatures)
+	headerCost, costContent := p.getCostContent(processingPlan.Cost)
+	m.Row(10, func() {})
+	m.TableList(headerFlat, flatContent, props.TableList{
+		ContentProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 2, 2, 2},
+		},
+		HeaderProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 2, 2, 2},
+		},
+		Align:                consts.Center,
+		AlternatedBackground: alternatedBackground(template),
+	})
+
 	m.Row(20, func() {
 		m.Col(12, func() {
-			m.Text("Bending sequence", props.Text{
+			m.Text("Cost estimate", props.Text{
 				Top:   8,
 				Style: consts.Bold,
 				Align: consts.Center,
 				Size:  12,
+				Color: primary,
 			})
 		})
 	})
 
 	m.Line(0.2)
 	m.Row(10, func() {})
-	m.TableList(headerSmall, smallContent, props.TableList{
+	m.TableList(headerCost, costContent, props.TableList{
 		ContentProp: props.TableListContent{
-			GridSizes: []uint{1, 1, 2, 2, 2, 2, 2},
+			GridSizes: []uint{6, 3, 3},
 		},
 		HeaderProp: props.TableListContent{
-			GridSizes: []uint{1, 1, 2, 2, 2, 2, 2},
+			GridSizes: []uint{6, 3, 3},
 		},
-		Align: consts.Center,
+		Align:                consts.Center,
+		AlternatedBackground: alternatedBackground(template),
 	})
 
 	m.Row(10, func() {})
@@ -276,21 +366,194 @@ func (p *pdfService) GeneratePDF(processingPlan *entity.ProcessingPlan) (bytes.B
 	return ret, nil
 }
 func (p *pdfService) getSmallContent(configMap map[int]entity.BendFeature, bendingSequence []entity.BendingSequence) ([]string, [][]string) {
-	header := []string{"Op", "Bend ID", "Bend Angle", "Length", "Radius", "Direction", "Tool"}
+	header := []string{"Op", "Bend ID", "Bend Angle", "Length", "Radius", "Direction", "Tool", "Force"}
 
 	contents := [][]string{}
-	var direction string
 	for i, sequence := range bendingSequence {
 		feature := configMap[int(sequence.BendID)]
-		if feature.Direction == 1 {
-			direction = "Inside"
-		} else {
-			direction = "Outside"
-		}
 
 		contents = append(contents, []string{fmt.Sprint(i + 1), fmt.Sprint(feature.BendID), fmt.Sprint(feature.Angle),
-			fmt.Sprint(feature.Length), fmt.Sprint(feature.Radius), direction, fmt.Sprint(feature.ToolID)})
+			fmt.Sprint(feature.Length), fmt.Sprint(feature.Radius), bendDirection(feature), fmt.Sprint(feature.ToolID), fmt.Sprintf("%.2f", feature.BendingForce)})
+	}
+
+	return header, contents
+}
+
+func (p *pdfService) getFlatPatternContent(bendFeatures []entity.BendFeature) ([]string, [][]string) {
+	header := []string{"Bend ID", "Bend Angle", "Radius", "Setback", "Allowance", "Deduction"}
+
+	contents := [][]string{}
+	for _, feature := range bendFeatures {
+		contents = append(contents, []string{fmt.Sprint(feature.BendID), fmt.Sprint(feature.Angle), fmt.Sprint(feature.Radius),
+			fmt.Sprintf("%.3f", feature.OutsideSetback), fmt.Sprintf("%.3f", feature.BendAllowance), fmt.Sprintf("%.3f", feature.BendDeduction)})
+	}
+
+	return header, contents
+}
+
+func (p *pdfService) getCostContent(cost entity.CostBreakdown) ([]string, [][]string) {
+	header := []string{"Item", "Per part", fmt.Sprintf("Batch of %d", cost.Quantity)}
+
+	quantity := float64(cost.Quantity)
+	contents := [][]string{
+		{fmt.Sprintf("Material (%.3f kg blank)", cost.BlankMass), fmt.Sprintf("%.2f", cost.MaterialCost), fmt.Sprintf("%.2f", cost.MaterialCost*quantity)},
+		{"Machine time", fmt.Sprintf("%.2f", cost.MachineCost), fmt.Sprintf("%.2f", cost.MachineCost*quantity)},
+		{"Tool setup", "", fmt.Sprintf("%.2f", cost.SetupCost)},
+		{"Quantity discount", "", fmt.Sprintf("-%.2f", cost.Discount)},
+		{"Total", fmt.Sprintf("%.2f", cost.UnitCost), fmt.Sprintf("%.2f", cost.TotalCost)},
 	}
 
 	return header, contents
 }
+
+// bendFeatureMap indexes bend features by bend ID.
+func bendFeatureMap(bendFeatures []entity.BendFeature) map[int]entity.BendFeature {
+	features := map[int]entity.BendFeature{}
+	for _, feature := range bendFeatures {
+		features[int(feature.BendID)] = feature
+	}
+
+	return features
+}
+
+func bendDirection(feature entity.BendFeature) string {
+	if feature.Direction == 1 {
+		return "Inside"
+	}
+
+	return "Outside"
+}
+
+// flatPatternLength prints n/a for parts whose flanges were not measured.
+func flatPatternLength(length float64) string {
+	if length <= 0 {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.2f", length)
+}
+
+// bendingForceModel names the model on the PDF. Plans made before models were
+// selectable used the legacy formula.
+func bendingForceModel(model entity.BendingForceModel) string {
+	switch model {
+	case entity.AirBending:
+		return "Air bending"
+	case entity.Bottoming:
+		return "Bottoming"
+	case entity.Coining:
+		return "Coining"
+	default:
+		return "Legacy"
+	}
+}
+
+// planField returns the label and value a template field prints for a plan.
+// Fields that do not apply to the plan, such as the approver of a draft, are
+// left out.
+func planField(field string, processingPlan *entity.ProcessingPlan) (string, string, bool) {
+	switch field {
+	case entity.FieldEngineer:
+		return "Engineer", processingPlan.Engineer, true
+	case entity.FieldDate:
+		return "Date", time.Unix(processingPlan.CreatedAt, 0).Format("02  October 2006"), true
+	case entity.FieldProjectName:
+		return "Project name", processingPlan.ProjectTitle, true
+	case entity.FieldModules:
+		return "Modules", fmt.Sprint(processingPlan.Modules), true
+	case entity.FieldPartName:
+		return "Part name", processingPlan.FileName, true
+	case entity.FieldPartNo:
+		return "Part no", processingPlan.PartNo, true
+	case entity.FieldMaterial:
+		return "Material", processingPlan.Material, true
+	case entity.FieldBendingForce:
+		return "Bending force", fmt.Sprintf("%.2f", processingPlan.BendingForce), true
+	case entity.FieldBendingForceModel:
+		return "Bending force model", bendingForceModel(processingPlan.BendingForceModel), true
+	case entity.FieldMachine:
+		return "Machine", processingPlan.MachineName, processingPlan.MachineName != ""
+	case entity.FieldPlanVersion:
+		return "Plan version", fmt.Sprint(processingPlan.Version), true
+	case entity.FieldApprovedBy:
+		return "Approved by", processingPlan.Moderator, processingPlan.IsApproved()
+	case entity.FieldTools:
+		return "Number of tools", fmt.Sprint(processingPlan.Tools), true
+	case entity.FieldRotations:
+		return "Number of rotations", fmt.Sprint(processingPlan.Rotations), true
+	case entity.FieldFlips:
+		return "Number of flips", fmt.Sprint(processingPlan.Flips), true
+	case entity.FieldQuantity:
+		return "Quantity", fmt.Sprint(processingPlan.Quantity), true
+	case entity.FieldPlanningTime:
+		return "Planning time", fmt.Sprintf("%.3f", processingPlan.ProcessingTime), true
+	case entity.FieldProductionTime:
+		return "Estimated production time", fmt.Sprintf("%.1f", processingPlan.EstimatedManufacturingTime), true
+	default:
+		return "", "", false
+	}
+}
+
+// templateBrand draws a template's logo, or its company name when it has no
+// logo.
+func templateBrand(m pdf.Maroto, template *entity.PDFTemplate, size float64) {
+	if template.Logo != "" {
+		m.Base64Image(template.Logo, consts.Extension(template.LogoType), props.Rect{
+			Percent: 90,
+		})
+		return
+	}
+
+	m.Text(template.CompanyName, props.Text{
+		Size:  size,
+		Top:   size / 3,
+		Style: consts.Bold,
+		Color: templateColour(template.PrimaryColour),
+	})
+}
+
+// templateFooter draws a template's footer text and the page number.
+func templateFooter(m pdf.Maroto, template *entity.PDFTemplate) {
+	m.Row(10, func() {
+		m.Col(9, func() {
+			m.Text(template.FooterText, props.Text{
+				Style: consts.Italic,
+				Size:  8,
+			})
+		})
+		m.Col(3, func() {
+			m.Text(strconv.Itoa(m.GetCurrentPage())+"/{nb}", props.Text{
+				Style: consts.BoldItalic,
+				Align: consts.Right,
+				Size:  9,
+			})
+		})
+	})
+}
+
+func pageSize(template *entity.PDFTemplate) consts.PageSize {
+	if template.PaperSize == entity.PaperLetter {
+		return consts.Letter
+	}
+
+	return consts.A4
+}
+
+func templateColour(rgb entity.RGB) color.Color {
+	return color.Color{
+		Red:   rgb.Red,
+		Green: rgb.Green,
+		Blue:  rgb.Blue,
+	}
+}
+
+// alternatedBackground shades every other table row in a template's
+// secondary colour. Tables are not shaded when it has none.
+func alternatedBackground(template *entity.PDFTemplate) *color.Color {
+	if template.SecondaryColour == nil {
+		return nil
+	}
+
+	background := templateColour(*template.SecondaryColour)
+	return &background
+}
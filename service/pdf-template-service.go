@@ -0,0 +1,205 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxLogoSize is the largest logo, in bytes, a template can have.
+const maxLogoSize = 512 * 1024
+
+var (
+	pdfTemplateRepo repository.PDFTemplateRepository
+)
+
+// ErrInvalidLogo is returned for logos that can not be printed
+var ErrInvalidLogo = fmt.Errorf("logo must be a PNG or JPEG image of at most %d KB", maxLogoSize/1024)
+
+// PDFTemplateService -
+type PDFTemplateService interface {
+	Validate(template *entity.PDFTemplate) error
+	Create(template *entity.PDFTemplate) (*entity.PDFTemplate, error)
+	Update(template *entity.PDFTemplate) (*entity.PDFTemplate, error)
+	SetLogo(id string, logo []byte) (int64, error)
+	RemoveLogo(id string) (int64, error)
+	Find(id string) (*entity.PDFTemplate, error)
+	FindAll() ([]entity.PDFTemplate, error)
+	Delete(id string) (int64, error)
+	ForCustomer(userID string) (*entity.PDFTemplate, error)
+}
+
+type pdfTemplateService struct{}
+
+// NewPDFTemplateService -
+func NewPDFTemplateService(dbRepository repository.PDFTemplateRepository) PDFTemplateService {
+	pdfTemplateRepo = dbRepository
+	return &pdfTemplateService{}
+}
+
+// DefaultPDFTemplate is the Fxtract branding, used when no template is
+// assigned to a customer and none is the default.
+func DefaultPDFTemplate() *entity.PDFTemplate {
+	return &entity.PDFTemplate{
+		Name:          "Fxtract",
+		CompanyName:   "Fxtract",
+		PrimaryColour: entity.RGB{Red: 3, Green: 166, Blue: 166},
+		QRCodeURL:     "https://fxtract.com/projects/{cadfile_id}/{cadfile_id}",
+		Fields:        append([]string{}, entity.PDFFields...),
+		PaperSize:     entity.PaperA4,
+		CustomerIDs:   []primitive.ObjectID{},
+	}
+}
+
+// Validate checks a template and fills in the paper size and fields of
+// templates that leave them out.
+func (*pdfTemplateService) Validate(template *entity.PDFTemplate) error {
+	if template == nil {
+		return errors.New("PDF template is empty")
+	}
+
+	if template.Name == "" {
+		return errors.New("fill in all the fields")
+	}
+
+	colours := []entity.RGB{template.PrimaryColour}
+	if template.SecondaryColour != nil {
+		colours = append(colours, *template.SecondaryColour)
+	}
+	for _, colour := range colours {
+		for _, c := range []int{colour.Red, colour.Green, colour.Blue} {
+			if c < 0 || c > 255 {
+				return errors.New("colour components must be between 0 and 255")
+			}
+		}
+	}
+
+	switch template.PaperSize {
+	case "":
+		template.PaperSize = entity.PaperA4
+	case entity.PaperA4, entity.PaperLetter:
+	default:
+		return fmt.Errorf("paper size must be %s or %s", entity.PaperA4, entity.PaperLetter)
+	}
+
+	if len(template.Fields) == 0 {
+		template.Fields = append([]string{}, entity.PDFFields...)
+	}
+
+	seen := map[string]bool{}
+	for _, field := range template.Fields {
+		if !isPDFField(field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+
+		if seen[field] {
+			return fmt.Errorf("field %q is listed twice", field)
+		}
+		seen[field] = true
+	}
+
+	if template.QRCodeURL != "" {
+		link, err := url.Parse(template.QRCodeURL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return errors.New("QR code URL must be an http or https URL")
+		}
+	}
+
+	if template.CustomerIDs == nil {
+		template.CustomerIDs = []primitive.ObjectID{}
+	}
+
+	return nil
+}
+
+func isPDFField(field string) bool {
+	for _, known := range entity.PDFFields {
+		if field == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (*pdfTemplateService) Create(template *entity.PDFTemplate) (*entity.PDFTemplate, error) {
+	return pdfTemplateRepo.Create(template)
+}
+
+func (*pdfTemplateService) Update(template *entity.PDFTemplate) (*entity.PDFTemplate, error) {
+	return pdfTemplateRepo.Update(template)
+}
+
+// SetLogo checks that a logo is a PNG or JPEG image and stores it on the
+// template.
+func (*pdfTemplateService) SetLogo(id string, logo []byte) (int64, error) {
+	if len(logo) == 0 || len(logo) > maxLogoSize {
+		return 0, ErrInvalidLogo
+	}
+
+	var logoType string
+	switch http.DetectContentType(logo) {
+	case "image/png":
+		logoType = "png"
+	case "image/jpeg":
+		logoType = "jpg"
+	default:
+		return 0, ErrInvalidLogo
+	}
+
+	return pdfTemplateRepo.UpdateLogo(id, base64.StdEncoding.EncodeToString(logo), logoType)
+}
+
+func (*pdfTemplateService) RemoveLogo(id string) (int64, error) {
+	return pdfTemplateRepo.UpdateLogo(id, "", "")
+}
+
+func (*pdfTemplateService) Find(id string) (*entity.PDFTemplate, error) {
+	return pdfTemplateRepo.Find(id)
+}
+
+func (*pdfTemplateService) FindAll() ([]entity.PDFTemplate, error) {
+	return pdfTemplateRepo.FindAll()
+}
+
+func (*pdfTemplateService) Delete(id string) (int64, error) {
+	return pdfTemplateRepo.Delete(id)
+}
+
+// ForCustomer returns the template of a user's PDFs: the one assigned to the
+// user, else the default template, else the Fxtract branding.
+func (*pdfTemplateService) ForCustomer(userID string) (*entity.PDFTemplate, error) {
+	template, err := pdfTemplateRepo.FindByCustomer(userID)
+	if err == nil {
+		return template, nil
+	}
+
+	if !errors.Is(err, mongo.ErrNoDocuments) && !errors.Is(err, primitive.ErrInvalidHex) {
+		return nil, err
+	}
+
+	template, err = pdfTemplateRepo.FindDefault()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return DefaultPDFTemplate(), nil
+	}
+
+	return template, err
+}
+
+// qrCodeURL fills in the QR code target of a template for a plan.
+func qrCodeURL(template *entity.PDFTemplate, processingPlan *entity.ProcessingPlan) string {
+	return strings.NewReplacer(
+		"{cadfile_id}", processingPlan.CADFileID.Hex(),
+		"{plan_id}", processingPlan.ID.Hex(),
+		"{version}", strconv.FormatInt(processingPlan.Version, 10),
+	).Replace(template.QRCodeURL)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"math"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// PlanDelta is how a figure changed from one plan version to another.
+type PlanDelta struct {
+	From       float64 `json:"from"`
+	To         float64 `json:"to"`
+	Difference float64 `json:"difference"`
+}
+
+// ToolChange is a bend formed with a different tool in the second plan.
+type ToolChange struct {
+	BendID     int64  `json:"bend_id"`
+	FromToolID string `json:"from_tool_id"`
+	ToToolID   string `json:"to_tool_id"`
+}
+
+// SequenceChange is a bend formed at a different step in the second plan.
+// Positions start at 1, and 0 means the plan does not form the bend.
+type SequenceChange struct {
+	BendID       int64 `json:"bend_id"`
+	FromPosition int   `json:"from_position"`
+	ToPosition   int   `json:"to_position"`
+}
+
+// PlanComparison is the difference between two versions of a CAD file's
+// processing plan.
+type PlanComparison struct {
+	CADFileID                  string           `json:"cadfile_id"`
+	FromVersion                int64            `json:"from_version"`
+	ToVersion                  int64            `json:"to_version"`
+	Rotations                  PlanDelta        `json:"rotations"`
+	Flips                      PlanDelta        `json:"flips"`
+	Tools                      PlanDelta        `json:"tools"`
+	EstimatedManufacturingTime PlanDelta        `json:"estimated_manufacturing_time"`
+	TotalToolDistance          PlanDelta        `json:"total_tool_distance"`
+	ToolChanges                []ToolChange     `json:"tool_changes"`
+	FromSequence               []int64          `json:"from_sequence"`
+	ToSequence                 []int64          `json:"to_sequence"`
+	SequenceChanges            []SequenceChange `json:"sequence_changes"`
+}
+
+// ComparePlans diffs two versions of a processing plan. Differences are
+// those of the second plan from the first.
+func ComparePlans(from *entity.ProcessingPlan, to *entity.ProcessingPlan) PlanComparison {
+	comparison := PlanComparison{
+		CADFileID:                  from.CADFileID.Hex(),
+		FromVersion:                from.Version,
+		ToVersion:                  to.Version,
+		Rotations:                  planDelta(float64(from.Rotations), float64(to.Rotations)),
+		Flips:                      planDelta(float64(from.Flips), float64(to.Flips)),
+		Tools:                      planDelta(float64(from.Tools), float64(to.Tools)),
+		EstimatedManufacturingTime: planDelta(from.EstimatedManufacturingTime, to.EstimatedManufacturingTime),
+		TotalToolDistance:          planDelta(from.TotalToolDistance, to.TotalToolDistance),
+		ToolChanges:                []ToolChange{},
+		FromSequence:               sequenceOrder(from.BendingSequences),
+		ToSequence:                 sequenceOrder(to.BendingSequences),
+		SequenceChanges:            []SequenceChange{},
+	}
+
+	toTools := make(map[int64]string)
+	for _, bend := range to.BendFeatures {
+		toTools[bend.BendID] = bend.ToolID
+	}
+
+	for _, bend := range from.BendFeatures {
+		if toolID, ok := toTools[bend.BendID]; ok && toolID != bend.ToolID {
+			comparison.ToolChanges = append(comparison.ToolChanges, ToolChange{BendID: bend.BendID, FromToolID: bend.ToolID, ToToolID: toolID})
+		}
+	}
+
+	fromPositions := sequencePositions(comparison.FromSequence)
+	toPositions := sequencePositions(comparison.ToSequence)
+
+	for _, bendID := range comparison.FromSequence {
+		if fromPositions[bendID] != toPositions[bendID] {
+			comparison.SequenceChanges = append(comparison.SequenceChanges, SequenceChange{BendID: bendID, FromPosition: fromPositions[bendID], ToPosition: toPositions[bendID]})
+		}
+	}
+
+	for _, bendID := range comparison.ToSequence {
+		if _, ok := fromPositions[bendID]; !ok {
+			comparison.SequenceChanges = append(comparison.SequenceChanges, SequenceChange{BendID: bendID, ToPosition: toPositions[bendID]})
+		}
+	}
+
+	return comparison
+}
+
+func planDelta(from float64, to float64) PlanDelta {
+	return PlanDelta{From: from, To: to, Difference: math.Round((to-from)*1000) / 1000}
+}
+
+func sequenceOrder(sequences []entity.BendingSequence) []int64 {
+	order := make([]int64, len(sequences))
+	for i, sequence := range sequences {
+		order[i] = sequence.BendID
+	}
+
+	return order
+}
+
+func sequencePositions(order []int64) map[int64]int {
+	positions := make(map[int64]int)
+	for i, bendID := range order {
+		positions[bendID] = i + 1
+	}
+
+	return positions
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestComparePlans(t *testing.T) {
+	cadFileID := primitive.NewObjectID()
+
+	sequence := func(bendIDs ...int64) []entity.BendingSequence {
+		sequences := []entity.BendingSequence{}
+		for _, bendID := range bendIDs {
+			sequences = append(sequences, entity.BendingSequence{BendID: bendID})
+		}
+		return sequences
+	}
+
+	from := &entity.ProcessingPlan{
+		CADFileID:                  cadFileID,
+		Version:                    1,
+		Rotations:                  2,
+		Flips:                      1,
+		Tools:                      2,
+		EstimatedManufacturingTime: 40.5,
+		TotalToolDistance:          120.25,
+		BendingSequences:           sequence(1, 2, 3),
+		BendFeatures: []entity.BendFeature{
+			{BendID: 1, ToolID: "T1"},
+			{BendID: 2, ToolID: "T1"},
+			{BendID: 3, ToolID: "T2"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		to              *entity.ProcessingPlan
+		toolChanges     []ToolChange
+		sequenceChanges []SequenceChange
+	}{
+		{
+			name:            "same plan",
+			to:              from,
+			toolChanges:     []ToolChange{},
+			sequenceChanges: []SequenceChange{},
+		},
+		{
+			name: "reordered with a new tool",
+			to: &entity.ProcessingPlan{
+				CADFileID:        cadFileID,
+				Version:          2,
+				BendingSequences: sequence(2, 1, 3),
+				BendFeatures: []entity.BendFeature{
+					{BendID: 1, ToolID: "T1"},
+					{BendID: 2, ToolID: "T3"},
+					{BendID: 3, ToolID: "T2"},
+				},
+			},
+			toolChanges: []ToolChange{{BendID: 2, FromToolID: "T1", ToToolID: "T3"}},
+			sequenceChanges: []SequenceChange{
+				{BendID: 1, FromPosition: 1, ToPosition: 2},
+				{BendID: 2, FromPosition: 2, ToPosition: 1},
+			},
+		},
+		{
+			name: "bends dropped and added",
+			to: &entity.ProcessingPlan{
+				CADFileID:        cadFileID,
+				Version:          3,
+				BendingSequences: sequence(1, 4),
+				BendFeatures: []entity.BendFeature{
+					{BendID: 1, ToolID: "T1"},
+					{BendID: 4, ToolID: "T2"},
+				},
+			},
+			toolChanges: []ToolChange{},
+			sequenceChanges: []SequenceChange{
+				{BendID: 2, FromPosition: 2},
+				{BendID: 3, FromPosition: 3},
+				{BendID: 4, ToPosition: 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comparison := ComparePlans(from, test.to)
+
+			if comparison.CADFileID != cadFileID.Hex() || comparison.FromVersion != from.Version || comparison.ToVersion != test.to.Version {
+				t.Errorf("compared %s versions %d and %d", comparison.CADFileID, comparison.FromVersion, comparison.ToVersion)
+			}
+
+			if !reflect.DeepEqual(comparison.ToolChanges, test.toolChanges) {
+				t.Errorf("tool changes %+v, want %+v", comparison.ToolChanges, test.toolChanges)
+			}
+
+			if !reflect.DeepEqual(comparison.SequenceChanges, test.sequenceChanges) {
+				t.Errorf("sequence changes %+v, want %+v", comparison.SequenceChanges, test.sequenceChanges)
+			}
+		})
+	}
+}
+
+func TestComparePlansDeltas(t *testing.T) {
+	from := &entity.ProcessingPlan{Rotations: 3, Flips: 1, Tools: 2, EstimatedManufacturingTime: 40.5, TotalToolDistance: 0.3}
+	to := &entity.ProcessingPlan{Rotations: 1, Flips: 1, Tools: 3, EstimatedManufacturingTime: 35.25, TotalToolDistance: 0.6}
+
+	comparison := ComparePlans(from, to)
+
+	tests := []struct {
+		name  string
+		delta PlanDelta
+		want  PlanDelta
+	}{
+		{"rotations", comparison.Rotations, PlanDelta{From: 3, To: 1, Difference: -2}},
+		{"flips", comparison.Flips, PlanDelta{From: 1, To: 1, Difference: 0}},
+		{"tools", comparison.Tools, PlanDelta{From: 2, To: 3, Difference: 1}},
+		{"manufacturing time", comparison.EstimatedManufacturingTime, PlanDelta{From: 40.5, To: 35.25, Difference: -5.25}},
+		{"tool distance", comparison.TotalToolDistance, PlanDelta{From: 0.3, To: 0.6, Difference: 0.3}},
+	}
+
+	for _, test := range tests {
+		if test.delta != test.want {
+			t.Errorf("%s delta %+v, want %+v", test.name, test.delta, test.want)
+		}
+	}
+}
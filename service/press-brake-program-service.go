@@ -0,0 +1,261 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// ErrUnknownProgramDialect is returned for a dialect that is not registered
+var ErrUnknownProgramDialect = errors.New("unknown press brake program dialect")
+
+// PressBrakeProgram is a processing plan in the form a press brake controller
+// needs it, before it is written in a controller's dialect.
+type PressBrakeProgram struct {
+	PartNo    string        `json:"part_no"`
+	FileName  string        `json:"filename"`
+	Material  string        `json:"material"`
+	Thickness float64       `json:"thickness"`
+	Version   int64         `json:"version"`
+	Steps     []ProgramStep `json:"steps"`
+}
+
+// ProgramStep is a bend of a press brake program. The part is flipped when
+// the bend direction changes from the previous step, and rotated end for end
+// when the bend shares a flange with the previous one. The back gauge is set
+// to the length of the bend's longer flange, and is nil when the flange
+// lengths of the part are unknown.
+type ProgramStep struct {
+	Step       int      `json:"step"`
+	BendID     int64    `json:"bend_id"`
+	Angle      float64  `json:"angle"`
+	Radius     float64  `json:"radius"`
+	Length     float64  `json:"length"`
+	Direction  string   `json:"direction"`
+	ToolID     string   `json:"tool_id"`
+	ToolName   string   `json:"tool_name"`
+	DieOpening float64  `json:"die_opening"`
+	BackGauge  *float64 `json:"back_gauge"`
+	Flip       bool     `json:"flip"`
+	Rotate     bool     `json:"rotate"`
+	Force      float64  `json:"force"`
+}
+
+// ProgramIssue is a step of a program that its tool can not form.
+type ProgramIssue struct {
+	Step    int    `json:"step"`
+	BendID  int64  `json:"bend_id"`
+	ToolID  string `json:"tool_id"`
+	Message string `json:"message"`
+}
+
+// ProgramDialect writes press brake programs for a family of controllers.
+// Dialects are made available with RegisterProgramDialect.
+type ProgramDialect interface {
+	// Name identifies the dialect in export requests.
+	Name() string
+	// Extension is the file extension of the dialect's programs.
+	Extension() string
+	Write(program *PressBrakeProgram) ([]byte, error)
+}
+
+var programDialects = map[string]ProgramDialect{}
+
+func init() {
+	RegisterProgramDialect(&genericDialect{})
+}
+
+// RegisterProgramDialect makes a dialect available for export, replacing any
+// dialect of the same name.
+func RegisterProgramDialect(dialect ProgramDialect) {
+	programDialects[dialect.Name()] = dialect
+}
+
+// ProgramDialects returns the names of the available dialects.
+func ProgramDialects() []string {
+	names := []string{}
+	for name := range programDialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// BuildProgram turns a processing plan into a press brake program. Each step
+// is checked against its tool, which must be in tools and able to form the
+// bend; the steps that fail are returned as issues.
+func BuildProgram(processingPlan *entity.ProcessingPlan, cadFile *entity.CADFile, tools map[string]*entity.Tool) (*PressBrakeProgram, []ProgramIssue) {
+	thickness := cadFile.FeatureProps.Thickness
+
+	program := &PressBrakeProgram{
+		PartNo:    processingPlan.PartNo,
+		FileName:  processingPlan.FileName,
+		Material:  processingPlan.Material,
+		Thickness: thickness,
+		Version:   processingPlan.Version,
+		Steps:     []ProgramStep{},
+	}
+	issues := []ProgramIssue{}
+
+	flanges := make(map[int64]float64)
+	for _, flange := range cadFile.FeatureProps.Flanges {
+		flanges[flange.FaceID] = flange.Length
+	}
+
+	features := bendFeatureMap(processingPlan.BendFeatures)
+
+	var previous *entity.BendFeature
+	for i, sequence := range processingPlan.BendingSequences {
+		bend, ok := features[int(sequence.BendID)]
+		if !ok {
+			issues = append(issues, ProgramIssue{Step: i + 1, BendID: sequence.BendID, Message: "bend is not a feature of the part"})
+			continue
+		}
+
+		step := ProgramStep{
+			Step:      i + 1,
+			BendID:    bend.BendID,
+			Angle:     bend.Angle,
+			Radius:    bend.Radius,
+			Length:    bend.Length,
+			Direction: bendDirection(bend),
+			ToolID:    bend.ToolID,
+			Force:     bend.BendingForce,
+		}
+
+		tool, ok := tools[bend.ToolID]
+		if bend.ToolID == "" {
+			issues = append(issues, ProgramIssue{Step: step.Step, BendID: bend.BendID, Message: "no tool was selected for the bend"})
+		} else if !ok || tool == nil {
+			issues = append(issues, ProgramIssue{Step: step.Step, BendID: bend.BendID, ToolID: bend.ToolID, Message: "tool is not in the tool library"})
+		} else {
+			step.ToolName = tool.ToolName
+			if candidate := scoreTool(*tool, bend); !candidate.Feasible {
+				issues = append(issues, ProgramIssue{Step: step.Step, BendID: bend.BendID, ToolID: bend.ToolID, Message: "tool can not form the bend: " + strings.Join(candidate.Reasons, "; ")})
+			}
+		}
+		step.DieOpening = DieOpening(tool, thickness)
+
+		first, firstOK := flanges[bend.FirstFaceID]
+		second, secondOK := flanges[bend.SecondFaceID]
+		if firstOK || secondOK {
+			backGauge := first
+			if second > backGauge {
+				backGauge = second
+			}
+			step.BackGauge = &backGauge
+		}
+
+		if previous != nil {
+			step.Flip = bend.Direction != previous.Direction
+			step.Rotate = sharesFace(bend, *previous)
+		}
+
+		program.Steps = append(program.Steps, step)
+		previous = &bend
+	}
+
+	return program, issues
+}
+
+// ExportProgram writes a program in a dialect.
+func ExportProgram(dialectName string, program *PressBrakeProgram) (*Export, error) {
+	dialect, ok := programDialects[dialectName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProgramDialect, dialectName)
+	}
+
+	content, err := dialect.Write(program)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := exportFilename(program.PartNo, strings.TrimSuffix(program.FileName, filepath.Ext(program.FileName)))
+
+	return &Export{Filename: filename + "." + dialect.Extension(), ContentType: "text/plain", Content: content}, nil
+}
+
+func sharesFace(a entity.BendFeature, b entity.BendFeature) bool {
+	return a.FirstFaceID == b.FirstFaceID || a.FirstFaceID == b.SecondFaceID ||
+		a.SecondFaceID == b.FirstFaceID || a.SecondFaceID == b.SecondFaceID
+}
+
+// genericDialect is a plain text format for controllers without a dialect of
+// their own, and for operators who key programs in by hand. Lines starting
+// with ";" are comments. The header names the part, then each step is a line
+// of KEY=VALUE fields separated by spaces, in this order:
+//
+//	STEP       step number, from 1
+//	BEND       bend ID from feature recognition
+//	ANGLE      bend angle in degrees
+//	RADIUS     inside radius in mm
+//	LENGTH     bend length in mm
+//	DIR        IN or OUT
+//	TOOL       tool ID
+//	DIE        V-die opening in mm
+//	X          back gauge position in mm, or - when it must be set by hand
+//	FLIP       1 to turn the part over before the step, else 0
+//	ROT        1 to turn the part end for end before the step, else 0
+//	FORCE      bending force
+//
+// The program ends with a line reading END.
+type genericDialect struct{}
+
+func (*genericDialect) Name() string {
+	return "generic"
+}
+
+func (*genericDialect) Extension() string {
+	return "txt"
+}
+
+func (*genericDialect) Write(program *PressBrakeProgram) ([]byte, error) {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, "; FXTRACT GENERIC PRESS BRAKE PROGRAM 1")
+	fmt.Fprintf(&b, "; PART=%s FILE=%s MATERIAL=%s THICKNESS=%.3f PLAN=%d\n",
+		genericValue(program.PartNo), genericValue(program.FileName), genericValue(program.Material), program.Thickness, program.Version)
+
+	for _, step := range program.Steps {
+		backGauge := "-"
+		if step.BackGauge != nil {
+			backGauge = fmt.Sprintf("%.3f", *step.BackGauge)
+		}
+
+		direction := "OUT"
+		if step.Direction == "Inside" {
+			direction = "IN"
+		}
+
+		fmt.Fprintf(&b, "STEP=%d BEND=%d ANGLE=%.3f RADIUS=%.3f LENGTH=%.3f DIR=%s TOOL=%s DIE=%.3f X=%s FLIP=%d ROT=%d FORCE=%.2f\n",
+			step.Step, step.BendID, step.Angle, step.Radius, step.Length, direction, genericValue(step.ToolID), step.DieOpening,
+			backGauge, boolFlag(step.Flip), boolFlag(step.Rotate), step.Force)
+	}
+
+	fmt.Fprintln(&b, "END")
+
+	return b.Bytes(), nil
+}
+
+// genericValue keeps a value on one field of a generic program line.
+func genericValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return strings.Join(strings.Fields(value), "_")
+}
+
+func boolFlag(flag bool) int {
+	if flag {
+		return 1
+	}
+
+	return 0
+}
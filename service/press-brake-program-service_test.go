@@ -0,0 +1,119 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+func TestBuildProgram(t *testing.T) {
+	tools := map[string]*entity.Tool{
+		"T1": {ToolID: "T1", ToolName: "Gooseneck 90", Angle: 90, MinRadius: 1, MaxRadius: 4, Length: 200, DieOpening: 12},
+	}
+
+	cadFile := &entity.CADFile{
+		FeatureProps: entity.FeatureProperty{
+			Thickness: 2,
+			Flanges:   []entity.Flange{{FaceID: 1, Length: 20}, {FaceID: 2, Length: 60}, {FaceID: 3, Length: 25}},
+		},
+	}
+
+	processingPlan := &entity.ProcessingPlan{
+		PartNo:   "P-100",
+		FileName: "bracket.step",
+		Version:  3,
+		BendFeatures: []entity.BendFeature{
+			{BendID: 1, FirstFaceID: 1, SecondFaceID: 2, Angle: 90, Radius: 2, Length: 100, Direction: 1, ToolID: "T1", BendingForce: 40},
+			{BendID: 2, FirstFaceID: 2, SecondFaceID: 3, Angle: 90, Radius: 2, Length: 100, Direction: 1, ToolID: "T1", BendingForce: 40},
+			{BendID: 3, FirstFaceID: 4, SecondFaceID: 5, Angle: 90, Radius: 2, Length: 100, Direction: 0, ToolID: "T1", BendingForce: 40},
+		},
+		BendingSequences: []entity.BendingSequence{{BendID: 1}, {BendID: 2}, {BendID: 3}},
+	}
+
+	program, issues := BuildProgram(processingPlan, cadFile, tools)
+	if len(issues) != 0 {
+		t.Fatalf("issues %+v, want none", issues)
+	}
+
+	if program.PartNo != "P-100" || program.FileName != "bracket.step" || program.Version != 3 || program.Thickness != 2 {
+		t.Errorf("program header %+v does not match the plan", program)
+	}
+
+	tests := []struct {
+		bendID    int64
+		direction string
+		backGauge float64
+		flip      bool
+		rotate    bool
+	}{
+		{1, "Inside", 60, false, false},
+		{2, "Inside", 60, false, true},
+		{3, "Outside", -1, true, false},
+	}
+
+	if len(program.Steps) != len(tests) {
+		t.Fatalf("%d steps, want %d", len(program.Steps), len(tests))
+	}
+
+	for i, test := range tests {
+		step := program.Steps[i]
+		if step.Step != i+1 || step.BendID != test.bendID || step.Direction != test.direction ||
+			step.Flip != test.flip || step.Rotate != test.rotate {
+			t.Errorf("step %d is %+v", i+1, step)
+		}
+
+		if step.ToolName != "Gooseneck 90" || step.DieOpening != 12 || step.Force != 40 {
+			t.Errorf("step %d has tool %q, die %v and force %v", i+1, step.ToolName, step.DieOpening, step.Force)
+		}
+
+		if test.backGauge < 0 {
+			if step.BackGauge != nil {
+				t.Errorf("step %d back gauge %v, want none", i+1, *step.BackGauge)
+			}
+		} else if step.BackGauge == nil || *step.BackGauge != test.backGauge {
+			t.Errorf("step %d back gauge %v, want %v", i+1, step.BackGauge, test.backGauge)
+		}
+	}
+}
+
+func TestBuildProgramIssues(t *testing.T) {
+	tools := map[string]*entity.Tool{
+		"T1":  {ToolID: "T1", Angle: 90, MinRadius: 1, MaxRadius: 4, Length: 200},
+		"T45": {ToolID: "T45", Angle: 45, MinRadius: 1, MaxRadius: 4, Length: 200},
+	}
+
+	cadFile := &entity.CADFile{FeatureProps: entity.FeatureProperty{Thickness: 2}}
+
+	tests := []struct {
+		name    string
+		bend    entity.BendFeature
+		bendID  int64
+		toolID  string
+		message string
+	}{
+		{"bend not on the part", entity.BendFeature{BendID: 1, ToolID: "T1"}, 9, "", "bend is not a feature of the part"},
+		{"no tool", entity.BendFeature{BendID: 1, Angle: 90, Radius: 2, Length: 100}, 1, "", "no tool was selected for the bend"},
+		{"tool not in the library", entity.BendFeature{BendID: 1, Angle: 90, Radius: 2, Length: 100, ToolID: "T9"}, 1, "T9", "tool is not in the tool library"},
+		{"tool can not form the bend", entity.BendFeature{BendID: 1, Angle: 90, Radius: 2, Length: 100, ToolID: "T45"}, 1, "T45", "tool can not form the bend: "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processingPlan := &entity.ProcessingPlan{
+				BendFeatures:     []entity.BendFeature{test.bend},
+				BendingSequences: []entity.BendingSequence{{BendID: test.bendID}},
+			}
+
+			_, issues := BuildProgram(processingPlan, cadFile, tools)
+			if len(issues) != 1 {
+				t.Fatalf("issues %+v, want one", issues)
+			}
+
+			issue := issues[0]
+			if issue.Step != 1 || issue.BendID != test.bendID || issue.ToolID != test.toolID || !strings.HasPrefix(issue.Message, test.message) {
+				t.Errorf("issue %+v, want bend %d, tool %q and %q", issue, test.bendID, test.toolID, test.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/repository"
+)
+
+var (
+	processedEventRepo repository.ProcessedEventRepository
+)
+
+// eventClaimLease is how long a claim protects an event that is still being
+// handled. After that, a redelivery may take the claim over.
+const eventClaimLease = 10 * time.Minute
+
+// ProcessedEventService -
+type ProcessedEventService interface {
+	Claim(eventID string, eventName string) (bool, error)
+	Complete(eventID string) error
+	Release(eventID string) error
+}
+
+type processedEventService struct{}
+
+// NewProcessedEventService -
+func NewProcessedEventService(dbRepository repository.ProcessedEventRepository) ProcessedEventService {
+	processedEventRepo = dbRepository
+	return &processedEventService{}
+}
+
+func (*processedEventService) Claim(eventID string, eventName string) (bool, error) {
+	now := time.Now()
+
+	event := &entity.ProcessedEvent{
+		ID:        eventID,
+		EventName: eventName,
+		Status:    entity.EventInProgress,
+		ClaimedAt: now.Unix(),
+	}
+
+	return processedEventRepo.Claim(event, now.Add(-eventClaimLease).Unix())
+}
+
+func (*processedEventService) Complete(eventID string) error {
+	return processedEventRepo.Complete(eventID, time.Now().Unix())
+}
+
+func (*processedEventService) Release(eventID string) error {
+	return processedEventRepo.Release(eventID)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// processedEventRepoStub keeps the ledger in memory with the semantics of the
+// MongoDB repository.
+type processedEventRepoStub struct {
+	events map[string]entity.ProcessedEvent
+}
+
+func (r *processedEventRepoStub) Claim(event *entity.ProcessedEvent, staleBefore int64) (bool, error) {
+	existing, ok := r.events[event.ID]
+	if !ok {
+		r.events[event.ID] = *event
+		return true, nil
+	}
+
+	if existing.Status != entity.EventInProgress || existing.ClaimedAt >= staleBefore {
+		return false, nil
+	}
+
+	existing.ClaimedAt = event.ClaimedAt
+	r.events[event.ID] = existing
+	return true, nil
+}
+
+func (r *processedEventRepoStub) Complete(id string, completedAt int64) error {
+	if event, ok := r.events[id]; ok {
+		event.Status = entity.EventDone
+		event.CompletedAt = completedAt
+		r.events[id] = event
+	}
+
+	return nil
+}
+
+func (r *processedEventRepoStub) Release(id string) error {
+	if event, ok := r.events[id]; ok && event.Status == entity.EventInProgress {
+		delete(r.events, id)
+	}
+
+	return nil
+}
+
+func TestProcessedEventLedger(t *testing.T) {
+	type step struct {
+		action string
+		want   bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"first claim", []step{{"claim", true}}},
+		{"claimed twice", []step{{"claim", true}, {"claim", false}}},
+		{"completed", []step{{"claim", true}, {"complete", false}, {"claim", false}}},
+		{"released", []step{{"claim", true}, {"release", false}, {"claim", true}}},
+		{"released after completion", []step{{"claim", true}, {"complete", false}, {"release", false}, {"claim", false}}},
+		{"stale claim", []step{{"claim", true}, {"expire", false}, {"claim", true}, {"claim", false}}},
+		{"stale completion", []step{{"claim", true}, {"complete", false}, {"expire", false}, {"claim", false}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &processedEventRepoStub{events: map[string]entity.ProcessedEvent{}}
+			ledger := NewProcessedEventService(repo)
+
+			for i, step := range test.steps {
+				switch step.action {
+				case "claim":
+					claimed, err := ledger.Claim("event-1", "processPlanningComplete")
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if claimed != step.want {
+						t.Errorf("claim in step %d = %v, want %v", i, claimed, step.want)
+					}
+				case "complete":
+					if err := ledger.Complete("event-1"); err != nil {
+						t.Fatal(err)
+					}
+				case "release":
+					if err := ledger.Release("event-1"); err != nil {
+						t.Fatal(err)
+					}
+				case "expire":
+					// Pretend the handler holding the claim crashed a while ago.
+					event := repo.events["event-1"]
+					event.ClaimedAt = time.Now().Add(-eventClaimLease - time.Minute).Unix()
+					repo.events["event-1"] = event
+				}
+			}
+		})
+	}
+}
+
+func TestProcessedEventClaimRecordsTheEvent(t *testing.T) {
+	repo := &processedEventRepoStub{events: map[string]entity.ProcessedEvent{}}
+	ledger := NewProcessedEventService(repo)
+
+	before := time.Now().Unix()
+	if _, err := ledger.Claim("event-1", "processPlanningComplete"); err != nil {
+		t.Fatal(err)
+	}
+
+	event := repo.events["event-1"]
+	if event.EventName != "processPlanningComplete" || event.Status != entity.EventInProgress || event.ClaimedAt < before {
+		t.Errorf("claimed event is %+v", event)
+	}
+
+	if err := ledger.Complete("event-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if event := repo.events["event-1"]; event.Status != entity.EventDone || event.CompletedAt < before {
+		t.Errorf("completed event is %+v", event)
+	}
+}
@@ -1,22 +1,58 @@
 package service
 
 import (
+	"crypto/sha1"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/WilfredDube/fxtract-backend/entity"
 	"github.com/WilfredDube/fxtract-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
 	processingPlanRepo repository.ProcessingPlanRepository
 )
 
+// Errors of processing plan reviews
+var (
+	ErrPlanReleased      = errors.New("a released processing plan can not be changed")
+	ErrInvalidTransition = errors.New("invalid review status change")
+	ErrNotReviewer       = errors.New("only a reviewer of the processing plan can approve or reject it")
+	ErrNotPlanMember     = errors.New("only the owner or a reviewer of the processing plan can change its status")
+	ErrNotPlanOwner      = errors.New("only the owner of the processing plan can assign its reviewers")
+	ErrInvalidReview     = errors.New("invalid review")
+)
+
+// maxReviewUpdateAttempts bounds how often MutateReview re-reads a plan whose
+// review keeps being changed concurrently.
+const maxReviewUpdateAttempts = 10
+
+// planTransitions are the review statuses a plan can move to from each status.
+var planTransitions = map[entity.PlanStatus][]entity.PlanStatus{
+	entity.PlanDraft:    {entity.PlanInReview},
+	entity.PlanInReview: {entity.PlanDraft, entity.PlanApproved, entity.PlanRejected},
+	entity.PlanRejected: {entity.PlanDraft, entity.PlanInReview},
+	entity.PlanApproved: {entity.PlanReleased},
+}
+
 // ProcessingPlanService -
 type ProcessingPlanService interface {
 	Validate(processingPlan *entity.ProcessingPlan) error
+	ValidateConstraints(constraints *entity.PlanningConstraints, cadFile *entity.CADFile) error
 	Create(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error)
 	Update(processingPlan entity.ProcessingPlan) (*entity.ProcessingPlan, error)
 	Find(id string) (*entity.ProcessingPlan, error)
+	FindVersions(cadFileID string) ([]entity.ProcessingPlan, error)
+	FindVersion(cadFileID string, version int64) (*entity.ProcessingPlan, error)
+	FindByID(id string) (*entity.ProcessingPlan, error)
+	NextVersion(cadFileID string) (int64, error)
+	Activate(cadFileID string, version int64) (int64, error)
+	AssignReviewers(processingPlan *entity.ProcessingPlan, reviewers []entity.Reviewer, user *entity.User, ownerID primitive.ObjectID) error
+	AddComment(processingPlan *entity.ProcessingPlan, comment entity.ReviewComment, user *entity.User, ownerID primitive.ObjectID) error
+	Transition(processingPlan *entity.ProcessingPlan, status entity.PlanStatus, user *entity.User, ownerID primitive.ObjectID, comment string) error
+	MutateReview(cadFileID string, version int64, mutate func(processingPlan *entity.ProcessingPlan) error) (*entity.ProcessingPlan, error)
 	FindAll(processingPlanID string) ([]entity.ProcessingPlan, error)
 	Delete(id string) (int64, error)
 	CascadeDelete(id string) (int64, error)
@@ -38,6 +74,49 @@ func (*processingPlanService) Validate(processingPlan *entity.ProcessingPlan) er
 	return nil
 }
 
+// ValidateConstraints checks that planning constraints can apply to a CAD
+// file, and defaults the objective to the shortest manufacturing time.
+func (*processingPlanService) ValidateConstraints(constraints *entity.PlanningConstraints, cadFile *entity.CADFile) error {
+	if constraints == nil {
+		return errors.New("planning constraints are empty")
+	}
+
+	switch constraints.Objective {
+	case "":
+		constraints.Objective = entity.MinimiseTime
+	case entity.MinimiseTime, entity.MinimiseToolChanges, entity.MinimiseHandling:
+	default:
+		return fmt.Errorf("unknown planning objective %q", constraints.Objective)
+	}
+
+	if (constraints.MaxRotations != nil && *constraints.MaxRotations < 0) || (constraints.MaxFlips != nil && *constraints.MaxFlips < 0) {
+		return errors.New("maximum rotations and flips can not be negative")
+	}
+
+	bends := make(map[int64]bool)
+	for _, bend := range cadFile.BendFeatures {
+		bends[bend.BendID] = true
+	}
+
+	for _, bendID := range []int64{constraints.FirstBendID, constraints.LastBendID} {
+		if bendID != 0 && !bends[bendID] {
+			return fmt.Errorf("bend %d is not a bend of %s", bendID, cadFile.FileName)
+		}
+	}
+
+	if constraints.FirstBendID != 0 && constraints.FirstBendID == constraints.LastBendID && len(bends) > 1 {
+		return errors.New("the first and last bends must differ")
+	}
+
+	for _, toolID := range constraints.ForbiddenTools {
+		if _, err := primitive.ObjectIDFromHex(toolID); err != nil {
+			return fmt.Errorf("invalid tool ID %q", toolID)
+		}
+	}
+
+	return nil
+}
+
 func (c *processingPlanService) Create(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error) {
 	if err := c.Validate(processingPlan); err != nil {
 		return nil, err
@@ -54,6 +133,41 @@ func (*processingPlanService) Find(id string) (*entity.ProcessingPlan, error) {
 	return processingPlanRepo.Find(id)
 }
 
+func (*processingPlanService) FindVersions(cadFileID string) ([]entity.ProcessingPlan, error) {
+	return processingPlanRepo.FindVersions(cadFileID)
+}
+
+func (*processingPlanService) FindVersion(cadFileID string, version int64) (*entity.ProcessingPlan, error) {
+	return processingPlanRepo.FindVersion(cadFileID, version)
+}
+
+func (*processingPlanService) FindByID(id string) (*entity.ProcessingPlan, error) {
+	return processingPlanRepo.FindByID(id)
+}
+
+// PlanID returns the ID of the plan a task makes for a CAD file. A task plans
+// each of its CAD files once, so redeliveries of its planning event find the
+// plan stored by an earlier attempt instead of making another.
+func PlanID(taskID string, cadFileID string) primitive.ObjectID {
+	sum := sha1.Sum([]byte(taskID + "/" + cadFileID))
+
+	var id primitive.ObjectID
+	copy(id[:], sum[:])
+
+	return id
+}
+
+// NextVersion allocates the version number of a CAD file's next plan. Each
+// call returns a new number, even for plans of the same CAD file completing
+// at the same time.
+func (*processingPlanService) NextVersion(cadFileID string) (int64, error) {
+	return processingPlanRepo.NextVersion(cadFileID)
+}
+
+func (*processingPlanService) Activate(cadFileID string, version int64) (int64, error) {
+	return processingPlanRepo.Activate(cadFileID, version)
+}
+
 func (*processingPlanService) FindAll(processingPlanID string) ([]entity.ProcessingPlan, error) {
 	return processingPlanRepo.FindAll(processingPlanID)
 }
@@ -65,3 +179,154 @@ func (*processingPlanService) Delete(id string) (int64, error) {
 func (*processingPlanService) CascadeDelete(processingPlanID string) (int64, error) {
 	return processingPlanRepo.CascadeDelete(processingPlanID)
 }
+
+// AssignReviewers sets who reviews a plan that has not been decided on yet.
+// Only ownerID, the owner of the plan's project, or an admin can do so.
+func (*processingPlanService) AssignReviewers(processingPlan *entity.ProcessingPlan, reviewers []entity.Reviewer, user *entity.User, ownerID primitive.ObjectID) error {
+	if user.ID != ownerID && user.UserRole != entity.ADMIN {
+		return ErrNotPlanOwner
+	}
+
+	switch processingPlan.ReviewStatus() {
+	case entity.PlanReleased:
+		return ErrPlanReleased
+	case entity.PlanApproved:
+		return fmt.Errorf("%w: an approved plan keeps its reviewers", ErrInvalidTransition)
+	}
+
+	if len(reviewers) == 0 {
+		return fmt.Errorf("%w: at least one reviewer is needed", ErrInvalidReview)
+	}
+
+	processingPlan.Reviewers = reviewers
+	return nil
+}
+
+// AddComment adds a user's comment to the review of a plan. Like status
+// changes, comments are limited to the owner and the reviewers of the plan.
+func (*processingPlanService) AddComment(processingPlan *entity.ProcessingPlan, comment entity.ReviewComment, user *entity.User, ownerID primitive.ObjectID) error {
+	if user.ID != ownerID && !isReviewer(processingPlan, user) {
+		return ErrNotPlanMember
+	}
+
+	if processingPlan.ReviewStatus() == entity.PlanReleased {
+		return ErrPlanReleased
+	}
+
+	if comment.Comment == "" {
+		return fmt.Errorf("%w: comment is empty", ErrInvalidReview)
+	}
+
+	processingPlan.ReviewComments = append(processingPlan.ReviewComments, comment)
+	return nil
+}
+
+// Transition moves a plan to another review status on behalf of a user. Only
+// a reviewer of the plan, or an admin, can approve or reject it; the other
+// changes, such as releasing the plan, can also be made by ownerID, the owner
+// of the plan's project. Approval records the user as the plan's moderator.
+// The comment, if any, is kept with the status it was made for.
+func (*processingPlanService) Transition(processingPlan *entity.ProcessingPlan, status entity.PlanStatus, user *entity.User, ownerID primitive.ObjectID, comment string) error {
+	current := processingPlan.ReviewStatus()
+	if current == entity.PlanReleased {
+		return ErrPlanReleased
+	}
+
+	allowed := false
+	for _, next := range planTransitions[current] {
+		if next == status {
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, current, status)
+	}
+
+	switch status {
+	case entity.PlanInReview:
+		if len(processingPlan.Reviewers) == 0 {
+			return fmt.Errorf("%w: assign reviewers before submitting the plan", ErrInvalidTransition)
+		}
+	case entity.PlanApproved, entity.PlanRejected:
+		if !isReviewer(processingPlan, user) {
+			return ErrNotReviewer
+		}
+	}
+
+	if user.ID != ownerID && !isReviewer(processingPlan, user) {
+		return ErrNotPlanMember
+	}
+
+	now := time.Now().Unix()
+
+	processingPlan.Status = status
+	if status == entity.PlanApproved {
+		processingPlan.Moderator = user.FullName()
+		processingPlan.ApprovedAt = now
+	} else if status != entity.PlanReleased {
+		processingPlan.Moderator = ""
+		processingPlan.ApprovedAt = 0
+	}
+
+	if comment != "" {
+		processingPlan.ReviewComments = append(processingPlan.ReviewComments, entity.ReviewComment{
+			AuthorID:  user.ID,
+			Author:    user.FullName(),
+			Status:    status,
+			Comment:   comment,
+			CreatedAt: now,
+		})
+	}
+
+	return nil
+}
+
+// MutateReview applies a review change to the latest copy of a plan version
+// and stores it, re-reading and re-applying the change when the plan was
+// reviewed concurrently. A plan released in the meantime fails the change
+// with ErrPlanReleased.
+func (*processingPlanService) MutateReview(cadFileID string, version int64, mutate func(processingPlan *entity.ProcessingPlan) error) (*entity.ProcessingPlan, error) {
+	var err error
+	for attempt := 0; attempt < maxReviewUpdateAttempts; attempt++ {
+		var processingPlan *entity.ProcessingPlan
+		processingPlan, err = processingPlanRepo.FindVersion(cadFileID, version)
+		if err != nil {
+			return nil, err
+		}
+
+		if processingPlan.ReviewStatus() == entity.PlanReleased {
+			return nil, ErrPlanReleased
+		}
+
+		if err = mutate(processingPlan); err != nil {
+			return nil, err
+		}
+
+		processingPlan, err = processingPlanRepo.UpdateReview(processingPlan)
+		if err == nil {
+			return processingPlan, nil
+		}
+
+		if !errors.Is(err, repository.ErrReviewConflict) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func isReviewer(processingPlan *entity.ProcessingPlan, user *entity.User) bool {
+	if user.UserRole == entity.ADMIN {
+		return true
+	}
+
+	for _, reviewer := range processingPlan.Reviewers {
+		if reviewer.UserID == user.ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/WilfredDube/fxtract-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var allPlanStatuses = []entity.PlanStatus{
+	entity.PlanDraft, entity.PlanInReview, entity.PlanApproved, entity.PlanRejected, entity.PlanReleased,
+}
+
+type reviewUsers struct {
+	owner, reviewer, admin, stranger *entity.User
+}
+
+func newReviewUsers() reviewUsers {
+	return reviewUsers{
+		owner:    &entity.User{ID: primitive.NewObjectID(), Firstname: "Olive", Lastname: "Owner"},
+		reviewer: &entity.User{ID: primitive.NewObjectID(), Firstname: "Rita", Lastname: "Reviewer"},
+		admin:    &entity.User{ID: primitive.NewObjectID(), Firstname: "Ada", Lastname: "Admin", UserRole: entity.ADMIN},
+		stranger: &entity.User{ID: primitive.NewObjectID(), Firstname: "Sam", Lastname: "Stranger"},
+	}
+}
+
+func (u reviewUsers) plan(status entity.PlanStatus) *entity.ProcessingPlan {
+	return &entity.ProcessingPlan{
+		Status:    status,
+		Reviewers: []entity.Reviewer{{UserID: u.reviewer.ID, Name: u.reviewer.FullName()}},
+	}
+}
+
+func TestPlanTransitions(t *testing.T) {
+	allowed := map[entity.PlanStatus][]entity.PlanStatus{
+		entity.PlanDraft:    {entity.PlanInReview},
+		entity.PlanInReview: {entity.PlanDraft, entity.PlanApproved, entity.PlanRejected},
+		entity.PlanRejected: {entity.PlanDraft, entity.PlanInReview},
+		entity.PlanApproved: {entity.PlanReleased},
+	}
+
+	users := newReviewUsers()
+	processingPlanService := NewProcessingPlanService(nil)
+
+	for _, from := range allPlanStatuses {
+		for _, to := range allPlanStatuses {
+			var want error
+			switch {
+			case from == entity.PlanReleased:
+				want = ErrPlanReleased
+			case !containsStatus(allowed[from], to):
+				want = ErrInvalidTransition
+			}
+
+			processingPlan := users.plan(from)
+			err := processingPlanService.Transition(processingPlan, to, users.admin, users.owner.ID, "")
+			if !errors.Is(err, want) {
+				t.Errorf("%s to %s returned %v, want %v", from, to, err, want)
+			}
+
+			if err == nil && processingPlan.Status != to {
+				t.Errorf("%s to %s left the plan %s", from, to, processingPlan.Status)
+			}
+		}
+	}
+
+	// A plan without a status is a draft.
+	processingPlan := users.plan("")
+	if err := processingPlanService.Transition(processingPlan, entity.PlanInReview, users.owner, users.owner.ID, ""); err != nil {
+		t.Errorf("submitting a plan without a status returned %v", err)
+	}
+}
+
+func TestTransitionRoles(t *testing.T) {
+	users := newReviewUsers()
+
+	tests := []struct {
+		from, to entity.PlanStatus
+		user     *entity.User
+		want     error
+	}{
+		{entity.PlanDraft, entity.PlanInReview, users.owner, nil},
+		{entity.PlanDraft, entity.PlanInReview, users.reviewer, nil},
+		{entity.PlanDraft, entity.PlanInReview, users.admin, nil},
+		{entity.PlanDraft, entity.PlanInReview, users.stranger, ErrNotPlanMember},
+		{entity.PlanInReview, entity.PlanApproved, users.owner, ErrNotReviewer},
+		{entity.PlanInReview, entity.PlanApproved, users.reviewer, nil},
+		{entity.PlanInReview, entity.PlanApproved, users.admin, nil},
+		{entity.PlanInReview, entity.PlanApproved, users.stranger, ErrNotReviewer},
+		{entity.PlanInReview, entity.PlanRejected, users.owner, ErrNotReviewer},
+		{entity.PlanInReview, entity.PlanRejected, users.reviewer, nil},
+		{entity.PlanInReview, entity.PlanDraft, users.owner, nil},
+		{entity.PlanInReview, entity.PlanDraft, users.stranger, ErrNotPlanMember},
+		{entity.PlanApproved, entity.PlanReleased, users.owner, nil},
+		{entity.PlanApproved, entity.PlanReleased, users.reviewer, nil},
+		{entity.PlanApproved, entity.PlanReleased, users.stranger, ErrNotPlanMember},
+	}
+
+	processingPlanService := NewProcessingPlanService(nil)
+	for _, test := range tests {
+		processingPlan := users.plan(test.from)
+
+		err := processingPlanService.Transition(processingPlan, test.to, test.user, users.owner.ID, "")
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s moving %s to %s returned %v, want %v", test.user.FullName(), test.from, test.to, err, test.want)
+		}
+
+		if err != nil && processingPlan.Status != test.from {
+			t.Errorf("%s moving %s to %s changed the plan although it failed", test.user.FullName(), test.from, test.to)
+		}
+	}
+}
+
+func TestTransitionRecordsTheReview(t *testing.T) {
+	users := newReviewUsers()
+	processingPlanService := NewProcessingPlanService(nil)
+
+	processingPlan := users.plan(entity.PlanDraft)
+	processingPlan.Reviewers = nil
+	if err := processingPlanService.Transition(processingPlan, entity.PlanInReview, users.owner, users.owner.ID, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("submitting a plan without reviewers returned %v, want ErrInvalidTransition", err)
+	}
+
+	processingPlan = users.plan(entity.PlanInReview)
+	if err := processingPlanService.Transition(processingPlan, entity.PlanApproved, users.reviewer, users.owner.ID, "looks good"); err != nil {
+		t.Fatal(err)
+	}
+
+	if processingPlan.Moderator != users.reviewer.FullName() || processingPlan.ApprovedAt == 0 {
+		t.Errorf("approved plan has moderator %q approved at %d", processingPlan.Moderator, processingPlan.ApprovedAt)
+	}
+
+	if len(processingPlan.ReviewComments) != 1 || processingPlan.ReviewComments[0].Status != entity.PlanApproved ||
+		processingPlan.ReviewComments[0].AuthorID != users.reviewer.ID {
+		t.Errorf("approval comments are %+v", processingPlan.ReviewComments)
+	}
+
+	if err := processingPlanService.Transition(processingPlan, entity.PlanReleased, users.owner, users.owner.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if processingPlan.Moderator != users.reviewer.FullName() || processingPlan.ApprovedAt == 0 || len(processingPlan.ReviewComments) != 1 {
+		t.Errorf("releasing the plan changed its approval: %+v", processingPlan)
+	}
+
+	processingPlan = users.plan(entity.PlanInReview)
+	processingPlan.Moderator, processingPlan.ApprovedAt = "Someone", 1
+	if err := processingPlanService.Transition(processingPlan, entity.PlanDraft, users.owner, users.owner.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if processingPlan.Moderator != "" || processingPlan.ApprovedAt != 0 || len(processingPlan.ReviewComments) != 0 {
+		t.Errorf("plan moved back to draft is %+v", processingPlan)
+	}
+}
+
+func TestAssignReviewers(t *testing.T) {
+	users := newReviewUsers()
+	reviewers := []entity.Reviewer{{UserID: users.stranger.ID, Name: users.stranger.FullName()}}
+
+	tests := []struct {
+		name      string
+		status    entity.PlanStatus
+		user      *entity.User
+		reviewers []entity.Reviewer
+		want      error
+	}{
+		{"owner", entity.PlanDraft, users.owner, reviewers, nil},
+		{"admin", entity.PlanInReview, users.admin, reviewers, nil},
+		{"owner of a rejected plan", entity.PlanRejected, users.owner, reviewers, nil},
+		{"reviewer", entity.PlanDraft, users.reviewer, reviewers, ErrNotPlanOwner},
+		{"stranger adding themselves", entity.PlanDraft, users.stranger, reviewers, ErrNotPlanOwner},
+		{"approved plan", entity.PlanApproved, users.owner, reviewers, ErrInvalidTransition},
+		{"released plan", entity.PlanReleased, users.admin, reviewers, ErrPlanReleased},
+		{"no reviewers", entity.PlanDraft, users.owner, []entity.Reviewer{}, ErrInvalidReview},
+	}
+
+	processingPlanService := NewProcessingPlanService(nil)
+	for _, test := range tests {
+		processingPlan := users.plan(test.status)
+
+		err := processingPlanService.AssignReviewers(processingPlan, test.reviewers, test.user, users.owner.ID)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: AssignReviewers returned %v, want %v", test.name, err, test.want)
+		}
+
+		wantReviewer := users.reviewer.ID
+		if test.want == nil {
+			wantReviewer = users.stranger.ID
+		}
+
+		if len(processingPlan.Reviewers) != 1 || processingPlan.Reviewers[0].UserID != wantReviewer {
+			t.Errorf("%s: plan reviewers are %+v", test.name, processingPlan.Reviewers)
+		}
+	}
+}
+
+func TestAddComment(t *testing.T) {
+	users := newReviewUsers()
+
+	tests := []struct {
+		name    string
+		status  entity.PlanStatus
+		user    *entity.User
+		comment string
+		want    error
+	}{
+		{"owner", entity.PlanDraft, users.owner, "please check bend 3", nil},
+		{"reviewer", entity.PlanInReview, users.reviewer, "bend 3 is fine", nil},
+		{"admin", entity.PlanApproved, users.admin, "noted", nil},
+		{"stranger", entity.PlanInReview, users.stranger, "let me in", ErrNotPlanMember},
+		{"empty comment", entity.PlanDraft, users.owner, "", ErrInvalidReview},
+		{"released plan", entity.PlanReleased, users.owner, "too late", ErrPlanReleased},
+	}
+
+	processingPlanService := NewProcessingPlanService(nil)
+	for _, test := range tests {
+		processingPlan := users.plan(test.status)
+		comment := entity.ReviewComment{AuthorID: test.user.ID, Author: test.user.FullName(), Comment: test.comment}
+
+		err := processingPlanService.AddComment(processingPlan, comment, test.user, users.owner.ID)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: AddComment returned %v, want %v", test.name, err, test.want)
+		}
+
+		wantComments := 0
+		if test.want == nil {
+			wantComments = 1
+		}
+
+		if len(processingPlan.ReviewComments) != wantComments {
+			t.Errorf("%s: plan has %d comments, want %d", test.name, len(processingPlan.ReviewComments), wantComments)
+		}
+	}
+}
+
+// processingPlanRepoStub stores a single plan and checks its review revision
+// like the MongoDB repository does. concurrent runs before each update and
+// may change the stored plan as another request would.
+type processingPlanRepoStub struct {
+	repository.ProcessingPlanRepository
+	plan       *entity.ProcessingPlan
+	concurrent func(stored *entity.ProcessingPlan)
+	updates    int
+}
+
+func (r *processingPlanRepoStub) FindVersion(cadFileID string, version int64) (*entity.ProcessingPlan, error) {
+	if r.plan == nil || r.plan.Version != version {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	processingPlan := *r.plan
+	processingPlan.ReviewComments = append([]entity.ReviewComment{}, r.plan.ReviewComments...)
+	return &processingPlan, nil
+}
+
+func (r *processingPlanRepoStub) UpdateReview(processingPlan *entity.ProcessingPlan) (*entity.ProcessingPlan, error) {
+	r.updates++
+	if r.concurrent != nil {
+		r.concurrent(r.plan)
+	}
+
+	if r.plan.ReviewStatus() == entity.PlanReleased || r.plan.ReviewRevision != processingPlan.ReviewRevision {
+		return nil, repository.ErrReviewConflict
+	}
+
+	stored := *processingPlan
+	stored.ReviewRevision++
+	r.plan = &stored
+
+	return &stored, nil
+}
+
+func TestMutateReview(t *testing.T) {
+	addComment := func(text string) func(*entity.ProcessingPlan) {
+		return func(stored *entity.ProcessingPlan) {
+			stored.ReviewComments = append(stored.ReviewComments, entity.ReviewComment{Comment: text})
+			stored.ReviewRevision++
+		}
+	}
+
+	mutateErr := errors.New("invalid change")
+
+	tests := []struct {
+		name         string
+		status       entity.PlanStatus
+		version      int64
+		concurrent   func(conflicts *int) func(*entity.ProcessingPlan)
+		mutateErr    error
+		want         error
+		wantCalls    int
+		wantComments []string
+	}{
+		{
+			name:         "no conflict",
+			version:      1,
+			wantCalls:    1,
+			wantComments: []string{"ours"},
+		},
+		{
+			name:    "concurrent comment",
+			version: 1,
+			concurrent: func(conflicts *int) func(*entity.ProcessingPlan) {
+				return func(stored *entity.ProcessingPlan) {
+					if *conflicts < 2 {
+						*conflicts++
+						addComment("theirs")(stored)
+					}
+				}
+			},
+			wantCalls:    3,
+			wantComments: []string{"theirs", "theirs", "ours"},
+		},
+		{
+			name:    "conflicting forever",
+			version: 1,
+			concurrent: func(conflicts *int) func(*entity.ProcessingPlan) {
+				return func(stored *entity.ProcessingPlan) { stored.ReviewRevision++ }
+			},
+			want:      repository.ErrReviewConflict,
+			wantCalls: maxReviewUpdateAttempts,
+		},
+		{
+			name:    "released concurrently",
+			version: 1,
+			concurrent: func(conflicts *int) func(*entity.ProcessingPlan) {
+				return func(stored *entity.ProcessingPlan) { stored.Status = entity.PlanReleased }
+			},
+			want:      ErrPlanReleased,
+			wantCalls: 1,
+		},
+		{name: "released", status: entity.PlanReleased, version: 1, want: ErrPlanReleased},
+		{name: "invalid change", version: 1, mutateErr: mutateErr, want: mutateErr, wantCalls: 1},
+		{name: "missing version", version: 2, want: mongo.ErrNoDocuments},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &processingPlanRepoStub{plan: &entity.ProcessingPlan{Version: 1, Status: test.status}}
+			conflicts := 0
+			if test.concurrent != nil {
+				repo.concurrent = test.concurrent(&conflicts)
+			}
+
+			processingPlanService := NewProcessingPlanService(repo)
+
+			calls := 0
+			processingPlan, err := processingPlanService.MutateReview("cadfile-1", test.version, func(processingPlan *entity.ProcessingPlan) error {
+				calls++
+				if test.mutateErr != nil {
+					return test.mutateErr
+				}
+
+				processingPlan.ReviewComments = append(processingPlan.ReviewComments, entity.ReviewComment{Comment: "ours"})
+				return nil
+			})
+
+			if !errors.Is(err, test.want) {
+				t.Fatalf("MutateReview returned %v, want %v", err, test.want)
+			}
+
+			if calls != test.wantCalls {
+				t.Errorf("mutate ran %d times, want %d", calls, test.wantCalls)
+			}
+
+			if test.want != nil {
+				if processingPlan != nil {
+					t.Errorf("failed MutateReview returned plan %+v", processingPlan)
+				}
+				return
+			}
+
+			comments := []string{}
+			for _, comment := range repo.plan.ReviewComments {
+				comments = append(comments, comment.Comment)
+			}
+
+			if len(comments) != len(test.wantComments) {
+				t.Fatalf("stored comments %v, want %v", comments, test.wantComments)
+			}
+			for i := range comments {
+				if comments[i] != test.wantComments[i] {
+					t.Fatalf("stored comments %v, want %v", comments, test.wantComments)
+				}
+			}
+
+			if processingPlan.ReviewRevision != repo.plan.ReviewRevision {
+				t.Errorf("returned revision %d, stored revision %d", processingPlan.ReviewRevision, repo.plan.ReviewRevision)
+			}
+		})
+	}
+}
+
+func containsStatus(statuses []entity.PlanStatus, status entity.PlanStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
@@ -16,6 +16,8 @@ type Processor struct {
 	ProcessorChannel chan string
 	TaskChannel      chan *entity.Task
 	CADFilesChannel  chan CADFileResponse
+	FailureChannel   chan FailureResponse
+	ProgressChannel  chan ProgressResponse
 	jwtService       JWTService
 }
 
@@ -24,6 +26,20 @@ type CADFileResponse struct {
 	CadFiles []entity.CADFile
 }
 
+// FailureResponse reports a CAD file that a worker could not process.
+type FailureResponse struct {
+	UserID  string           `json:"-"`
+	TaskID  string           `json:"task_id"`
+	Failure entity.Processed `json:"failure"`
+}
+
+// ProgressResponse reports the progress of a long-running job on a CAD file.
+type ProgressResponse struct {
+	UserID   string          `json:"-"`
+	TaskID   string          `json:"task_id"`
+	Progress entity.Progress `json:"progress"`
+}
+
 var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -39,6 +55,8 @@ func NewProcessor(jwtService JWTService) *Processor {
 		ProcessorChannel: make(chan string),
 		TaskChannel:      make(chan *entity.Task),
 		CADFilesChannel:  make(chan CADFileResponse),
+		FailureChannel:   make(chan FailureResponse),
+		ProgressChannel:  make(chan ProgressResponse),
 	}
 }
 
@@ -112,6 +130,37 @@ func (p *Processor) Run() {
 				conn.WriteMessage(websocket.TextMessage, resp)
 				log.Printf("Send cad files; user: %v Total: %d\n", cadFilesResponse.UserID, len(cadFilesResponse.CadFiles))
 			}
+		case failureResponse := <-p.FailureChannel:
+			if conn, ok := p.Users[failureResponse.UserID]; ok {
+				response := helper.Response{
+					Status:  false,
+					Message: "File processing failed",
+					Type:    "failure",
+					Errors:  []string{failureResponse.Failure.ErrorMessage},
+					Data:    failureResponse,
+				}
+				resp, err := json.Marshal(response)
+				if err != nil {
+					log.Println(err.Error())
+				}
+				conn.WriteMessage(websocket.TextMessage, resp)
+				log.Printf("Send failure: %v task: %v user: %v\n", failureResponse.Failure.FileName, failureResponse.TaskID, failureResponse.UserID)
+			}
+		case progressResponse := <-p.ProgressChannel:
+			if conn, ok := p.Users[progressResponse.UserID]; ok {
+				response := helper.Response{
+					Status:  true,
+					Message: progressResponse.Progress.Message,
+					Type:    "progress",
+					Errors:  nil,
+					Data:    progressResponse,
+				}
+				resp, err := json.Marshal(response)
+				if err != nil {
+					log.Println(err.Error())
+				}
+				conn.WriteMessage(websocket.TextMessage, resp)
+			}
 		}
 	}
 }
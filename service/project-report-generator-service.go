@@ -0,0 +1,330 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+	"github.com/johnfercher/maroto/pkg/consts"
+	"github.com/johnfercher/maroto/pkg/pdf"
+	"github.com/johnfercher/maroto/pkg/props"
+)
+
+// ReportTotals are the figures of a whole project report.
+type ReportTotals struct {
+	Parts          int
+	Quantity       int64
+	Bends          int
+	ProductionTime float64
+	Cost           float64
+}
+
+// GenerateProjectReport draws the processing plans of a project in one PDF: a
+// cover page, a summary of the parts with the project's totals, and a section
+// for each part. It is branded like the plans, with the Fxtract branding when
+// the template is nil.
+func (p *pdfService) GenerateProjectReport(project *entity.Project, processingPlans []entity.ProcessingPlan, template *entity.PDFTemplate) (bytes.Buffer, error) {
+	if template == nil {
+		template = DefaultPDFTemplate()
+	}
+
+	m := pdf.NewMaroto(consts.Portrait, pageSize(template))
+	m.SetPageMargins(10, 15, 10)
+
+	m.SetAliasNbPages("{nb}")
+	m.SetFirstPageNb(1)
+
+	m.RegisterFooter(func() {
+		templateFooter(m, template)
+	})
+
+	layout := "02  October 2006"
+
+	m.Row(70, func() {})
+	m.Row(20, func() {
+		m.Col(12, func() {
+			if template.Logo != "" {
+				m.Base64Image(template.Logo, consts.Extension(template.LogoType), props.Rect{
+					Center:  true,
+					Percent: 100,
+				})
+				return
+			}
+
+			m.Text(template.CompanyName, props.Text{
+				Size:  32,
+				Style: consts.Bold,
+				Align: consts.Center,
+				Color: templateColour(template.PrimaryColour),
+			})
+		})
+	})
+	m.Row(15, func() {
+		m.Col(12, func() {
+			m.Text("Manufacturing report", props.Text{
+				Size:  20,
+				Style: consts.Bold,
+				Align: consts.Center,
+			})
+		})
+	})
+	m.Line(0.2)
+	m.Row(15, func() {
+		m.Col(12, func() {
+			m.Text(project.Title, props.Text{
+				Top:   4,
+				Size:  16,
+				Style: consts.Bold,
+				Align: consts.Center,
+			})
+		})
+	})
+	m.Row(20, func() {
+		m.Col(12, func() {
+			m.Text(project.Description, props.Text{
+				Top:   2,
+				Size:  11,
+				Align: consts.Center,
+			})
+		})
+	})
+	m.Row(10, func() {
+		m.Col(12, func() {
+			m.Text(fmt.Sprintf("Parts: %d", len(processingPlans)), props.Text{
+				Style: consts.Bold,
+				Align: consts.Center,
+			})
+		})
+	})
+	m.Row(10, func() {
+		m.Col(12, func() {
+			m.Text(fmt.Sprintf("Date: %s", time.Now().Format(layout)), props.Text{
+				Style: consts.Bold,
+				Align: consts.Center,
+			})
+		})
+	})
+
+	// Pages after the cover carry the project's name.
+	m.RegisterHeader(func() {
+		m.Row(12, func() {
+			m.Col(6, func() {
+				templateBrand(m, template, 16)
+			})
+
+			m.Col(6, func() {
+				m.Text(project.Title, props.Text{
+					Top:   3,
+					Style: consts.Bold,
+					Align: consts.Right,
+				})
+			})
+		})
+		m.Line(0.2)
+	})
+	m.AddPage()
+
+	reportSection(m, template, "Summary")
+	headerSummary, summaryContent := p.getReportSummaryContent(processingPlans)
+	m.TableList(headerSummary, summaryContent, props.TableList{
+		ContentProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 1, 1, 1, 1, 2},
+		},
+		HeaderProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 1, 1, 1, 1, 2},
+		},
+		Align:                consts.Center,
+		AlternatedBackground: alternatedBackground(template),
+	})
+
+	totals := ProjectReportTotals(processingPlans)
+
+	reportSection(m, template, "Totals")
+	m.TableList([]string{"Parts", "Quantity", "Bends", "Production time", "Cost"}, [][]string{{
+		fmt.Sprint(totals.Parts), fmt.Sprint(totals.Quantity), fmt.Sprint(totals.Bends),
+		fmt.Sprintf("%.1f", totals.ProductionTime), fmt.Sprintf("%.2f", totals.Cost),
+	}}, props.TableList{
+		ContentProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 3, 3},
+		},
+		HeaderProp: props.TableListContent{
+			GridSizes: []uint{2, 2, 2, 3, 3},
+		},
+		Align:                consts.Center,
+		AlternatedBackground: alternatedBackground(template),
+	})
+
+	for i := range processingPlans {
+		processingPlan := processingPlans[i]
+
+		m.AddPage()
+		reportSection(m, template, fmt.Sprintf("Part %d: %s", i+1, reportPartName(processingPlan)))
+
+		m.Row(10, func() {
+			m.Col(6, func() {
+				m.Text(fmt.Sprintf("Material: %s", processingPlan.Material), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+			m.Col(6, func() {
+				m.Text(fmt.Sprintf("Quantity: %d", processingPlan.Quantity), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+		})
+		m.Row(10, func() {
+			m.Col(6, func() {
+				m.Text(fmt.Sprintf("Bending force: %.2f (%s)", processingPlan.BendingForce, bendingForceModel(processingPlan.BendingForceModel)), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+			m.Col(6, func() {
+				m.Text(fmt.Sprintf("Plan version: %d (%s)", processingPlan.Version, processingPlan.ReviewStatus()), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+		})
+		m.Row(10, func() {
+			m.Col(4, func() {
+				m.Text(fmt.Sprintf("Tools: %d", processingPlan.Tools), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+			m.Col(4, func() {
+				m.Text(fmt.Sprintf("Rotations: %d", processingPlan.Rotations), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+			m.Col(4, func() {
+				m.Text(fmt.Sprintf("Flips: %d", processingPlan.Flips), props.Text{
+					Top:   2,
+					Style: consts.Bold,
+				})
+			})
+		})
+
+		headerSmall, smallContent := p.getSmallContent(bendFeatureMap(processingPlan.BendFeatures), processingPlan.BendingSequences)
+		m.Row(10, func() {
+			m.Col(12, func() {
+				m.Text("Bending sequence", props.Text{
+					Top:   3,
+					Style: consts.Bold,
+					Color: templateColour(template.PrimaryColour),
+				})
+			})
+		})
+		m.TableList(headerSmall, smallContent, props.TableList{
+			ContentProp: props.TableListContent{
+				GridSizes: []uint{1, 1, 2, 1, 1, 2, 2, 2},
+			},
+			HeaderProp: props.TableListContent{
+				GridSizes: []uint{1, 1, 2, 1, 1, 2, 2, 2},
+			},
+			Align:                consts.Center,
+			AlternatedBackground: alternatedBackground(template),
+		})
+
+		headerCost, costContent := p.getCostContent(processingPlan.Cost)
+		m.Row(10, func() {
+			m.Col(12, func() {
+				m.Text("Cost estimate", props.Text{
+					Top:   3,
+					Style: consts.Bold,
+					Color: templateColour(template.PrimaryColour),
+				})
+			})
+		})
+		m.TableList(headerCost, costContent, props.TableList{
+			ContentProp: props.TableListContent{
+				GridSizes: []uint{6, 3, 3},
+			},
+			HeaderProp: props.TableListContent{
+				GridSizes: []uint{6, 3, 3},
+			},
+			Align:                consts.Center,
+			AlternatedBackground: alternatedBackground(template),
+		})
+	}
+
+	m.Row(10, func() {})
+	m.Line(0.2)
+	m.Row(10, func() {
+		m.Text("NB: All units are in degrees, mm, kN and sec", props.Text{
+			Top:   3,
+			Style: consts.Italic,
+			Align: consts.Center,
+			Size:  8,
+		})
+	})
+	m.Line(0.2)
+
+	ret, err := m.Output()
+	if err != nil {
+		return *bytes.NewBuffer([]byte{}), fmt.Errorf("could not generate PDF: %v", err)
+	}
+
+	return ret, nil
+}
+
+func (p *pdfService) getReportSummaryContent(processingPlans []entity.ProcessingPlan) ([]string, [][]string) {
+	header := []string{"Part no", "Part name", "Material", "Qty", "Bends", "Tools", "Time", "Cost"}
+
+	contents := [][]string{}
+	for _, processingPlan := range processingPlans {
+		contents = append(contents, []string{processingPlan.PartNo, processingPlan.FileName, processingPlan.Material,
+			fmt.Sprint(processingPlan.Cost.Quantity), fmt.Sprint(len(processingPlan.BendingSequences)), fmt.Sprint(processingPlan.Tools),
+			fmt.Sprintf("%.1f", batchProductionTime(processingPlan)), fmt.Sprintf("%.2f", processingPlan.Cost.TotalCost)})
+	}
+
+	return header, contents
+}
+
+// ProjectReportTotals adds up the parts of a project report. Times and costs
+// are for the whole batch of each part.
+func ProjectReportTotals(processingPlans []entity.ProcessingPlan) ReportTotals {
+	totals := ReportTotals{Parts: len(processingPlans)}
+	for _, processingPlan := range processingPlans {
+		totals.Quantity += processingPlan.Cost.Quantity
+		totals.Bends += len(processingPlan.BendingSequences)
+		totals.ProductionTime += batchProductionTime(processingPlan)
+		totals.Cost += processingPlan.Cost.TotalCost
+	}
+
+	return totals
+}
+
+func batchProductionTime(processingPlan entity.ProcessingPlan) float64 {
+	return processingPlan.EstimatedManufacturingTime * float64(processingPlan.Cost.Quantity)
+}
+
+func reportPartName(processingPlan entity.ProcessingPlan) string {
+	if processingPlan.PartNo == "" {
+		return processingPlan.FileName
+	}
+
+	return processingPlan.PartNo + " - " + processingPlan.FileName
+}
+
+func reportSection(m pdf.Maroto, template *entity.PDFTemplate, title string) {
+	m.Row(20, func() {
+		m.Col(12, func() {
+			m.Text(title, props.Text{
+				Top:   8,
+				Style: consts.Bold,
+				Align: consts.Center,
+				Size:  12,
+				Color: templateColour(template.PrimaryColour),
+			})
+		})
+	})
+
+	m.Line(0.2)
+	m.Row(5, func() {})
+}
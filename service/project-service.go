@@ -41,6 +41,10 @@ func (*service) Validate(project *entity.Project) error {
 		return errors.New("title or description can not be empty")
 	}
 
+	if _, err := NewBendingForceCalculator(project.BendingForceModel); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -3,8 +3,13 @@ package service
 import (
 	"github.com/WilfredDube/fxtract-backend/entity"
 	"github.com/WilfredDube/fxtract-backend/repository"
+	"github.com/pkg/errors"
 )
 
+// maxTaskUpdateAttempts bounds how often Mutate re-reads a task that keeps
+// being modified concurrently.
+const maxTaskUpdateAttempts = 10
+
 var (
 	taskRepo repository.TaskRepository
 )
@@ -12,7 +17,9 @@ var (
 // TaskService -
 type TaskService interface {
 	Create(task *entity.Task) (*entity.Task, error)
+	CreateWithEvents(task *entity.Task, records []entity.OutboxRecord) (*entity.Task, error)
 	Update(task *entity.Task) (*entity.Task, error)
+	Mutate(id string, mutate func(task *entity.Task) error) (*entity.Task, error)
 	Find(id string) (*entity.Task, error)
 	FindByUserID(id string) ([]entity.Task, error)
 	FindAll() ([]entity.Task, error)
@@ -30,10 +37,42 @@ func (*taskService) Create(task *entity.Task) (*entity.Task, error) {
 	return taskRepo.Create(task)
 }
 
+func (*taskService) CreateWithEvents(task *entity.Task, records []entity.OutboxRecord) (*entity.Task, error) {
+	return taskRepo.CreateWithEvents(task, records)
+}
+
 func (*taskService) Update(task *entity.Task) (*entity.Task, error) {
 	return taskRepo.Update(task)
 }
 
+// Mutate applies a change to the latest version of a task, re-reading and
+// re-applying it when the task was updated concurrently.
+func (*taskService) Mutate(id string, mutate func(task *entity.Task) error) (*entity.Task, error) {
+	var err error
+	for attempt := 0; attempt < maxTaskUpdateAttempts; attempt++ {
+		var task *entity.Task
+		task, err = taskRepo.Find(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = mutate(task); err != nil {
+			return nil, err
+		}
+
+		task, err = taskRepo.Update(task)
+		if err == nil {
+			return task, nil
+		}
+
+		if errors.Cause(err) != repository.ErrVersionConflict {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
 func (*taskService) Find(id string) (*entity.Task, error) {
 	return taskRepo.Find(id)
 }
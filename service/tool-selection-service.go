@@ -0,0 +1,238 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+// AngleTolerance is the largest difference, in degrees, allowed between a
+// tool's angle and the angle of the bend it forms.
+const AngleTolerance = 2.0
+
+// Weights of the criteria a tool is scored on. A tool that meets every
+// criterion exactly and is shared by all the bends scores 100.
+const (
+	angleWeight  = 40.0
+	radiusWeight = 25.0
+	lengthWeight = 15.0
+	sharedWeight = 20.0
+)
+
+// ToolCandidate is a tool scored against a single bend.
+type ToolCandidate struct {
+	ToolID   string   `json:"tool_id"`
+	ToolName string   `json:"tool_name"`
+	Score    float64  `json:"score"`
+	Feasible bool     `json:"feasible"`
+	Selected bool     `json:"selected"`
+	Reasons  []string `json:"reasons"`
+}
+
+// BendToolCandidates are the ranked tool candidates for a bend, best first.
+type BendToolCandidates struct {
+	BendID     int64           `json:"bend_id"`
+	Angle      float64         `json:"angle"`
+	Radius     float64         `json:"radius"`
+	Length     float64         `json:"length"`
+	Candidates []ToolCandidate `json:"candidates"`
+}
+
+// ToolSelectionService -
+type ToolSelectionService interface {
+	Rank(bends []entity.BendFeature) ([]BendToolCandidates, error)
+	Select(bends []entity.BendFeature) ([]entity.BendFeature, error)
+}
+
+type toolSelectionService struct {
+	toolService ToolService
+}
+
+// NewToolSelectionService -
+func NewToolSelectionService(toolService ToolService) ToolSelectionService {
+	return &toolSelectionService{toolService: toolService}
+}
+
+// Rank scores every tool in the library against each bend. Tools that can form
+// the bend are ranked ahead of those that cannot, and the tool chosen for the
+// bend by Select is marked as selected.
+func (s *toolSelectionService) Rank(bends []entity.BendFeature) ([]BendToolCandidates, error) {
+	tools, err := s.toolService.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := rankTools(tools, bends)
+	assignTools(ranked)
+
+	return ranked, nil
+}
+
+// Select sets the tool of each bend, preferring the fewest distinct tools.
+// Bends that no tool in the library can form are left without one.
+func (s *toolSelectionService) Select(bends []entity.BendFeature) ([]entity.BendFeature, error) {
+	ranked, err := s.Rank(bends)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]entity.BendFeature, len(bends))
+	copy(selected, bends)
+
+	for i, bend := range ranked {
+		selected[i].ToolID = ""
+		if len(bend.Candidates) > 0 && bend.Candidates[0].Selected {
+			selected[i].ToolID = bend.Candidates[0].ToolID
+		}
+	}
+
+	return selected, nil
+}
+
+func rankTools(tools []entity.Tool, bends []entity.BendFeature) []BendToolCandidates {
+	ranked := make([]BendToolCandidates, len(bends))
+	coverage := make(map[string]int)
+
+	for i, bend := range bends {
+		ranked[i] = BendToolCandidates{BendID: bend.BendID, Angle: bend.Angle, Radius: bend.Radius, Length: bend.Length}
+
+		for _, tool := range tools {
+			candidate := scoreTool(tool, bend)
+			if candidate.Feasible {
+				coverage[tool.ToolID]++
+			}
+
+			ranked[i].Candidates = append(ranked[i].Candidates, candidate)
+		}
+	}
+
+	for i := range ranked {
+		for j := range ranked[i].Candidates {
+			candidate := &ranked[i].Candidates[j]
+			if !candidate.Feasible {
+				continue
+			}
+
+			shared := coverage[candidate.ToolID]
+			candidate.Score += sharedWeight * float64(shared) / float64(len(bends))
+			candidate.Score = math.Round(candidate.Score*100) / 100
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("can form %d of %d bends", shared, len(bends)))
+		}
+
+		sortCandidates(ranked[i].Candidates)
+	}
+
+	return ranked
+}
+
+// scoreTool checks a tool against the angle, radius and length of a bend and
+// explains the outcome of each check.
+func scoreTool(tool entity.Tool, bend entity.BendFeature) ToolCandidate {
+	candidate := ToolCandidate{ToolID: tool.ToolID, ToolName: tool.ToolName, Feasible: true}
+
+	// The sign of a bend's angle gives its direction, which does not matter
+	// for the tool.
+	angleDiff := math.Abs(tool.Angle - math.Abs(bend.Angle))
+	if angleDiff > AngleTolerance {
+		candidate.Feasible = false
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("angle %v° is %.1f° off the bend angle, tolerance is %v°", tool.Angle, angleDiff, AngleTolerance))
+	} else {
+		candidate.Score += angleWeight * (1 - angleDiff/AngleTolerance)
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("angle %v° is within %.1f° of the bend angle", tool.Angle, angleDiff))
+	}
+
+	if bend.Radius < tool.MinRadius || bend.Radius > tool.MaxRadius {
+		candidate.Feasible = false
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("bend radius %v is outside the tool's range %v-%v", bend.Radius, tool.MinRadius, tool.MaxRadius))
+	} else {
+		// Radii in the middle of the range are preferred to those at its edges.
+		fit := 1.0
+		if span := tool.MaxRadius - tool.MinRadius; span > 0 {
+			fit = 1 - math.Abs(bend.Radius-(tool.MinRadius+span/2))/(span/2)
+		}
+		candidate.Score += radiusWeight * (0.5 + fit/2)
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("bend radius %v is within the tool's range %v-%v", bend.Radius, tool.MinRadius, tool.MaxRadius))
+	}
+
+	if tool.Length < bend.Length {
+		candidate.Feasible = false
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("tool length %v is shorter than the bend length %v", tool.Length, bend.Length))
+	} else {
+		// Shorter tools leave more of the press brake free for other stations.
+		candidate.Score += lengthWeight * bend.Length / tool.Length
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("tool length %v covers the bend length %v", tool.Length, bend.Length))
+	}
+
+	if !candidate.Feasible {
+		candidate.Score = 0
+	}
+
+	return candidate
+}
+
+// assignTools picks a tool for every bend that can be formed, greedily taking
+// the tool that can form the most unassigned bends so the part needs as few
+// tool changes as possible. Ties go to the tool with the highest total score.
+func assignTools(ranked []BendToolCandidates) {
+	assigned := make([]bool, len(ranked))
+
+	for {
+		bendsByTool := make(map[string][]int)
+		scoreByTool := make(map[string]float64)
+
+		for i, bend := range ranked {
+			if assigned[i] {
+				continue
+			}
+
+			for _, candidate := range bend.Candidates {
+				if candidate.Feasible {
+					bendsByTool[candidate.ToolID] = append(bendsByTool[candidate.ToolID], i)
+					scoreByTool[candidate.ToolID] += candidate.Score
+				}
+			}
+		}
+
+		best := ""
+		for toolID, bends := range bendsByTool {
+			if best == "" || len(bends) > len(bendsByTool[best]) ||
+				(len(bends) == len(bendsByTool[best]) && (scoreByTool[toolID] > scoreByTool[best] ||
+					(scoreByTool[toolID] == scoreByTool[best] && toolID < best))) {
+				best = toolID
+			}
+		}
+
+		if best == "" {
+			return
+		}
+
+		for _, i := range bendsByTool[best] {
+			assigned[i] = true
+
+			for j := range ranked[i].Candidates {
+				if ranked[i].Candidates[j].ToolID == best {
+					ranked[i].Candidates[j].Selected = true
+				}
+			}
+
+			sortCandidates(ranked[i].Candidates)
+		}
+	}
+}
+
+func sortCandidates(candidates []ToolCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Selected != candidates[j].Selected {
+			return candidates[i].Selected
+		}
+		if candidates[i].Feasible != candidates[j].Feasible {
+			return candidates[i].Feasible
+		}
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ToolID < candidates[j].ToolID
+	})
+}
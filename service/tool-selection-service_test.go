@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/WilfredDube/fxtract-backend/entity"
+)
+
+type toolServiceStub struct {
+	ToolService
+	tools []entity.Tool
+	err   error
+}
+
+func (s *toolServiceStub) FindAll() ([]entity.Tool, error) {
+	return s.tools, s.err
+}
+
+func TestScoreTool(t *testing.T) {
+	tool := entity.Tool{ToolID: "T90", Angle: 90, Length: 100, MinRadius: 1, MaxRadius: 3}
+
+	tests := []struct {
+		name         string
+		bend         entity.BendFeature
+		wantFeasible bool
+		wantScore    float64
+	}{
+		{"exact fit", entity.BendFeature{Angle: 90, Radius: 2, Length: 100}, true, 80},
+		{"opposite direction", entity.BendFeature{Angle: -90, Radius: 2, Length: 100}, true, 80},
+		{"angle within tolerance", entity.BendFeature{Angle: 91, Radius: 2, Length: 100}, true, 60},
+		{"opposite angle within tolerance", entity.BendFeature{Angle: -89, Radius: 2, Length: 100}, true, 60},
+		{"angle out of tolerance", entity.BendFeature{Angle: 93, Radius: 2, Length: 100}, false, 0},
+		{"opposite angle out of tolerance", entity.BendFeature{Angle: -93, Radius: 2, Length: 100}, false, 0},
+		{"radius at the edge of the range", entity.BendFeature{Angle: 90, Radius: 1, Length: 100}, true, 67.5},
+		{"radius out of range", entity.BendFeature{Angle: 90, Radius: 4, Length: 100}, false, 0},
+		{"short bend", entity.BendFeature{Angle: 90, Radius: 2, Length: 50}, true, 72.5},
+		{"tool too short", entity.BendFeature{Angle: 90, Radius: 2, Length: 150}, false, 0},
+	}
+
+	for _, test := range tests {
+		candidate := scoreTool(tool, test.bend)
+		if candidate.Feasible != test.wantFeasible || !approxEqual(candidate.Score, test.wantScore) {
+			t.Errorf("%s: scoreTool = feasible %v, score %v; want feasible %v, score %v (%v)",
+				test.name, candidate.Feasible, candidate.Score, test.wantFeasible, test.wantScore, candidate.Reasons)
+		}
+
+		if len(candidate.Reasons) != 3 {
+			t.Errorf("%s: %d reasons, want one per check", test.name, len(candidate.Reasons))
+		}
+	}
+}
+
+func TestRankTools(t *testing.T) {
+	tools := []entity.Tool{
+		{ToolID: "T88", Angle: 88, Length: 100, MinRadius: 1, MaxRadius: 3},
+		{ToolID: "T90", Angle: 90, Length: 100, MinRadius: 1, MaxRadius: 3},
+		{ToolID: "T135", Angle: 135, Length: 100, MinRadius: 1, MaxRadius: 3},
+	}
+	bends := []entity.BendFeature{
+		{BendID: 1, Angle: 90, Radius: 2, Length: 100},
+		{BendID: 2, Angle: -89, Radius: 2, Length: 100},
+	}
+
+	ranked := rankTools(tools, bends)
+
+	tests := []struct {
+		bendID    int64
+		wantOrder []string
+		wantScore []float64
+	}{
+		// T90 and T88 can form both bends, so each gets the full shared bonus.
+		// Equal scores are ranked by tool ID.
+		{1, []string{"T90", "T88", "T135"}, []float64{100, 60, 0}},
+		{2, []string{"T88", "T90", "T135"}, []float64{80, 80, 0}},
+	}
+
+	for i, test := range tests {
+		if ranked[i].BendID != test.bendID {
+			t.Fatalf("ranked bend %d is bend %d, want %d", i, ranked[i].BendID, test.bendID)
+		}
+
+		for j, toolID := range test.wantOrder {
+			candidate := ranked[i].Candidates[j]
+			if candidate.ToolID != toolID || !approxEqual(candidate.Score, test.wantScore[j]) {
+				t.Errorf("bend %d candidate %d is %s scoring %v, want %s scoring %v",
+					test.bendID, j, candidate.ToolID, candidate.Score, toolID, test.wantScore[j])
+			}
+		}
+
+		last := ranked[i].Candidates[len(ranked[i].Candidates)-1]
+		if last.Feasible || last.ToolID != "T135" {
+			t.Errorf("bend %d ranks %+v last, want the infeasible T135", test.bendID, last)
+		}
+	}
+}
+
+func TestSelectPrefersFewestTools(t *testing.T) {
+	tools := []entity.Tool{
+		// Forms every 90° bend, though not as well as T90-narrow.
+		{ToolID: "T90-wide", Angle: 90, Length: 200, MinRadius: 1, MaxRadius: 5},
+		// The best fit for the first bend only.
+		{ToolID: "T90-narrow", Angle: 90, Length: 100, MinRadius: 1.5, MaxRadius: 2.5},
+		{ToolID: "T135", Angle: 135, Length: 200, MinRadius: 1, MaxRadius: 5},
+	}
+	bends := []entity.BendFeature{
+		{BendID: 1, Angle: 90, Radius: 2, Length: 100, ToolID: "stale"},
+		{BendID: 2, Angle: -90, Radius: 4, Length: 150},
+		{BendID: 3, Angle: 90, Radius: 1, Length: 180},
+		{BendID: 4, Angle: 135, Radius: 2, Length: 100},
+		{BendID: 5, Angle: 45, Radius: 2, Length: 100, ToolID: "stale"},
+	}
+
+	selection := NewToolSelectionService(&toolServiceStub{tools: tools})
+
+	selected, err := selection.Select(bends)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, len(selected))
+	for i, bend := range selected {
+		got[i] = bend.ToolID
+	}
+
+	want := []string{"T90-wide", "T90-wide", "T90-wide", "T135", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Select picked %v, want %v", got, want)
+	}
+
+	if bends[0].ToolID != "stale" {
+		t.Errorf("Select changed the bends it was given")
+	}
+
+	ranked, err := selection.Rank(bends)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, bend := range ranked {
+		selectedCount := 0
+		for _, candidate := range bend.Candidates {
+			if candidate.Selected {
+				selectedCount++
+			}
+		}
+
+		wantCount := 1
+		if want[i] == "" {
+			wantCount = 0
+		}
+
+		if selectedCount != wantCount || (wantCount == 1 && bend.Candidates[0].ToolID != want[i]) {
+			t.Errorf("bend %d ranks %+v, want %q selected first", bend.BendID, bend.Candidates, want[i])
+		}
+	}
+}
+
+func TestSelectReportsToolLibraryFailures(t *testing.T) {
+	failure := errors.New("database unavailable")
+	selection := NewToolSelectionService(&toolServiceStub{err: failure})
+
+	if _, err := selection.Select([]entity.BendFeature{{BendID: 1, Angle: 90}}); !errors.Is(err, failure) {
+		t.Errorf("Select returned %v, want the tool library failure", err)
+	}
+}